@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tensorworks/bootnext/internal/config"
+	"github.com/tensorworks/bootnext/internal/elevate"
+	"github.com/tensorworks/bootnext/internal/reboot"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The errors reported when the system does not meet the prerequisites for accessing UEFI NVRAM variables
+var (
+	errNotUEFI     = errors.New("unsupported system configuration: the operating system has not been booted in UEFI mode")
+	errMissingTool = errors.New("a required application was not found in the system PATH")
+)
+
+// The values of the command-line flags that are shared by all commands
+type globalOptions struct {
+
+	// The backend specification from the `--backend` flag
+	backend string
+
+	// The path to the per-user configuration file from the `--config` flag
+	config string
+
+	// Specifies whether the `--no-elevate` flag was specified
+	noElevate bool
+
+	// Specifies whether the `--pause` flag was specified
+	pause bool
+
+	// The output format from the `--output` flag, for the commands that support it
+	output string
+}
+
+// Returns the usage text for the `--backend` flag
+func backendUsage() string {
+	return fmt.Sprintf(
+		"The backend used to access UEFI variables (%s), defaults to the value of the %s environment variable or \"auto\"",
+		strings.Join(uefi.BackendNames(), ", "),
+		uefi.BACKEND_ENV_VAR,
+	)
+}
+
+// Creates the backend specified by the `--backend` flag, falling back to the environment variable if the flag is empty
+func selectBackend(spec string) (uefi.Backend, error) {
+	if spec == "" {
+		spec = os.Getenv(uefi.BACKEND_ENV_VAR)
+	}
+
+	backend, err := uefi.NewBackend(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend: %v", err)
+	}
+	return backend, nil
+}
+
+// Verifies that the backend is able to access UEFI NVRAM variables, re-launching the process with elevated privileges
+// if they are required and have not been disabled
+func checkPrerequisites(backend uefi.Backend, modifiesNVRAM bool, noElevate bool) error {
+
+	// Verify that the operating system has been booted in UEFI mode
+	enabled, err := backend.IsUEFIEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to query system UEFI status: %v", err)
+	} else if !enabled {
+		return errNotUEFI
+	}
+
+	// Verify that all of the system tools we require for interacting with UEFI NVRAM variables are available
+	requiredTools := backend.RequiredTools()
+	for _, tool := range requiredTools {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%w: %v", errMissingTool, tool)
+		}
+	}
+
+	// Determine whether we require elevated privileges
+	// (We need them for writing to NVRAM variables under Linux, and for both reading and writing under Windows,
+	// but not when the backend only simulates NVRAM variables)
+	requireElevation := (modifiesNVRAM || runtime.GOOS == "windows") && !backend.IsVirtual()
+
+	// Determine whether the process is running with insufficient privileges
+	if requireElevation && !elevate.IsElevated() {
+
+		// Determine whether we should automatically request elevated privileges
+		if !noElevate {
+
+			// Re-run the process with elevated privileges and propagate the exit code
+			exitCode, err := elevate.RunElevated(elevatedArgs())
+			if err != nil {
+				return fmt.Errorf("failed to re-launch the process with elevated privileges: %v", err)
+			} else {
+				os.Exit(exitCode)
+			}
+
+		} else {
+			fmt.Fprint(os.Stderr, "Warning: running without elevated privileges, access to UEFI NVRAM variables may be denied.\n\n")
+		}
+	}
+
+	return nil
+}
+
+// Returns the command-line arguments that should be passed to the process when re-launching it with elevated privileges
+// (Elevation does not reliably preserve environment variables or the user's home directory, so any backend selected via
+// the environment and the path to the per-user configuration file are passed as flags)
+func elevatedArgs() []string {
+	args := os.Args[1:]
+
+	// A process started without arguments is running in interactive mode, which must be requested explicitly because
+	// the elevated process is not necessarily started without arguments (e.g. under Windows, `--pause` is appended)
+	if len(args) == 0 {
+		args = []string{"--interactive"}
+	}
+	if spec := os.Getenv(uefi.BACKEND_ENV_VAR); spec != "" {
+		args = append([]string{fmt.Sprintf("--backend=%s", spec)}, args...)
+	}
+
+	// Any `--config` flag that was specified explicitly appears later in the arguments, so it takes precedence
+	if path := config.UserPath(); path != "" {
+		args = append([]string{fmt.Sprintf("--config=%s", path)}, args...)
+	}
+	return args
+}
+
+// Reboots the system immediately or after the specified delay, or simulates a reboot if the backend does not operate
+// on the host system's firmware
+func rebootSystem(backend uefi.Backend, delay time.Duration) error {
+	if backend.IsVirtual() {
+		fmt.Printf("Skipping reboot because the %s backend is simulating NVRAM variables.\n", backend.Name())
+		return nil
+	}
+
+	// Schedule a delayed reboot if requested, recording it so that `bootnext cancel` can cancel it
+	if delay > 0 {
+		scheduled, err := reboot.Schedule(delay)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Scheduled a reboot for %s (run `bootnext cancel` to cancel it).\n", scheduled.Time.Format(time.RFC1123))
+		return nil
+	}
+
+	fmt.Println("Rebooting now...")
+	return reboot.Reboot()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Creates the `backup` subcommand
+func newBackupCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup [file]",
+		Short: "Save the complete UEFI boot configuration to a JSON file",
+		Long: "Captures the BootOrder, BootNext and Timeout variables and the raw contents of every Boot#### variable,\n" +
+			"writing them to the specified file, or to stdout if no file is specified.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			path := ""
+			if len(args) > 0 {
+				path = args[0]
+			}
+			return runBackup(backend, options.noElevate, path)
+		},
+	}
+}
+
+// Creates the `restore` subcommand
+func newRestoreCommand(options *globalOptions) *cobra.Command {
+	dryRun := false
+	command := &cobra.Command{
+		Use:   "restore file",
+		Short: "Restore the UEFI boot configuration from a JSON file created by `bootnext backup`",
+		Long: "Recreates any boot entries that are missing, rewrites any boot entries that have changed and resets the\n" +
+			"BootOrder and Timeout variables to the values in the backup. Boot entries that are not present in the backup are\n" +
+			"left untouched, and BootNext is not restored. Restoring the same backup multiple times is safe.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runRestore(backend, options.noElevate, args[0], dryRun)
+		},
+	}
+
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "Describe the changes that would be made but do not make any changes to the system")
+	return command
+}
+
+// Captures the complete UEFI boot configuration and writes it to the specified file, or to stdout if the path is empty
+func runBackup(backend uefi.Backend, noElevate bool, path string) error {
+
+	// Verify that we are able to read UEFI NVRAM variables
+	if err := checkPrerequisites(backend, false, noElevate); err != nil {
+		return err
+	}
+
+	// Capture the boot configuration
+	backup, err := uefi.CreateBackup(backend.RawVariables())
+	if err != nil {
+		return fmt.Errorf("failed to capture UEFI boot configuration: %v", err)
+	}
+	contents, err := json.MarshalIndent(backup, "", "\t")
+	if err != nil {
+		return err
+	}
+	contents = append(contents, '\n')
+
+	// Write the backup to stdout or to the specified file
+	if path == "" {
+		_, err = os.Stdout.Write(contents)
+		return err
+	}
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		return fmt.Errorf("failed to write backup file \"%s\": %v", path, err)
+	}
+	fmt.Printf("Saved %d boot entries to \"%s\"\n", len(backup.Entries), path)
+	return nil
+}
+
+// Restores the UEFI boot configuration from the specified backup file
+func runRestore(backend uefi.Backend, noElevate bool, path string, dryRun bool) error {
+
+	// Read and validate the backup file
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file \"%s\": %v", path, err)
+	}
+	backup, err := uefi.ParseBackup(contents)
+	if err != nil {
+		return fmt.Errorf("failed to parse backup file \"%s\": %v", path, err)
+	}
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !dryRun, noElevate); err != nil {
+		return err
+	}
+
+	// Determine which changes are required and print them
+	store := backend.RawVariables()
+	actions, err := uefi.PlanRestore(store, backup)
+	if err != nil {
+		return fmt.Errorf("failed to compare the UEFI boot configuration with the backup: %v", err)
+	} else if len(actions) == 0 {
+		fmt.Println("The UEFI boot configuration already matches the backup.")
+		return nil
+	}
+	fmt.Println("Restoring the backup requires the following changes:")
+	for _, action := range actions {
+		fmt.Printf("- %s\n", action.Description)
+	}
+
+	// Don't modify any variables if we are performing a dry run
+	if dryRun {
+		fmt.Println("\nDry run: not modifying any UEFI variables.")
+		return nil
+	}
+
+	// Apply the changes and verify that no further changes are required
+	fmt.Println("\nWriting UEFI variables...")
+	if err := uefi.ApplyRestore(store, actions); err != nil {
+		return err
+	}
+	if remaining, err := uefi.PlanRestore(store, backup); err != nil {
+		return fmt.Errorf("failed to verify the restored UEFI boot configuration: %v", err)
+	} else if len(remaining) > 0 {
+		return fmt.Errorf("failed to verify the restored UEFI boot configuration: %d changes did not take effect", len(remaining))
+	}
+
+	fmt.Println("Verified the restored boot configuration.")
+	recordSnapshot(backend)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/reboot"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Creates the `cancel` subcommand
+func newCancelCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel",
+		Short: "Clear any pending BootNext value and cancel any delayed reboot scheduled by bootnext",
+		Long: "Deletes the BootNext variable so that the next boot uses the normal BootOrder, and cancels any delayed reboot\n" +
+			"that was scheduled by running bootnext with the --delay flag.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runCancel(backend, options.noElevate)
+		},
+	}
+}
+
+// Clears the BootNext variable and cancels any scheduled reboot, reporting what was pending
+func runCancel(backend uefi.Backend, noElevate bool) error {
+
+	// Verify that we are able to modify UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, true, noElevate); err != nil {
+		return err
+	}
+
+	// Determine whether a BootNext value is pending
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+
+	// Clear the BootNext variable if it is set
+	if status.BootNext != "" {
+		fmt.Printf("Clearing pending BootNext value: %s\n", describeBootEntryID(entries, status.BootNext))
+		if err := backend.ClearBootNext(); err != nil {
+			return fmt.Errorf("failed to delete BootNext variable: %v", err)
+		}
+	} else {
+		fmt.Println("No BootNext value is pending.")
+	}
+
+	// Cancel any delayed reboot that we scheduled
+	// (Virtual backends never schedule reboots, so there is nothing to cancel when they are in use)
+	if !backend.IsVirtual() {
+		scheduled, err := reboot.CancelScheduled()
+		if err != nil {
+			return fmt.Errorf("failed to cancel scheduled reboot: %v", err)
+		} else if scheduled != nil {
+			fmt.Printf("Cancelled the reboot scheduled for %s.\n", scheduled.Time.Format(time.RFC1123))
+		} else {
+			fmt.Println("No scheduled reboot is pending.")
+		}
+	}
+
+	return nil
+}
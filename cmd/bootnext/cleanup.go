@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/reboot"
+	"github.com/tensorworks/bootnext/internal/state"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The name of the state record that tracks the one-shot boot entries created by `bootnext create --once`
+const oneShotEntriesRecord = "one-shot-entries"
+
+// Represents a boot entry that should be deleted once it has been used
+type oneShotEntry struct {
+
+	// The identifier of the boot entry
+	ID string `json:"id"`
+
+	// The description of the boot entry
+	Description string `json:"description"`
+
+	// The raw bytes of the device path list for the boot entry, used to verify that the entry has not been replaced
+	FilePathList []byte `json:"filePathList"`
+
+	// The time at which the boot entry was created
+	Created time.Time `json:"created"`
+}
+
+// Adds a boot entry to the list of one-shot boot entries that will be deleted by `bootnext cleanup`
+func recordOneShotEntry(entry uefi.BootEntry) error {
+	records := []oneShotEntry{}
+	if _, err := state.Load(oneShotEntriesRecord, &records); err != nil {
+		return err
+	}
+
+	records = append(records, oneShotEntry{
+		ID:           entry.ID,
+		Description:  entry.Description,
+		FilePathList: entry.FilePathList,
+		Created:      time.Now(),
+	})
+	return state.Save(oneShotEntriesRecord, records)
+}
+
+// Creates the `cleanup` subcommand
+func newCleanupCommand(options *globalOptions) *cobra.Command {
+	dryRun := false
+	command := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete one-shot boot entries that have been used and restore temporary Timeout values",
+		Long: "Deletes the boot entries created by `bootnext create --once` once the system has rebooted and the firmware\n" +
+			"has consumed the BootNext value that pointed to them, and restores the Timeout value replaced by\n" +
+			"`bootnext timeout --once` once the system has booted. This is intended to be run automatically at boot time.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runCleanup(backend, options.noElevate, dryRun)
+		},
+	}
+
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "Describe the changes that would be made but do not make any changes to the system")
+	return command
+}
+
+// Processes the state records for temporary changes that bootnext has made, reverting the changes that are no longer needed
+func runCleanup(backend uefi.Backend, noElevate bool, dryRun bool) error {
+
+	// Determine whether any temporary changes are pending cleanup
+	records := []oneShotEntry{}
+	if _, err := state.Load(oneShotEntriesRecord, &records); err != nil {
+		return fmt.Errorf("failed to read state record: %v", err)
+	}
+	timeoutPending, err := state.Load(pendingTimeoutRestoreRecord, &pendingTimeoutRestore{})
+	if err != nil {
+		return fmt.Errorf("failed to read state record: %v", err)
+	}
+	if len(records) == 0 && !timeoutPending {
+		fmt.Println("No temporary changes are pending cleanup.")
+		return nil
+	}
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !dryRun, noElevate); err != nil {
+		return err
+	}
+
+	// Process each type of temporary change
+	if len(records) > 0 {
+		if err := cleanupOneShotEntries(backend, records, dryRun); err != nil {
+			return err
+		}
+	}
+	if err := restorePendingTimeout(backend, dryRun); err != nil {
+		return err
+	}
+
+	if !dryRun {
+		recordSnapshot(backend)
+	}
+	return nil
+}
+
+// Deletes the recorded one-shot boot entries that the firmware has booted
+// (A boot entry is only considered to have been booted once the system has rebooted since it was created and BootNext
+// no longer references it, so that entries whose BootNext value was overwritten or cleared before rebooting are kept)
+func cleanupOneShotEntries(backend uefi.Backend, records []oneShotEntry, dryRun bool) error {
+
+	// Retrieve the list of UEFI boot entries, the value of BootNext and the time at which the system last booted
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+	booted, err := reboot.LastBootTime()
+	if err != nil {
+		return fmt.Errorf("failed to determine the last boot time: %v", err)
+	}
+
+	// Process each of the recorded boot entries
+	remaining := []oneShotEntry{}
+	for _, record := range records {
+		entry := uefi.FindBootEntry(entries, record.ID)
+
+		// Forget about boot entries that have already been deleted or replaced by an unrelated boot entry
+		// (Tool backends do not populate the device path list, so we only compare it when it is available)
+		if entry == nil {
+			fmt.Printf("Boot entry \"%s\" (%s) no longer exists\n", record.ID, record.Description)
+			continue
+		} else if entry.Description != record.Description ||
+			(entry.FilePathList != nil && !bytes.Equal(entry.FilePathList, record.FilePathList)) {
+			fmt.Printf("Boot entry \"%s\" (%s) has been replaced, leaving it in place\n", record.ID, record.Description)
+			continue
+		}
+
+		// Keep boot entries that the firmware has not yet booted
+		if status.BootNext == record.ID || booted.Before(record.Created) {
+			fmt.Printf("Boot entry \"%s\" (%s) has not been used yet, leaving it in place\n", record.ID, record.Description)
+			remaining = append(remaining, record)
+			continue
+		}
+
+		// Delete the boot entry
+		fmt.Printf("Deleting used boot entry \"%s\" (%s)\n", record.ID, record.Description)
+		if dryRun {
+			remaining = append(remaining, record)
+		} else if err := backend.DeleteBootEntry(*entry); err != nil {
+			return fmt.Errorf("failed to delete boot entry \"%s\": %v", record.ID, err)
+		}
+	}
+
+	// Update the state record
+	if dryRun {
+		return nil
+	} else if len(remaining) == 0 {
+		return state.Remove(oneShotEntriesRecord)
+	}
+	return state.Save(oneShotEntriesRecord, remaining)
+}
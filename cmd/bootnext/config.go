@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/config"
+	"github.com/tensorworks/bootnext/internal/selector"
+)
+
+// The error reported when the configuration files cannot be loaded
+var errInvalidConfig = errors.New("failed to load configuration")
+
+// Returns the usage text for the `--config` flag
+func configUsage() string {
+	return fmt.Sprintf(
+		"The per-user configuration file that defines named targets, defaults to the value of the %s environment variable or %s",
+		config.CONFIG_ENV_VAR,
+		describeConfigPath(config.DefaultUserPath()),
+	)
+}
+
+// Creates the `config` subcommand and its children
+func newConfigCommand(options *globalOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Check the configuration files that define named targets",
+		Long: fmt.Sprintf("Checks the configuration files that define named targets, which can be specified in place of a selector.\n"+
+			"The system-wide configuration file is %s, and targets in the per-user configuration file\n"+
+			"(%s) replace system-wide targets with the same name.", config.SystemPath(), describeConfigPath(config.UserPath())),
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:          "validate [file...]",
+		Short:        "Check the configuration files for errors and print the named targets they define",
+		Args:         cobra.ArbitraryArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate(options.config, args)
+		},
+	})
+
+	return command
+}
+
+// Checks the specified configuration files (or the system-wide and per-user configuration files if none are specified)
+// for errors, printing the named targets that they define
+func runConfigValidate(userPath string, files []string) error {
+
+	// Determine which files to check
+	// (Missing files are only an error if they were specified explicitly, since both default files are optional)
+	explicit := len(files) > 0
+	if !explicit {
+		if userPath == "" {
+			userPath = config.UserPath()
+		}
+		files = []string{config.SystemPath()}
+		if userPath != "" {
+			files = append(files, userPath)
+		}
+	}
+
+	// Check each file, including the syntax of each target's selector
+	invalid := 0
+	for index, path := range files {
+		if index > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Checking the configuration file \"%s\"...\n", path)
+
+		targets, err := config.LoadFile(path)
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			fmt.Println("The file does not exist, skipping.")
+			continue
+		} else if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			invalid++
+			continue
+		}
+
+		if len(targets) == 0 {
+			fmt.Println("The file does not define any named targets.")
+		}
+		for _, target := range targets {
+			fmt.Printf("- %s\n", describeTarget(target))
+			if _, err := selector.Parse(target.Selector); err != nil {
+				fmt.Printf("  Error: %v\n", err)
+				invalid++
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("found %d error(s) in the configuration files", invalid)
+	}
+	return nil
+}
+
+// Returns the named target that an expression refers to, or nil if it is not the name of a target defined in the
+// configuration files
+func resolveNamedTarget(userPath string, expression string) (*config.Target, error) {
+	loaded, err := config.Load(userPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v (run `bootnext config validate` for details)", errInvalidConfig, err)
+	}
+	return loaded.Targets[expression], nil
+}
+
+// Applies the settings of a named target to the flags that were not specified explicitly on the command line
+// (Flags always take precedence over the settings in configuration files)
+func applyTargetSettings(cmd *cobra.Command, target *config.Target, noReboot *bool, delay *time.Duration, selection *matchOptions) {
+	if target.NoReboot != nil && !cmd.Flags().Changed("no-reboot") {
+		*noReboot = *target.NoReboot
+	}
+	if target.Delay != nil && !cmd.Flags().Changed("delay") {
+		*delay = *target.Delay
+	}
+	if target.First != nil && !cmd.Flags().Changed("first") {
+		selection.first = *target.First
+	}
+	selection.fingerprint = target.Fingerprint
+}
+
+// Returns a description of a named target and its settings, suitable for displaying to the user
+func describeTarget(target *config.Target) string {
+	settings := []string{fmt.Sprintf("selector: %s", target.Selector)}
+	if target.Fingerprint != "" {
+		settings = append(settings, fmt.Sprintf("fingerprint: %s", target.Fingerprint))
+	}
+	if target.NoReboot != nil {
+		settings = append(settings, fmt.Sprintf("noReboot: %v", *target.NoReboot))
+	}
+	if target.Delay != nil {
+		settings = append(settings, fmt.Sprintf("delay: %v", *target.Delay))
+	}
+	if target.First != nil {
+		settings = append(settings, fmt.Sprintf("first: %v", *target.First))
+	}
+	return fmt.Sprintf("%s: {%s}", target.Name, strings.Join(settings, ", "))
+}
+
+// Returns the path to a configuration file for display, or a generic description if the path could not be determined
+func describeConfigPath(path string) string {
+	if path == "" {
+		return "the user's configuration directory"
+	}
+	return path
+}
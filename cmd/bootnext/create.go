@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The values of the command-line flags for the `create` subcommand
+type createOptions struct {
+
+	// The path to the EFI binary on the EFI System Partition
+	loader string
+
+	// The description for the new boot entry
+	label string
+
+	// The selector used to identify the existing boot entry whose partition the loader resides on
+	espFrom string
+
+	// Specifies whether to select the first boot entry matching `espFrom` if the ranking rules do not narrow them down to one
+	first bool
+
+	// Specifies whether the boot entry should be deleted by `bootnext cleanup` after it has been used
+	once bool
+
+	// Specifies whether the `--force` flag was specified
+	force bool
+
+	// Specifies whether the `--dry-run` flag was specified
+	dryRun bool
+
+	// Specifies whether the `--no-reboot` flag was specified
+	noReboot bool
+
+	// The delay before rebooting
+	delay time.Duration
+}
+
+// Creates the `create` subcommand
+func newCreateCommand(options *globalOptions) *cobra.Command {
+	create := &createOptions{}
+	command := &cobra.Command{
+		Use:   "create --loader path --label description",
+		Short: "Create a boot entry for an EFI binary on the EFI System Partition and set BootNext to it",
+		Long: "Creates a new boot entry for an EFI binary that does not already have one (e.g. a UEFI shell or an installer),\n" +
+			"sets the BootNext variable to the new boot entry and reboots. The boot entry is not added to BootOrder.\n\n" +
+			"The partition containing the EFI binary is identified from the device path of an existing boot entry,\n" +
+			"which defaults to the boot entry that the system was booted from.",
+		Example:      "  bootnext create --loader \\EFI\\tools\\shellx64.efi --label \"UEFI Shell\" --once",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if create.delay < 0 {
+				return fmt.Errorf("the reboot delay must not be negative")
+			}
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runCreate(backend, options.noElevate, create)
+		},
+	}
+
+	// Define the command-line flags for the subcommand
+	command.Flags().StringVar(&create.loader, "loader", "", "The path to the EFI binary, relative to the root of the EFI System Partition")
+	command.Flags().StringVar(&create.label, "label", "", "The description for the new boot entry")
+	command.Flags().StringVar(&create.espFrom, "esp-from", "", "A regular expression or selector matching an existing boot entry on the same partition as the EFI binary")
+	command.Flags().BoolVar(&create.first, "first", false, "If multiple boot entries match --esp-from and the ranking rules do not narrow them down to one, select the first of them")
+	command.Flags().BoolVar(&create.once, "once", false, "Record the boot entry so that `bootnext cleanup` deletes it after it has been used")
+	command.Flags().BoolVar(&create.force, "force", false, "Create the boot entry even if the EFI binary is missing, is built for a different architecture or would be rejected by Secure Boot")
+	command.Flags().BoolVar(&create.dryRun, "dry-run", false, "Describe the boot entry that would be created but do not make any changes to the system")
+	command.Flags().BoolVar(&create.noReboot, "no-reboot", false, "Do not automatically reboot after setting the BootNext variable")
+	command.Flags().DurationVar(&create.delay, "delay", 0, "Schedule the reboot to take place after the specified delay (e.g. 5m) rather than immediately")
+	command.MarkFlagRequired("loader")
+	command.MarkFlagRequired("label")
+
+	return command
+}
+
+// Identifies the partition containing the EFI binary from the device path of an existing boot entry
+func findLoaderPartition(backend uefi.Backend, entries []uefi.BootEntry, espFrom string, first bool) (*uefi.BootEntry, error) {
+
+	// If the user specified a boot entry then use its partition, refusing to guess between multiple matches
+	if espFrom != "" {
+		entry, err := resolveTarget(backend, entries, listPartitionsForAnnotation(), espFrom, matchOptions{first: first})
+		if err != nil {
+			return nil, err
+		} else if entry.HardDrive() == nil {
+			return nil, fmt.Errorf("the device path of boot entry \"%s\" does not identify a GPT partition", entry.Description)
+		}
+		return entry, nil
+	}
+
+	// Prefer the boot entry that the system was booted from, falling back to the first boot entry with a GPT partition
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+	if entry := uefi.FindBootEntry(entries, status.BootCurrent); entry != nil && entry.HardDrive() != nil {
+		return entry, nil
+	}
+	for index := range entries {
+		if entries[index].HardDrive() != nil {
+			return &entries[index], nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not identify the EFI System Partition from the device paths of the existing boot entries (use --esp-from to select a boot entry on the same partition as the EFI binary)")
+}
+
+// Creates a boot entry for an EFI binary, sets BootNext to it and reboots unless requested otherwise
+func runCreate(backend uefi.Backend, noElevate bool, options *createOptions) error {
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !options.dryRun, noElevate); err != nil {
+		return err
+	}
+
+	// Identify the partition containing the EFI binary
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	source, err := findLoaderPartition(backend, entries, options.espFrom, options.first)
+	if err != nil {
+		return err
+	}
+
+	// Build the load option for the new boot entry
+	option := uefi.NewLoaderLoadOption(options.label, source.HardDrive(), options.loader)
+	path, err := uefi.ParseDevicePath(option.FilePathList)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using the partition from boot entry \"%s\"\n", source.Description)
+	fmt.Printf("New boot entry: Description: \"%s\", Device Path: \"%s\"\n", option.Description, path.String())
+
+	// Verify that the EFI binary exists, matches the firmware architecture and will be permitted by Secure Boot
+	if err := verifyLoader(backend, &uefi.BootEntry{Description: option.Description, DevicePath: path}, options.force); err != nil {
+		return err
+	}
+
+	// Don't create the boot entry if we are performing a dry run
+	if options.dryRun {
+		return nil
+	}
+
+	// Create the boot entry
+	fmt.Println("Creating the boot entry...")
+	entry, err := backend.CreateBootEntry(option)
+	if err != nil {
+		return fmt.Errorf("failed to create boot entry: %v", err)
+	}
+	fmt.Printf("Created boot entry with ID \"%s\"\n", entry.ID)
+
+	// Record the boot entry so that it can be deleted after it has been used
+	if options.once {
+		if err := recordOneShotEntry(entry); err != nil {
+			return fmt.Errorf("failed to record one-shot boot entry: %v", err)
+		}
+	}
+
+	// Set the value of the BootNext variable to the new boot entry's identifier
+	fmt.Println("Setting the BootNext variable...")
+	if err := backend.SetBootNext(entry); err != nil {
+		return fmt.Errorf("failed to set BootNext variable value: %v", err)
+	}
+	recordSnapshot(backend)
+
+	// Determine whether we are triggering a reboot
+	if !options.noReboot {
+		if err := rebootSystem(backend, options.delay); err != nil {
+			return fmt.Errorf("failed to reboot: %v", err)
+		}
+	}
+
+	return nil
+}
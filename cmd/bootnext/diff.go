@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/output"
+	"github.com/tensorworks/bootnext/internal/state"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The name of the state record that holds the boot configuration after the last change that bootnext made
+const lastSnapshotRecord = "last-snapshot"
+
+// The exit code used by `bootnext diff` when the boot configuration differs from the snapshot
+const driftExitCode = 2
+
+// Records the current boot configuration as the baseline for `bootnext diff`
+// (Failing to record a snapshot does not cause an otherwise successful operation to fail, and snapshots are not
+// recorded for backends that only simulate NVRAM variables, since they would replace the host system's baseline)
+func recordSnapshot(backend uefi.Backend) {
+	if backend.IsVirtual() {
+		return
+	}
+
+	if err := saveSnapshot(backend); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record a snapshot of the UEFI boot configuration: %v\n", err)
+	}
+}
+
+// Captures the current boot configuration and saves it as the baseline for `bootnext diff`
+func saveSnapshot(backend uefi.Backend) error {
+	snapshot, err := uefi.CreateBackup(backend.RawVariables())
+	if err != nil {
+		return err
+	}
+	return state.Save(lastSnapshotRecord, snapshot)
+}
+
+// Creates the `diff` subcommand
+func newDiffCommand(options *globalOptions) *cobra.Command {
+	save := false
+	command := &cobra.Command{
+		Use:   "diff [snapshot]",
+		Short: "Compare the UEFI boot configuration against a snapshot, exiting with code 2 if it has changed",
+		Long: "Compares the current boot entries, BootOrder and Timeout against a backup file created by `bootnext backup`,\n" +
+			"or against the boot configuration that bootnext recorded after the last change it made if no file is specified.\n" +
+			"Reports added, removed, moved, renamed and reordered boot entries and device path changes, and exits with code 2\n" +
+			"if any differences are found.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if save && len(args) > 0 {
+				return fmt.Errorf("a snapshot file cannot be specified in conjunction with --save")
+			}
+			format, err := parseOutputFormat(options.output)
+			if err != nil {
+				return err
+			}
+			if save && format.IsStructured() {
+				return fmt.Errorf("%w: the --output flag cannot be used in conjunction with --save", errInvalidArguments)
+			}
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			path := ""
+			if len(args) > 0 {
+				path = args[0]
+			}
+			return runDiff(backend, options.noElevate, path, format, save)
+		},
+	}
+
+	command.Flags().StringVarP(&options.output, "output", "o", string(output.Table), outputUsage(""))
+	command.Flags().BoolVar(&save, "save", false, "Record the current boot configuration as the baseline for future comparisons")
+	return command
+}
+
+// Loads the snapshot from the specified backup file, or the snapshot recorded by bootnext if the path is empty
+func loadSnapshot(path string) (*uefi.Backup, error) {
+	if path != "" {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot file \"%s\": %v", path, err)
+		}
+		snapshot, err := uefi.ParseBackup(contents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot file \"%s\": %v", path, err)
+		}
+		return snapshot, nil
+	}
+
+	snapshot := &uefi.Backup{}
+	if exists, err := state.Load(lastSnapshotRecord, snapshot); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("bootnext has not recorded a snapshot of the UEFI boot configuration (specify a snapshot file, or run `bootnext diff --save` to record one)")
+	}
+	return snapshot, nil
+}
+
+// Prints the differences between a snapshot and the current boot configuration
+func printDiff(diff *uefi.BackupDiff) {
+	if !diff.HasChanges() {
+		fmt.Println("The UEFI boot configuration matches the snapshot.")
+		return
+	}
+
+	fmt.Println("The UEFI boot configuration differs from the snapshot:")
+	for _, entry := range diff.Added {
+		fmt.Printf("+ Added boot entry \"%s\" (\"%s\")\n", entry.ID, entry.Description)
+	}
+	for _, entry := range diff.Removed {
+		fmt.Printf("- Removed boot entry \"%s\" (\"%s\")\n", entry.ID, entry.Description)
+	}
+	for _, entry := range diff.Moved {
+		fmt.Printf("~ Moved boot entry \"%s\" (\"%s\") to \"%s\"\n", entry.OldID, entry.Description, entry.NewID)
+	}
+	for _, entry := range diff.Renamed {
+		fmt.Printf("~ Renamed boot entry \"%s\" from \"%s\" to \"%s\"\n", entry.ID, entry.OldDescription, entry.NewDescription)
+	}
+	for _, entry := range diff.DevicePathChanged {
+		fmt.Printf("~ Changed device path of boot entry \"%s\" (\"%s\") from \"%s\" to \"%s\"\n", entry.ID, entry.Description, entry.OldDevicePath, entry.NewDevicePath)
+	}
+	for _, entry := range diff.Reordered {
+		fmt.Printf("~ Moved boot entry \"%s\" (\"%s\") in BootOrder from %s to %s\n", entry.ID, entry.Description, describePosition(entry.OldPosition), describePosition(entry.NewPosition))
+	}
+	if diff.Timeout != nil {
+		fmt.Printf("~ Changed Timeout from %s to %s\n", describeTimeout(diff.Timeout.Old), describeTimeout(diff.Timeout.New))
+	}
+}
+
+// Returns a description of a one-based position in BootOrder, where zero indicates absence
+func describePosition(position int) string {
+	if position == 0 {
+		return "absent"
+	}
+	return fmt.Sprintf("position %d", position)
+}
+
+// Returns a description of a Timeout value
+func describeTimeout(timeout *uint16) string {
+	if timeout == nil {
+		return "not set"
+	}
+	return fmt.Sprintf("%d seconds", *timeout)
+}
+
+// Compares the current boot configuration against a snapshot, returning an error with a distinct exit code if it differs
+func runDiff(backend uefi.Backend, noElevate bool, path string, format output.Format, save bool) error {
+
+	// Verify that we are able to read UEFI NVRAM variables
+	if err := checkPrerequisites(backend, false, noElevate); err != nil {
+		return err
+	}
+
+	// If we are just recording the baseline then stop here
+	if save {
+		if err := saveSnapshot(backend); err != nil {
+			return fmt.Errorf("failed to record a snapshot of the UEFI boot configuration: %v", err)
+		}
+		fmt.Println("Recorded the current UEFI boot configuration as the baseline for future comparisons.")
+		return nil
+	}
+
+	// Load the snapshot and capture the current boot configuration
+	snapshot, err := loadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	current, err := uefi.CreateBackup(backend.RawVariables())
+	if err != nil {
+		return fmt.Errorf("failed to capture UEFI boot configuration: %v", err)
+	}
+
+	// Compare the boot configurations and print the differences
+	diff := uefi.DiffBackups(snapshot, current)
+	if format.IsStructured() {
+		if err := writeDiffDocument(format, &diffDocument{SchemaVersion: output.SCHEMA_VERSION, BackupDiff: diff}); err != nil {
+			return err
+		}
+	} else {
+		printDiff(diff)
+	}
+
+	// Report drift with a distinct exit code so that monitoring tools can distinguish it from failures
+	if diff.HasChanges() {
+		return &exitCodeError{code: driftExitCode, err: fmt.Errorf("the UEFI boot configuration has changed since the snapshot was taken")}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/disk"
+)
+
+// Creates the `esp` subcommand
+func newESPCommand(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "esp",
+		Short: "Print the EFI System Partitions on the system's disks, along with their device nodes and mount points",
+		Long: "Identifies every EFI System Partition by its GPT partition type GUID and prints its unique partition GUID,\n" +
+			"device node, disk model and serial number, and the path at which it is mounted.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runESP()
+		},
+	}
+}
+
+// Prints the EFI System Partitions on the system's disks
+func runESP() error {
+
+	// Identify the EFI System Partitions
+	partitions, err := disk.ListPartitions()
+	if err != nil {
+		return fmt.Errorf("failed to list disk partitions: %v", err)
+	}
+	esps := disk.FindESPs(partitions)
+	if len(esps) == 0 {
+		fmt.Println("No EFI System Partitions were found.")
+		return nil
+	}
+
+	// Print the details of each partition
+	fmt.Println("Detected the following EFI System Partitions:")
+	for index := range esps {
+		fmt.Printf("- GUID: \"%s\", Partition: %s\n", esps[index].GUID, describePartition(&esps[index]))
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Requests that the firmware boot into its setup user interface and reboots, or clears an existing request
+func runFirmwareSetup(backend uefi.Backend, clear bool, dryRun bool, noElevate bool, noReboot bool, delay time.Duration) error {
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !dryRun, noElevate); err != nil {
+		return err
+	}
+
+	// If we are clearing an existing request then we don't need to check for firmware support or reboot
+	if clear {
+		if dryRun {
+			fmt.Println("Dry run: not clearing the request to boot into the firmware setup screen.")
+			return nil
+		}
+		if err := backend.SetFirmwareSetup(false); err != nil {
+			return fmt.Errorf("failed to clear the request to boot into the firmware setup screen: %v", err)
+		}
+		fmt.Println("Cleared any pending request to boot into the firmware setup screen.")
+		return nil
+	}
+
+	// Verify that the firmware advertises support for booting into its setup user interface
+	supported, err := backend.IsFirmwareSetupSupported()
+	if err != nil {
+		return fmt.Errorf("failed to determine whether the firmware supports booting into its setup screen: %v", err)
+	} else if !supported {
+		return fmt.Errorf("the firmware does not support booting into its setup screen (EFI_OS_INDICATIONS_BOOT_TO_FW_UI is not set in OsIndicationsSupported)")
+	}
+	fmt.Println("The firmware supports booting into its setup screen.")
+
+	// Don't modify the OsIndications variable or reboot if we are performing a dry run
+	if dryRun {
+		return nil
+	}
+
+	// Set the bit in the OsIndications variable
+	fmt.Println("Setting the OsIndications variable...")
+	if err := backend.SetFirmwareSetup(true); err != nil {
+		return fmt.Errorf("failed to set OsIndications variable value: %v", err)
+	}
+
+	// Determine whether we are triggering a reboot
+	if !noReboot {
+		if err := rebootSystem(backend, delay); err != nil {
+			return fmt.Errorf("failed to reboot: %v", err)
+		}
+	}
+
+	return nil
+}
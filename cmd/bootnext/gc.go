@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Represents a boot entry that has been identified as stale, along with the reason why
+type staleEntry struct {
+	Entry  uefi.BootEntry
+	Reason string
+}
+
+// Creates the `gc` subcommand
+func newGCCommand(options *globalOptions) *cobra.Command {
+	yes := false
+	command := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete stale boot entries that reference missing partitions or files, or that are duplicates",
+		Long: "Identifies boot entries whose partition no longer exists on any disk, whose loader file is missing from a mounted\n" +
+			"EFI System Partition, or that are exact duplicates of other boot entries, and deletes them after confirmation.\n" +
+			"Deleted boot entries are also removed from BootOrder.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runGC(backend, options.noElevate, yes)
+		},
+	}
+
+	command.Flags().BoolVarP(&yes, "yes", "y", false, "Delete the stale boot entries without prompting for confirmation")
+	return command
+}
+
+// Identifies stale boot entries, skipping the boot entry that the system was booted from
+// (Boot entries are checked in BootOrder priority so that the highest-priority copy of any duplicate is retained, and
+// missing partitions are only reported when the list of partitions is non-empty, since an empty list indicates that
+// partition enumeration failed rather than that every disk has been removed)
+func findStaleEntries(entries []uefi.BootEntry, status *uefi.BootStatus, partitions []disk.Partition) ([]staleEntry, error) {
+
+	// Sort the boot entries by their position in BootOrder, followed by any boot entries that are not in BootOrder
+	position := func(id string) int {
+		if index := uefi.IndexInOrder(status.BootOrder, id); index != -1 {
+			return index
+		}
+		return len(status.BootOrder)
+	}
+	sorted := append([]uefi.BootEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return position(sorted[i].ID) < position(sorted[j].ID) })
+
+	stale := []staleEntry{}
+	kept := []uefi.BootEntry{}
+	for _, entry := range sorted {
+
+		// Boot entries without a decoded device path cannot be checked
+		// (This is the case for tool backends that do not report raw device paths)
+		if entry.FilePathList == nil {
+			continue
+		}
+
+		// The boot entry that the system was booted from is never flagged, but is still retained so that any
+		// lower-priority duplicates of it are identified
+		if entry.ID == status.BootCurrent {
+			kept = append(kept, entry)
+			continue
+		}
+
+		// Determine whether the boot entry is a duplicate of a higher-priority boot entry
+		reason := ""
+		for _, other := range kept {
+			if entry.Description == other.Description &&
+				entry.Active == other.Active &&
+				bytes.Equal(entry.FilePathList, other.FilePathList) &&
+				bytes.Equal(entry.OptionalData, other.OptionalData) {
+				reason = fmt.Sprintf("duplicate of boot entry \"%s\"", other.ID)
+				break
+			}
+		}
+
+		// Determine whether the partition or the loader file referenced by the boot entry is missing
+		if hardDrive := entry.HardDrive(); reason == "" && hardDrive != nil {
+			guid, _ := hardDrive.PartitionGUID()
+			if partition := disk.FindPartition(partitions, guid); partition == nil && len(partitions) > 0 {
+				reason = fmt.Sprintf("partition %s does not exist on any disk", guid)
+			} else if loader := entry.LoaderPath(); partition != nil && loader != "" && partition.MountPoint != "" {
+				exists, err := partition.FileExists(loader)
+				if err != nil {
+					return nil, fmt.Errorf("failed to check for loader file \"%s\": %v", loader, err)
+				} else if !exists {
+					reason = fmt.Sprintf("loader file \"%s\" does not exist on partition %s", loader, guid)
+				}
+			}
+		}
+
+		if reason != "" {
+			stale = append(stale, staleEntry{Entry: entry, Reason: reason})
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+
+	return stale, nil
+}
+
+// Prompts the user to answer a yes or no question, treating anything other than an explicit "yes" as "no"
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Identifies stale boot entries and deletes them after confirmation, removing them from BootOrder
+func runGC(backend uefi.Backend, noElevate bool, yes bool) error {
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, true, noElevate); err != nil {
+		return err
+	}
+
+	// Retrieve the list of UEFI boot entries and the boot manager variables
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+
+	// Retrieve the list of partitions
+	// (Failing to list partitions is not fatal, but boot entries can then only be checked for duplicates)
+	partitions, err := disk.ListPartitions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to list disk partitions, not checking for missing partitions or loader files: %v\n", err)
+		partitions = nil
+	} else if len(partitions) == 0 {
+		fmt.Fprint(os.Stderr, "Warning: no disk partitions were found, not checking for missing partitions or loader files\n")
+	}
+
+	// Identify and print the stale boot entries
+	stale, err := findStaleEntries(entries, status, partitions)
+	if err != nil {
+		return err
+	} else if len(stale) == 0 {
+		fmt.Println("No stale boot entries were found.")
+		return nil
+	}
+	fmt.Println("Found the following stale boot entries:")
+	for _, candidate := range stale {
+		fmt.Printf("- ID: \"%s\", Description: \"%s\" (%s)\n", candidate.Entry.ID, candidate.Entry.Description, candidate.Reason)
+	}
+
+	// Prompt for confirmation unless it has already been provided
+	if !yes && !confirm(fmt.Sprintf("\nDelete %d stale boot entries?", len(stale))) {
+		fmt.Println("Not deleting any boot entries.")
+		return nil
+	}
+
+	// Delete the stale boot entries
+	for _, candidate := range stale {
+		fmt.Printf("Deleting boot entry \"%s\"...\n", candidate.Entry.ID)
+		if err := backend.DeleteBootEntry(candidate.Entry); err != nil {
+			return fmt.Errorf("failed to delete boot entry \"%s\": %v", candidate.Entry.ID, err)
+		}
+	}
+
+	// Remove the deleted boot entries from BootOrder
+	// (Some backends already do this when deleting boot entries, so we re-read the current value first)
+	status, err = backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+	order := []string{}
+	for _, id := range status.BootOrder {
+		deleted := false
+		for _, candidate := range stale {
+			deleted = deleted || candidate.Entry.ID == id
+		}
+		if !deleted {
+			order = append(order, id)
+		}
+	}
+	if !uefi.EqualOrders(order, status.BootOrder) {
+		fmt.Println("Removing the deleted boot entries from the BootOrder variable...")
+		if err := backend.SetBootOrder(order); err != nil {
+			return fmt.Errorf("failed to set BootOrder variable value: %v", err)
+		}
+	}
+
+	recordSnapshot(backend)
+	return nil
+}
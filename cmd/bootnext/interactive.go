@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tensorworks/bootnext/internal/picker"
+	"github.com/tensorworks/bootnext/internal/terminal"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Lets the user choose the target boot entry from an interactive list, then sets BootNext to it once they have confirmed
+// the change, rebooting unless requested otherwise
+// (If a selector is specified then only the boot entries that match it are listed)
+func runInteractive(backend uefi.Backend, expression string, dryRun bool, noElevate bool, noReboot bool, force bool, delay time.Duration) error {
+
+	// Interactive mode requires a terminal for both input and output
+	if !terminal.IsInteractive() {
+		return errors.New("interactive mode requires a terminal (specify a selector to choose the target boot entry instead)")
+	}
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !dryRun, noElevate); err != nil {
+		return err
+	}
+
+	// Retrieve the list of UEFI boot entries and the boot manager variables that identify the current, next and default entries
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+
+	// Narrow down the list using the selector, if one was specified
+	if expression != "" {
+		matches, err := findMatchingEntries(entries, expression)
+		if err != nil {
+			return err
+		}
+		entries = []uefi.BootEntry{}
+		for _, match := range matches {
+			entries = append(entries, *match)
+		}
+	}
+	if len(entries) == 0 {
+		return errors.New("there are no UEFI boot entries to choose from")
+	}
+
+	// Let the user choose the target boot entry
+	console, err := terminal.OpenConsole()
+	if err != nil {
+		return fmt.Errorf("failed to configure the terminal for interactive input: %v", err)
+	}
+	entry, err := pickBootEntry(console, entries, status)
+	console.Close()
+	if errors.Is(err, picker.ErrCancelled) {
+		fmt.Println("No boot entry was selected.")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read interactive input: %v", err)
+	}
+	fmt.Printf("Selected boot entry: \"%s\"\n", entry.Description)
+
+	// Verify that the boot entry's loader exists, matches the firmware architecture and will be permitted by Secure Boot
+	if err := verifyLoader(backend, entry, force); err != nil {
+		return err
+	}
+
+	// Ask the user to confirm the change before modifying the BootNext variable or rebooting
+	if !dryRun && !confirm(describeConfirmation(entry, noReboot, delay)) {
+		fmt.Println("The BootNext variable was not modified.")
+		return nil
+	}
+
+	return applyBootNext(backend, entry, dryRun, noReboot, delay)
+}
+
+// Displays the list of boot entries on a terminal and waits for the user to choose one, marking the entries that the
+// system was booted from, will boot from next, and boots from by default
+func pickBootEntry(term terminal.Terminal, entries []uefi.BootEntry, status *uefi.BootStatus) (*uefi.BootEntry, error) {
+	items := []picker.Item{}
+	for _, entry := range entries {
+		item := picker.Item{Label: fmt.Sprintf("%s  %s", entry.ID, entry.Description)}
+		if entry.ID == status.BootCurrent {
+			item.Tags = append(item.Tags, "current")
+		}
+		if entry.ID == status.BootNext {
+			item.Tags = append(item.Tags, "next")
+		}
+		if len(status.BootOrder) > 0 && entry.ID == status.BootOrder[0] {
+			item.Tags = append(item.Tags, "default")
+		}
+		items = append(items, item)
+	}
+
+	index, err := picker.Run(term, "Select the boot entry to use for the next boot:", items)
+	if err != nil {
+		return nil, err
+	}
+	return &entries[index], nil
+}
+
+// Returns the question that asks the user to confirm the change to the BootNext variable
+func describeConfirmation(entry *uefi.BootEntry, noReboot bool, delay time.Duration) string {
+	switch {
+	case noReboot:
+		return fmt.Sprintf("Set BootNext to \"%s\"?", entry.Description)
+	case delay > 0:
+		return fmt.Sprintf("Set BootNext to \"%s\" and reboot in %v?", entry.Description, delay)
+	default:
+		return fmt.Sprintf("Set BootNext to \"%s\" and reboot now?", entry.Description)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/output"
+	"github.com/tensorworks/bootnext/internal/selector"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Prints an individual boot entry or load option as a list item, followed by the details of the partition that its
+// device path references if the partition exists on the system
+func printBootEntry(entry uefi.BootEntry, partitions []disk.Partition) {
+	fmt.Print("- ID: \"", entry.ID, "\", Description: \"", entry.Description, "\"")
+	if entry.Kind != "" {
+		fmt.Print(", Kind: \"", entry.Kind, "\"")
+	}
+	if fingerprint := selector.Fingerprint(&entry); fingerprint != "" {
+		fmt.Print(", Fingerprint: \"", fingerprint, "\"")
+	}
+	if entry.DevicePathText != "" {
+		fmt.Print(", Device Path: \"", entry.DevicePathText, "\"")
+	}
+	fmt.Print("\n")
+
+	if hardDrive := entry.HardDrive(); hardDrive != nil {
+		guid, _ := hardDrive.PartitionGUID()
+		if partition := disk.FindPartition(partitions, guid); partition != nil {
+			fmt.Printf("  Partition: %s\n", describePartition(partition))
+		}
+	}
+}
+
+// Prints the boot entries that match a selector expression, or all of the boot entries if the expression is empty
+func printMatchingEntries(entries []uefi.BootEntry, partitions []disk.Partition, expression string) error {
+	if expression == "" {
+		fmt.Println("Detected the following UEFI boot entries:")
+		for _, entry := range entries {
+			printBootEntry(entry, partitions)
+		}
+		return nil
+	}
+
+	matches, err := selectEntries(entries, partitions, expression)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Detected the following UEFI boot entries matching %s:\n", describeSelector(expression))
+	if len(matches) == 0 {
+		fmt.Println("(none)")
+	}
+	for _, entry := range matches {
+		printBootEntry(*entry, partitions)
+	}
+	return nil
+}
+
+// Lists the partitions on the system so that boot entries can be annotated with their details
+// (Boot entries can still be listed without these details, so any errors are ignored)
+func listPartitionsForAnnotation() []disk.Partition {
+	partitions, err := disk.ListPartitions()
+	if err != nil {
+		return nil
+	}
+	return partitions
+}
+
+// Returns a description of a partition's device node, disk and mount point, suitable for displaying to the user
+func describePartition(partition *disk.Partition) string {
+	details := []string{}
+	if partition.Disk.Model != "" {
+		details = append(details, partition.Disk.Model)
+	}
+	if partition.Disk.Serial != "" {
+		details = append(details, "serial "+partition.Disk.Serial)
+	}
+
+	description := fmt.Sprintf("%s on disk %s", partition.Device, partition.Disk.Device)
+	if len(details) > 0 {
+		description += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+	if partition.MountPoint != "" {
+		description += fmt.Sprintf(", mounted at %s", partition.MountPoint)
+	} else {
+		description += ", not mounted"
+	}
+
+	return description
+}
+
+// Creates the `list` subcommand
+func newListCommand(options *globalOptions) *cobra.Command {
+	kind := ""
+	command := &cobra.Command{
+		Use:   "list [selector...]",
+		Short: "Print the UEFI boot entries or other load options, along with their order variables",
+		Long: "Prints the Boot####, Driver####, SysPrep#### or PlatformRecovery#### load options, along with the\n" +
+			"BootOrder, DriverOrder or SysPrepOrder variable that determines their priority.\n" +
+			"If a selector is specified then only the load options that match it are printed.",
+		Args:         cobra.ArbitraryArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// Determine which kinds of load options to list
+			kinds := uefi.LoadOptionKinds
+			if !strings.EqualFold(kind, "all") {
+				parsed, err := uefi.ParseLoadOptionKind(kind)
+				if err != nil {
+					return err
+				}
+				kinds = []*uefi.LoadOptionKind{parsed}
+			}
+
+			format, err := parseOutputFormat(options.output)
+			if err != nil {
+				return err
+			}
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			if format.IsStructured() {
+				if err := checkPrerequisites(backend, false, options.noElevate); err != nil {
+					return err
+				}
+				return writeLoadOptionList(backend, kinds, listPartitionsForAnnotation(), strings.Join(args, " "), format)
+			}
+			return runList(backend, options.noElevate, kinds, strings.Join(args, " "))
+		},
+	}
+
+	command.Flags().StringVar(&kind, "kind", "boot", "The kind of load options to list (boot, driver, sysprep, recovery or all)")
+	command.Flags().StringVarP(&options.output, "output", "o", string(output.Table), outputUsage(""))
+	return command
+}
+
+// Prints the load options of each of the specified kinds that match a selector expression as a machine-readable
+// document, along with their order variables and the BootCurrent and BootNext variables
+// (All of the load options are printed if the expression is empty)
+func writeLoadOptionList(backend uefi.Backend, kinds []*uefi.LoadOptionKind, partitions []disk.Partition, expression string, format output.Format) error {
+	document := &listDocument{
+		SchemaVersion: output.SCHEMA_VERSION,
+		Selector:      expression,
+		Orders:        []orderRecord{},
+		Entries:       []entryRecord{},
+	}
+
+	// Retrieve the boot manager variables so that the boot entries they reference can be marked
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to retrieve UEFI boot manager variables: %v\n", err)
+		status = nil
+	} else {
+		document.BootCurrent = status.BootCurrent
+		document.BootNext = status.BootNext
+	}
+
+	for _, kind := range kinds {
+
+		// Retrieve the load options and their order
+		options, err := backend.ListLoadOptions(kind)
+		if err != nil {
+			return fmt.Errorf("failed to list %s#### load options: %v", kind.Prefix, err)
+		}
+		order, err := backend.LoadOptionOrder(kind)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the order of %s#### load options: %v", kind.Prefix, err)
+		}
+		if kind.OrderVariable != "" {
+			document.Orders = append(document.Orders, orderRecord{Variable: kind.OrderVariable, IDs: append([]string{}, order...)})
+		}
+
+		// Filter the load options using the selector, if one was specified
+		matches := []*uefi.BootEntry{}
+		if expression != "" {
+			matches, err = selectEntries(options, partitions, expression)
+			if err != nil {
+				return err
+			}
+		} else {
+			for index := range options {
+				matches = append(matches, &options[index])
+			}
+		}
+
+		// Only boot entries are referenced by BootCurrent and BootNext
+		kindStatus := status
+		if kind != uefi.BootOptions {
+			kindStatus = nil
+		}
+		for _, option := range matches {
+			document.Entries = append(document.Entries, newEntryRecord(kind, option, partitions, order, kindStatus))
+		}
+	}
+
+	return writeListDocument(format, document)
+}
+
+// Prints the load options of each of the specified kinds that match a selector expression, along with their order
+// (All of the load options are printed if the expression is empty)
+func runList(backend uefi.Backend, noElevate bool, kinds []*uefi.LoadOptionKind, expression string) error {
+
+	// Verify that we are able to read UEFI NVRAM variables
+	if err := checkPrerequisites(backend, false, noElevate); err != nil {
+		return err
+	}
+
+	partitions := listPartitionsForAnnotation()
+	for index, kind := range kinds {
+		if index > 0 {
+			fmt.Println()
+		}
+
+		// Retrieve the load options and their order
+		options, err := backend.ListLoadOptions(kind)
+		if err != nil {
+			return fmt.Errorf("failed to list %s#### load options: %v", kind.Prefix, err)
+		}
+		order, err := backend.LoadOptionOrder(kind)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the order of %s#### load options: %v", kind.Prefix, err)
+		}
+
+		// Filter the load options using the selector, if one was specified
+		matches := []*uefi.BootEntry{}
+		if expression != "" {
+			matches, err = selectEntries(options, partitions, expression)
+			if err != nil {
+				return err
+			}
+		} else {
+			for index := range options {
+				matches = append(matches, &options[index])
+			}
+		}
+
+		// Print the load options
+		fmt.Printf("%s#### load options:\n", kind.Prefix)
+		if len(matches) == 0 {
+			fmt.Println("(none)")
+		}
+		for _, option := range matches {
+			printBootEntry(*option, partitions)
+		}
+
+		// Print the order, noting when it is implicit
+		if kind.OrderVariable != "" {
+			fmt.Printf("%s: %s\n", kind.OrderVariable, describeOrder(order))
+		} else if len(options) > 0 {
+			fmt.Printf("Processed in numerical order: %s\n", describeOrder(order))
+		}
+	}
+
+	return nil
+}
+
+// Returns a comma-separated list of identifiers, or "not set" if the list is empty
+func describeOrder(order []string) string {
+	if len(order) == 0 {
+		return "not set"
+	}
+	return strings.Join(order, ", ")
+}
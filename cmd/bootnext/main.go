@@ -1,117 +1,98 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"regexp"
-	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tensorworks/bootnext/internal/constants"
-	"github.com/tensorworks/bootnext/internal/elevate"
+	"github.com/tensorworks/bootnext/internal/output"
 	"github.com/tensorworks/bootnext/internal/process"
-	"github.com/tensorworks/bootnext/internal/reboot"
+	"github.com/tensorworks/bootnext/internal/terminal"
 	"github.com/tensorworks/bootnext/internal/uefi"
 )
 
-func run(pattern string, dryRun bool, listOnly bool, noElevate bool, noReboot bool) error {
+// Lists the UEFI boot entries and sets BootNext to the entry that matches the selector, rebooting unless requested otherwise
+func run(backend uefi.Backend, pattern string, dryRun bool, listOnly bool, noElevate bool, noReboot bool, force bool, selection matchOptions, delay time.Duration, format output.Format) error {
 
-	// Verify that the operating system has been booted in UEFI mode
-	enabled, err := uefi.IsUEFIEnabled()
-	if err != nil {
-		return fmt.Errorf("failed to query system UEFI status: %v", err)
-	} else if !enabled {
-		return fmt.Errorf("unsupported system configuration: the operating system has not been booted in UEFI mode")
-	}
-
-	// Verify that all of the system tools we require for interacting with UEFI NVRAM variables are available
-	requiredTools := uefi.RequiredTools()
-	for _, tool := range requiredTools {
-		if _, err := exec.LookPath(tool); err != nil {
-			return fmt.Errorf("a required application was not found in the system PATH: %v", tool)
-		}
-	}
-
-	// Determine whether we require elevated privileges
-	// (We need them for writing to NVRAM variables under Linux, and for both reading and writing under Windows)
-	requireElevation := (!dryRun && !listOnly) || runtime.GOOS == "windows"
-
-	// Determine whether the process is running with insufficient privileges
-	if requireElevation && !elevate.IsElevated() {
-
-		// Determine whether we should automatically request elevated privileges
-		if !noElevate {
-
-			// Re-run the process with elevated privileges and propagate the exit code
-			exitCode, err := elevate.RunElevated()
-			if err != nil {
-				return fmt.Errorf("failed to re-launch the process with elevated privileges: %v", err)
-			} else {
-				os.Exit(exitCode)
-			}
-
-		} else {
-			fmt.Print("Warning: running without elevated privileges, access to UEFI NVRAM variables may be denied.\n\n")
-		}
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !dryRun && !listOnly, noElevate); err != nil {
+		return err
 	}
 
 	// Retrieve the list of UEFI boot entries
-	entries, err := uefi.ListBootEntries()
+	entries, err := backend.ListBootEntries()
 	if err != nil {
 		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
 	}
 
+	// If we are just listing the boot entries then print the entries that match the selector (if any) and stop here
+	partitions := listPartitionsForAnnotation()
+	if listOnly && format.IsStructured() {
+		return writeLoadOptionList(backend, []*uefi.LoadOptionKind{uefi.BootOptions}, partitions, pattern, format)
+	} else if listOnly {
+		return printMatchingEntries(entries, partitions, pattern)
+	}
+
 	// Print the list of boot entries
 	fmt.Println("Detected the following UEFI boot entries:")
 	for _, entry := range entries {
-		fmt.Print("- ID: \"", entry.ID, "\", Description: \"", entry.Description, "\"\n")
+		printBootEntry(entry, partitions)
 	}
 
-	// If we are just listing the boot entries then stop here
-	if listOnly {
-		return nil
+	// Identify the boot entry that matches the selector, refusing to guess between multiple matches
+	fmt.Printf("\nMatching boot entries against %s\n", describeSelector(pattern))
+	entry, err := resolveTarget(backend, entries, partitions, pattern, selection)
+	if err != nil {
+		return err
 	}
 
-	// Compile the regular expression pattern supplied by the user, enabling case-insensitive matching
-	regex, err := regexp.Compile(fmt.Sprintf("(?i)%s", pattern))
-	if err != nil {
-		return fmt.Errorf("failed to compile regular expression \"%s\": %v", pattern, err)
+	// Print the matching boot entry
+	fmt.Printf("Found matching boot entry: \"%s\"\n", entry.Description)
+
+	// Verify that the boot entry's loader exists, matches the firmware architecture and will be permitted by Secure Boot
+	if err := verifyLoader(backend, entry, force); err != nil {
+		return err
 	}
 
-	// Identify the first boot entry that matches the pattern
-	fmt.Printf("\nMatching boot entries against regular expression \"%s\"\n", pattern)
-	for _, entry := range entries {
-		if regex.MatchString(entry.Description) {
-
-			// Print the matching boot entry
-			fmt.Printf("Found matching boot entry: \"%s\"\n", entry.Description)
-
-			// Don't modify the BootNext variable or reboot if we are performing a dry run
-			if !dryRun {
-
-				// Set the value of the BootNext variable to the entry's identifier
-				fmt.Println("Setting the BootNext variable...")
-				if err := uefi.SetBootNext(entry); err != nil {
-					return fmt.Errorf("failed to set BootNext variable value: %v", err)
-				}
-
-				// Determine whether we are triggering a reboot
-				if !noReboot {
-					fmt.Println("Rebooting now...")
-					if err := reboot.Reboot(); err != nil {
-						return fmt.Errorf("failed to reboot: %v", err)
-					}
-				}
-			}
+	return applyBootNext(backend, entry, dryRun, noReboot, delay)
+}
+
+// Sets BootNext to the target boot entry and reboots unless requested otherwise
+func applyBootNext(backend uefi.Backend, entry *uefi.BootEntry, dryRun bool, noReboot bool, delay time.Duration) error {
+
+	// Don't modify the BootNext variable or reboot if we are performing a dry run
+	if !dryRun {
 
-			return nil
+		// Set the value of the BootNext variable to the entry's identifier
+		fmt.Println("Setting the BootNext variable...")
+		if err := backend.SetBootNext(*entry); err != nil {
+			return fmt.Errorf("failed to set BootNext variable value: %v", err)
+		}
+		recordSnapshot(backend)
+
+		// Determine whether we are triggering a reboot
+		if !noReboot {
+			if err := rebootSystem(backend, delay); err != nil {
+				return fmt.Errorf("failed to reboot: %v", err)
+			}
 		}
 	}
 
-	// If we reach this point then none of the boot entries matched the pattern
-	return fmt.Errorf("could not find any UEFI boot entries matching the pattern \"%s\"", pattern)
+	return nil
+}
+
+// An error that causes the process to exit with a specific exit code
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
 }
 
 func main() {
@@ -127,59 +108,179 @@ func main() {
 			"This facilitates quickly switching to another OS without modifying the default boot order.",
 		}, "\n"),
 
-		Use: "bootnext pattern",
+		Use: "bootnext selector...",
 
 		SilenceUsage: true,
 
+		// Errors are printed by `reportError()`, so that they can be formatted as JSON when requested
+		SilenceErrors: true,
+
 		Example: strings.Join([]string{
 			"  bootnext windows   Selects the Windows Boot Manager and boots into it",
 			"  bootnext ubuntu    Selects the GRUB bootloader installed by Ubuntu Linux and boots into it",
 			"  bootnext USB       Selects the first available bootable USB device and boots into it",
+			"  bootnext -i        Displays the list of boot entries to choose from interactively",
+			"  bootnext type:usb and not disk:sda",
+			"                     Selects the first boot entry for a USB device that is not on the disk /dev/sda",
+			"  bootnext --list path:~shimx64",
+			"                     Prints the boot entries whose loader path contains \"shimx64\"",
 		}, "\n"),
 	}
 
-	// Inject the usage information for our command's positional arguments
+	// Inject the usage information for our command's positional arguments, preserving the default template for subcommands
 	patternUsage := strings.Join([]string{
-		"  pattern            A regular expression that will be used to select the target boot entry",
-		"                     (case insensitive)",
+		"  selector           A regular expression that will be used to select the target boot entry",
+		"                     (case insensitive), or a selector expression built from the terms id:,",
+		"                     index:, desc:, path:, partuuid:, disk:, type:, kind: and fingerprint:,",
+		"                     combined using and, or, not and parentheses (multiple arguments are joined",
+		"                     with spaces), or the name of a target defined in a configuration file",
 	}, "\n")
-	template := command.UsageTemplate()
-	template = strings.Replace(template, "\nFlags:\n", fmt.Sprintf("\nPositional Arguments:\n%s\n\nFlags:\n", patternUsage), 1)
+	defaultTemplate := command.UsageTemplate()
+	template := strings.Replace(defaultTemplate, "\nFlags:\n", fmt.Sprintf("\nPositional Arguments:\n%s\n\nFlags:\n", patternUsage), 1)
 	command.SetUsageTemplate(template)
 
+	// Define the command-line flags that are shared by all commands
+	options := &globalOptions{}
+	command.PersistentFlags().StringVar(&options.backend, "backend", "", backendUsage())
+	command.PersistentFlags().StringVar(&options.config, "config", "", configUsage())
+	command.PersistentFlags().BoolVar(&options.noElevate, "no-elevate", false, "Do not automatically prompt for elevated privileges when required")
+	command.PersistentFlags().BoolVar(&options.pause, "pause", false, "Pause for input when the application is finished running")
+
 	// Define the command-line flags for our command
 	dryRun := command.Flags().Bool("dry-run", false, "Describe the actions that would be performed but do not make any changes to the system")
-	listOnly := command.Flags().Bool("list", false, "Print the list of UEFI boot entries but do not set the BootNext variable")
-	noElevate := command.Flags().Bool("no-elevate", false, "Do not automatically prompt for elevated privileges when required")
+	listOnly := command.Flags().Bool("list", false, "Print the list of UEFI boot entries (or those matching the selector, if one is specified) but do not set the BootNext variable")
 	noReboot := command.Flags().Bool("no-reboot", false, "Do not automatically reboot after setting the BootNext variable")
-	pause := command.Flags().Bool("pause", false, "Pause for input when the application is finished running")
+	delay := command.Flags().Duration("delay", 0, "Schedule the reboot to take place after the specified delay (e.g. 5m) rather than immediately")
+	firmwareSetup := command.Flags().Bool("firmware-setup", false, "Reboot into the UEFI firmware setup screen instead of a boot entry")
+	force := command.Flags().Bool("force", false, "Set the BootNext variable even if the target boot entry's loader is missing, is built for a different architecture or would be rejected by Secure Boot")
+	interactive := command.Flags().BoolP("interactive", "i", false, "Choose the target boot entry from an interactive list (the default when no arguments are specified in a terminal)")
+	selection := matchOptions{}
+	command.Flags().BoolVar(&selection.first, "first", false, "If multiple boot entries match the selector and the ranking rules do not narrow them down to one, select the first of them")
+	command.Flags().BoolVar(&selection.explain, "explain", false, "Print which fields of each boot entry matched the selector and how the target boot entry was chosen")
+	clear := command.Flags().Bool("clear", false, "When used with --firmware-setup, cancel a pending request to boot into the firmware setup screen")
+	command.Flags().StringVarP(&options.output, "output", "o", string(output.Table), outputUsage(" when used with --list"))
+
+	// Report errors in command-line flags as invalid arguments, so that they have a stable error code in JSON output
+	command.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %v", errInvalidArguments, err)
+	})
 
 	// Wire up the validation logic for our command-line flags and positional arguments
+	command.Args = cobra.ArbitraryArgs
 	command.RunE = func(cmd *cobra.Command, args []string) error {
 
-		// If no flags or arguments were specified then print the usage message
+		// If no flags or arguments were specified then display the interactive list of boot entries when running in a
+		// terminal, and print the usage message otherwise
 		if len(os.Args) < 2 {
-			cmd.Help()
-			return nil
+			if !terminal.IsInteractive() {
+				cmd.Help()
+				return nil
+			}
+			*interactive = true
+		}
+
+		// Verify that the reboot delay is valid
+		if *delay < 0 {
+			return fmt.Errorf("the reboot delay must not be negative")
+		}
+
+		// Verify that the output format is valid, and is only used in conjunction with `--list`
+		format, err := parseOutputFormat(options.output)
+		if err != nil {
+			return err
+		} else if cmd.Flags().Changed("output") && !*listOnly {
+			return fmt.Errorf("%w: the --output flag can only be used in conjunction with --list", errInvalidArguments)
 		}
 
-		// Verify that a pattern was provided if `--list` was not specified
-		pattern := ""
-		if len(args) > 0 {
-			pattern = args[0]
-		} else if !*listOnly {
-			return fmt.Errorf("a pattern must be specified for selecting the target UEFI boot entry")
+		// Verify that `--clear` is only used in conjunction with `--firmware-setup`
+		if *clear && !*firmwareSetup {
+			return fmt.Errorf("the --clear flag can only be used in conjunction with --firmware-setup")
+		}
+
+		// Verify that interactive mode is not combined with flags that do not select a boot entry
+		if *interactive && (*listOnly || *firmwareSetup) {
+			return fmt.Errorf("the --interactive flag cannot be used in conjunction with --list or --firmware-setup")
+		}
+
+		// Booting into the firmware setup screen does not require a selector
+		if *firmwareSetup {
+			if len(args) > 0 {
+				return fmt.Errorf("a selector cannot be specified in conjunction with --firmware-setup")
+			}
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runFirmwareSetup(backend, *clear, *dryRun, options.noElevate, *noReboot, *delay)
+		}
+
+		// If the selector is the name of a target defined in the configuration files then use the target's selector,
+		// and apply its settings to any flags that were not specified explicitly
+		pattern := strings.Join(args, " ")
+		if pattern != "" {
+			target, err := resolveNamedTarget(options.config, pattern)
+			if err != nil {
+				return err
+			}
+			if target != nil && !format.IsStructured() {
+				fmt.Printf("Using the named target %s defined in \"%s\"\n\n", describeTarget(target), target.Source)
+			}
+			if target != nil {
+				pattern = target.Selector
+				applyTargetSettings(cmd, target, noReboot, delay, &selection)
+			}
+		}
+
+		// Verify that a selector was provided if `--list` or `--interactive` was not specified
+		if len(args) == 0 && !*listOnly && !*interactive {
+			return fmt.Errorf("a selector must be specified for selecting the target UEFI boot entry")
+		}
+
+		// Create the backend that will be used to access UEFI variables
+		backend, err := selectBackend(options.backend)
+		if err != nil {
+			return err
+		}
+
+		// Let the user choose the target boot entry if interactive mode was requested
+		if *interactive {
+			return runInteractive(backend, pattern, *dryRun, options.noElevate, *noReboot, *force, *delay)
 		}
 
 		// Process the provided input values and propagate any errors
-		return run(pattern, *dryRun, *listOnly, *noElevate, *noReboot)
+		return run(backend, pattern, *dryRun, *listOnly, options.noElevate, *noReboot, *force, selection, *delay, format)
 	}
 
-	// Execute the command
+	// Register our subcommands
+	for _, subcommand := range []*cobra.Command{
+		newStatusCommand(options),
+		newCancelCommand(options),
+		newOrderCommand(options),
+		newCreateCommand(options),
+		newCleanupCommand(options),
+		newGCCommand(options),
+		newBackupCommand(options),
+		newRestoreCommand(options),
+		newDiffCommand(options),
+		newTimeoutCommand(options),
+		newListCommand(options),
+		newESPCommand(options),
+		newConfigCommand(options),
+	} {
+		subcommand.SetUsageTemplate(defaultTemplate)
+		command.AddCommand(subcommand)
+	}
+
+	// Execute the command, reporting any error and propagating any specific exit code that was requested
 	err := command.Execute()
 	if err != nil {
-		process.ExitWithPause(1, *pause)
+		reportError(err, options.output)
+	}
+	if exitErr := (*exitCodeError)(nil); errors.As(err, &exitErr) {
+		process.ExitWithPause(exitErr.code, options.pause)
+	} else if err != nil {
+		process.ExitWithPause(1, options.pause)
 	}
 
-	process.ExitWithPause(0, *pause)
+	process.ExitWithPause(0, options.pause)
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/selector"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The errors reported when a selector does not identify exactly one boot entry
+var (
+	errNoMatch        = errors.New("could not find any UEFI boot entries matching")
+	errAmbiguousMatch = errors.New("multiple UEFI boot entries match")
+)
+
+// The maximum number of boot entries to suggest when a selector does not match any boot entries
+const maxSuggestions = 3
+
+// Options that control how the target boot entry is chosen from the boot entries that match a selector
+type matchOptions struct {
+
+	// Specifies whether to select the first matching boot entry if the ranking rules do not narrow the matches to one
+	first bool
+
+	// Specifies whether to print the reasons that each boot entry matched and how the target boot entry was chosen
+	explain bool
+
+	// The fingerprint that the target boot entry is expected to have, if it was recorded along with the selector
+	fingerprint string
+}
+
+// A rule that narrows down multiple matching boot entries by preferring those with a particular attribute
+type rankingRule struct {
+
+	// A description of the boot entries that the rule prefers (e.g. "active")
+	description string
+
+	// Determines whether a boot entry has the preferred attribute
+	keep func(candidate *selector.Candidate) bool
+}
+
+// Returns the boot entries that match the supplied selector expression, which is either a case-insensitive regular
+// expression that matches boot entry descriptions or an expression using the selector syntax (e.g. `type:usb and not id:0003`)
+func findMatchingEntries(entries []uefi.BootEntry, expression string) ([]*uefi.BootEntry, error) {
+	return selectEntries(entries, listPartitionsForAnnotation(), expression)
+}
+
+// Returns the boot entries that match the supplied selector expression, using a list of partitions that has already
+// been retrieved to resolve the partitions that the boot entries reference
+func selectEntries(entries []uefi.BootEntry, partitions []disk.Partition, expression string) ([]*uefi.BootEntry, error) {
+
+	// Parse the selector expression supplied by the user
+	parsed, err := selector.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evaluate the selector against each of the boot entries, along with the partitions that they reference
+	matches := []*uefi.BootEntry{}
+	for _, candidate := range selector.Filter(parsed, selector.NewCandidates(entries, partitions)) {
+		matches = append(matches, candidate.Entry)
+	}
+	return matches, nil
+}
+
+// Identifies the single boot entry that matches the supplied selector expression, which is the target for BootNext
+//
+// If multiple boot entries match then the ranking rules are applied in turn to narrow them down, and the boot entries
+// that remain are reported as an error unless `options.first` is set, in which case the first of them is selected.
+func resolveTarget(backend uefi.Backend, entries []uefi.BootEntry, partitions []disk.Partition, expression string, options matchOptions) (*uefi.BootEntry, error) {
+
+	// Identify every boot entry that matches the selector
+	parsed, err := selector.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	candidates := selector.NewCandidates(entries, partitions)
+	matches := selector.Filter(parsed, candidates)
+	if options.explain {
+		explainMatches(parsed, matches)
+	}
+
+	// If the fingerprint of the target boot entry was recorded then re-resolve the target by its fingerprint when the
+	// matching boot entries no longer have it (e.g. because the firmware has renumbered the boot entries)
+	if options.fingerprint != "" {
+		matches = reconcileFingerprint(matches, candidates, expression, options.fingerprint)
+	}
+	if len(matches) == 0 {
+		return nil, noMatchError(parsed, candidates, expression)
+	} else if len(matches) == 1 {
+		return matches[0].Entry, nil
+	}
+
+	// Retrieve the boot order so that boot entries in the boot order can be preferred
+	order := []string{}
+	if status, err := backend.GetBootStatus(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to retrieve the boot order for ranking the matching boot entries: %v\n", err)
+	} else {
+		order = status.BootOrder
+	}
+
+	// Apply the ranking rules in turn until only one boot entry remains
+	// (Rules that would exclude every remaining boot entry are skipped, since they do not help to distinguish them)
+	for _, rule := range rankingRules(order) {
+		ranked := []selector.Candidate{}
+		for index := range matches {
+			if rule.keep(&matches[index]) {
+				ranked = append(ranked, matches[index])
+			}
+		}
+		if options.explain {
+			fmt.Printf("Ranking rule \"%s\" kept %d of %d matching boot entries\n", rule.description, len(ranked), len(matches))
+		}
+		if len(ranked) == 1 {
+			fmt.Printf("%d boot entries match %s, selecting the only one that is %s\n", len(matches), describeSelector(expression), rule.description)
+			return ranked[0].Entry, nil
+		} else if len(ranked) > 0 {
+			matches = ranked
+		}
+	}
+
+	// Refuse to choose between the remaining boot entries unless the user asked for the first of them
+	if options.first {
+		fmt.Fprintf(os.Stderr, "Warning: %d boot entries match %s, selecting the first because --first was specified\n", len(matches), describeSelector(expression))
+		return matches[0].Entry, nil
+	}
+	fmt.Printf("The following %d boot entries all match %s:\n", len(matches), describeSelector(expression))
+	for index := range matches {
+		printBootEntry(*matches[index].Entry, partitions)
+		fmt.Printf("  Details: %s\n", describeCandidate(&matches[index], order))
+	}
+	return nil, fmt.Errorf("%w %s (use a more specific selector, or --first to select the first of them)", errAmbiguousMatch, describeSelector(expression))
+}
+
+// Narrows down the boot entries that match a selector to those with the expected fingerprint, or replaces them with the
+// boot entries that have the expected fingerprint if none of them do, warning the user that the selector is out of date
+// (The matching boot entries are returned unchanged if no boot entries have the expected fingerprint)
+func reconcileFingerprint(matches []selector.Candidate, candidates []selector.Candidate, expression string, fingerprint string) []selector.Candidate {
+	withFingerprint := func(candidates []selector.Candidate) []selector.Candidate {
+		kept := []selector.Candidate{}
+		for _, candidate := range candidates {
+			if strings.EqualFold(selector.Fingerprint(candidate.Entry), fingerprint) {
+				kept = append(kept, candidate)
+			}
+		}
+		return kept
+	}
+
+	if pinned := withFingerprint(matches); len(pinned) > 0 {
+		return pinned
+	}
+
+	relocated := withFingerprint(candidates)
+	if len(relocated) == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: no boot entry has the fingerprint \"%s\" recorded for %s, using the boot entries that match it instead\n", fingerprint, describeSelector(expression))
+		return matches
+	}
+
+	ids := []string{}
+	for _, candidate := range relocated {
+		ids = append(ids, candidate.Entry.ID)
+	}
+	fmt.Fprintf(
+		os.Stderr,
+		"Warning: the boot entries matching %s no longer have the recorded fingerprint \"%s\", using the boot entries that do instead (ID %s)\n",
+		describeSelector(expression),
+		fingerprint,
+		strings.Join(ids, ", "),
+	)
+	return relocated
+}
+
+// Prints the reasons that each matching boot entry matched a selector
+func explainMatches(parsed selector.Selector, matches []selector.Candidate) {
+	if len(matches) == 1 {
+		fmt.Printf("1 boot entry matches the selector \"%s\":\n", parsed)
+	} else {
+		fmt.Printf("%d boot entries match the selector \"%s\":\n", len(matches), parsed)
+	}
+	for index := range matches {
+		fmt.Printf("- ID: \"%s\", Description: \"%s\"\n", matches[index].Entry.ID, matches[index].Entry.Description)
+		for _, reason := range parsed.Explain(&matches[index]) {
+			fmt.Printf("  %s\n", reason)
+		}
+	}
+}
+
+// Prints the boot entries that most closely resemble a selector that did not match any boot entries, and returns the
+// error reporting that no boot entries matched
+func noMatchError(parsed selector.Selector, candidates []selector.Candidate, expression string) error {
+	suggestions := selector.Suggest(parsed, candidates, maxSuggestions)
+	if len(suggestions) > 0 {
+		fmt.Println("Did you mean one of the following boot entries?")
+		for _, suggestion := range suggestions {
+			fmt.Printf(
+				"- ID: \"%s\", Description: \"%s\" (its %s \"%s\" resembles \"%s\")\n",
+				suggestion.Candidate.Entry.ID,
+				suggestion.Candidate.Entry.Description,
+				suggestion.Label,
+				suggestion.Value,
+				suggestion.Query,
+			)
+		}
+	}
+	return fmt.Errorf("%w %s", errNoMatch, describeSelector(expression))
+}
+
+// Returns a rule for each of the criteria used to narrow down multiple matching boot entries, in order of precedence
+func rankingRules(order []string) []rankingRule {
+	return []rankingRule{
+		{
+			description: "active",
+			keep:        func(candidate *selector.Candidate) bool { return candidate.Entry.Active },
+		},
+		{
+			description: "present on disk",
+			keep:        isPresentOnDisk,
+		},
+		{
+			description: "in BootOrder",
+			keep:        func(candidate *selector.Candidate) bool { return uefi.IndexInOrder(order, candidate.Entry.ID) != -1 },
+		},
+	}
+}
+
+// Determines whether the partition and loader referenced by a boot entry exist on the system's disks
+// (Boot entries that do not reference a partition, such as network boot entries, are always considered to be present,
+// as are loaders on partitions that are not mounted, since their files cannot be inspected)
+func isPresentOnDisk(candidate *selector.Candidate) bool {
+	if !candidate.ReferencesPartition() {
+		return true
+	} else if candidate.Partition == nil {
+		return false
+	} else if candidate.Partition.MountPoint == "" || candidate.LoaderPath() == "" {
+		return true
+	}
+	exists, err := candidate.Partition.FileExists(candidate.LoaderPath())
+	return err != nil || exists
+}
+
+// Returns a description of the attributes that the ranking rules use to distinguish between boot entries
+func describeCandidate(candidate *selector.Candidate, order []string) string {
+	details := []string{}
+	if candidate.Entry.Active {
+		details = append(details, "active")
+	} else {
+		details = append(details, "inactive")
+	}
+
+	if position := uefi.IndexInOrder(order, candidate.Entry.ID); position != -1 {
+		details = append(details, fmt.Sprintf("position %d in BootOrder", position+1))
+	} else {
+		details = append(details, "not in BootOrder")
+	}
+
+	if candidate.ReferencesPartition() && candidate.Partition == nil {
+		details = append(details, "partition not found on any disk")
+	} else if !isPresentOnDisk(candidate) {
+		details = append(details, "loader missing from disk")
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// Returns a description of a selector expression that distinguishes plain patterns from selector syntax
+func describeSelector(expression string) string {
+	if selector.IsPattern(expression) {
+		return fmt.Sprintf("the pattern \"%s\"", expression)
+	}
+	return fmt.Sprintf("the selector \"%s\"", expression)
+}
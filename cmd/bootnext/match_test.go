@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/selector"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The partition GUIDs referenced by the test boot entries
+const (
+	mountedGUID   = "7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13"
+	unmountedGUID = "5d3c2b1a-0f9e-4d8c-b7a6-958473625140"
+	missingGUID   = "0b9c8d7e-6f5a-4b3c-a2d1-e0f9a8b7c6d5"
+)
+
+// Returns a boot entry with a text device path, as reported by backends that do not decode device paths
+func testEntry(id string, description string, active bool, devicePath string) uefi.BootEntry {
+	return uefi.BootEntry{ID: id, Description: description, Active: active, DevicePathText: devicePath}
+}
+
+// Returns the text device path of a loader on a GPT partition
+func diskPath(guid string, loader string) string {
+	return "HD(1,GPT," + guid + ",0x800,0x12c000)/File(" + loader + ")"
+}
+
+// Creates the boot entries, partitions and backend that target resolution is tested against
+// (The mounted partition is backed by a temporary directory that holds the loaders of the boot entries that are present
+// on disk, and the backend's BootOrder lists a subset of the boot entries)
+func newMatchFixture(t *testing.T) ([]uefi.BootEntry, []disk.Partition, uefi.Backend) {
+	t.Helper()
+
+	// Create the loaders that are present on the mounted partition
+	mountPoint := t.TempDir()
+	for _, loader := range []string{"EFI/ubuntu/shimx64.efi", "EFI/debian/grubx64.efi", "EFI/Microsoft/Boot/bootmgfw.efi"} {
+		path := filepath.Join(mountPoint, filepath.FromSlash(loader))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("loader"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	partitions := []disk.Partition{
+		{GUID: uefi.MustParseGUID(mountedGUID), MountPoint: mountPoint},
+		{GUID: uefi.MustParseGUID(unmountedGUID)},
+	}
+
+	entries := []uefi.BootEntry{
+		testEntry("0001", "ubuntu", true, diskPath(mountedGUID, `\EFI\ubuntu\shimx64.efi`)),
+		testEntry("0002", "ubuntu", false, diskPath(mountedGUID, `\EFI\ubuntu\shimx64.efi`)),
+		testEntry("0003", "ubuntu", true, diskPath(missingGUID, `\EFI\ubuntu\shimx64.efi`)),
+		testEntry("0004", "debian", true, diskPath(mountedGUID, `\EFI\debian\shimx64.efi`)),
+		testEntry("0005", "debian", true, diskPath(mountedGUID, `\EFI\debian\grubx64.efi`)),
+		testEntry("0006", "Windows Boot Manager", true, diskPath(mountedGUID, `\EFI\Microsoft\Boot\bootmgfw.efi`)),
+		testEntry("0007", "Windows Boot Manager", true, diskPath(mountedGUID, `\EFI\Microsoft\Boot\bootmgfw.efi`)),
+		testEntry("0008", "UEFI: PXE IPv4", true, "PciRoot(0x0)/Pci(0x1c,0x0)/MAC(525400123456,1)/IPv4(0.0.0.0)"),
+		testEntry("0009", "UEFI: PXE IPv4", true, "PciRoot(0x0)/Pci(0x1c,0x1)/MAC(525400abcdef,1)/IPv4(0.0.0.0)"),
+		testEntry("000A", "fedora", true, diskPath(unmountedGUID, `\EFI\fedora\shimx64.efi`)),
+		testEntry("000B", "fedora", true, diskPath(missingGUID, `\EFI\fedora\shimx64.efi`)),
+	}
+
+	backend := &uefi.NativeBackend{Store: uefi.NewMemoryStore()}
+	if err := backend.SetBootOrder([]string{"0006", "0001", "0003", "0004", "0008", "0009"}); err != nil {
+		t.Fatal(err)
+	}
+	return entries, partitions, backend
+}
+
+func TestResolveTarget(t *testing.T) {
+	entries, partitions, backend := newMatchFixture(t)
+	fingerprint := func(id string) string {
+		for index := range entries {
+			if entries[index].ID == id {
+				return selector.Fingerprint(&entries[index])
+			}
+		}
+		t.Fatalf("no boot entry has the ID %s", id)
+		return ""
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		options    matchOptions
+		expected   string
+		err        error
+	}{
+		{
+			name:       "single match",
+			expression: "id:2",
+			expected:   "0002",
+		},
+		{
+			name:       "single match is not ranked",
+			expression: "id:3",
+			expected:   "0003",
+		},
+		{
+			name:       "active boot entries are preferred",
+			expression: "ubuntu and not id:3",
+			expected:   "0001",
+		},
+		{
+			name:       "boot entries present on disk are preferred when several are active",
+			expression: "ubuntu",
+			expected:   "0001",
+		},
+		{
+			name:       "boot entries whose loader exists are preferred",
+			expression: "debian",
+			expected:   "0005",
+		},
+		{
+			name:       "boot entries on unmounted partitions are considered present",
+			expression: "fedora",
+			expected:   "000A",
+		},
+		{
+			name:       "boot entries in BootOrder are preferred when the other rules tie",
+			expression: "windows",
+			expected:   "0006",
+		},
+		{
+			name:       "ties are refused",
+			expression: "pxe",
+			err:        errAmbiguousMatch,
+		},
+		{
+			name:       "ties are broken by --first",
+			expression: "pxe",
+			options:    matchOptions{first: true},
+			expected:   "0008",
+		},
+		{
+			name:       "--first does not override the ranking rules",
+			expression: "ubuntu",
+			options:    matchOptions{first: true},
+			expected:   "0001",
+		},
+		{
+			name:       "no matches",
+			expression: "nonexistent",
+			err:        errNoMatch,
+		},
+		{
+			name:       "invalid selector",
+			expression: "desc:~(",
+			err:        selector.ErrInvalidSelector,
+		},
+		{
+			name:       "fingerprint narrows the matches",
+			expression: "debian",
+			options:    matchOptions{fingerprint: fingerprint("0004")},
+			expected:   "0004",
+		},
+		{
+			name:       "fingerprint re-resolves a selector that no longer matches the target",
+			expression: "id:5",
+			options:    matchOptions{fingerprint: fingerprint("0004")},
+			expected:   "0004",
+		},
+		{
+			name:       "fingerprint re-resolves a selector that no longer matches anything",
+			expression: "id:20",
+			options:    matchOptions{fingerprint: fingerprint("0005")},
+			expected:   "0005",
+		},
+		{
+			name:       "re-resolved boot entries are ranked",
+			expression: "id:8",
+			options:    matchOptions{fingerprint: fingerprint("0001")},
+			expected:   "0001",
+		},
+		{
+			name:       "unknown fingerprint falls back to the matches",
+			expression: "id:5",
+			options:    matchOptions{fingerprint: "0123456789abcdef"},
+			expected:   "0005",
+		},
+		{
+			name:       "unknown fingerprint does not resolve a selector that matches nothing",
+			expression: "id:20",
+			options:    matchOptions{fingerprint: "0123456789abcdef"},
+			err:        errNoMatch,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			target, err := resolveTarget(backend, entries, partitions, testCase.expression, testCase.options)
+			if testCase.err != nil {
+				if !errors.Is(err, testCase.err) {
+					t.Fatalf("got error %v, expected %v", err, testCase.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if target.ID != testCase.expected {
+				t.Errorf("got %s, expected %s", target.ID, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestRankingRules(t *testing.T) {
+	entries, partitions, _ := newMatchFixture(t)
+	order := []string{"0006", "0001", "0003", "0004", "0008", "0009"}
+	candidates := selector.NewCandidates(entries, partitions)
+
+	// The rules are applied in order of precedence: active, then present on disk, then in BootOrder
+	expected := []struct {
+		description string
+		kept        string
+	}{
+		{description: "active", kept: "0001,0003,0004,0005,0006,0007,0008,0009,000A,000B"},
+		{description: "present on disk", kept: "0001,0002,0005,0006,0007,0008,0009,000A"},
+		{description: "in BootOrder", kept: "0001,0003,0004,0006,0008,0009"},
+	}
+	rules := rankingRules(order)
+	if len(rules) != len(expected) {
+		t.Fatalf("got %d rules, expected %d", len(rules), len(expected))
+	}
+	for index, rule := range rules {
+		kept := ""
+		for candidate := range candidates {
+			if rule.keep(&candidates[candidate]) {
+				if kept != "" {
+					kept += ","
+				}
+				kept += candidates[candidate].Entry.ID
+			}
+		}
+		if rule.description != expected[index].description || kept != expected[index].kept {
+			t.Errorf("rule %d: got %q keeping %s, expected %q keeping %s", index, rule.description, kept, expected[index].description, expected[index].kept)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Identifies the single boot entry that matches a selector expression, refusing to guess between multiple matches
+type entryResolver func(expression string) (*uefi.BootEntry, error)
+
+// Computes a new boot order from the current boot order and the list of boot entries
+type orderTransform func(order []string, entries []uefi.BootEntry, resolve entryResolver) ([]string, error)
+
+// Creates the `order` subcommand and its children
+func newOrderCommand(options *globalOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "order",
+		Short: "Show or modify the permanent boot order in the BootOrder variable",
+		Long: "Shows or modifies the UEFI BootOrder variable, which determines the default boot entry for every boot.\n" +
+			"Unlike setting BootNext, changes to the boot order persist across reboots.",
+	}
+
+	// Define the command-line flags that are shared by the subcommands that modify the boot order
+	dryRun := command.PersistentFlags().Bool("dry-run", false, "Print the current and new boot order but do not modify the BootOrder variable")
+	selection := matchOptions{}
+	command.PersistentFlags().BoolVar(&selection.first, "first", false, "If multiple boot entries match a selector and the ranking rules do not narrow them down to one, select the first of them")
+	command.PersistentFlags().BoolVar(&selection.explain, "explain", false, "Print which fields of each boot entry matched a selector and how the boot entry was chosen")
+
+	// Creates a subcommand that modifies the boot order using the supplied transform
+	newModifyCommand := func(use string, short string, args cobra.PositionalArgs, transform func(args []string) orderTransform) *cobra.Command {
+		return &cobra.Command{
+			Use:          use,
+			Short:        short,
+			Args:         args,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				backend, err := selectBackend(options.backend)
+				if err != nil {
+					return err
+				}
+				return runOrderChange(backend, options.noElevate, *dryRun, selection, transform(args))
+			},
+		}
+	}
+
+	// Creates a transform that applies an operation to the boot entry matching the supplied selector
+	// (Multiple arguments are joined with spaces, so that selector expressions do not need to be quoted)
+	forMatchingEntry := func(operation func(order []string, id string) ([]string, error)) func(args []string) orderTransform {
+		return func(args []string) orderTransform {
+			return func(order []string, entries []uefi.BootEntry, resolve entryResolver) ([]string, error) {
+				entry, err := resolve(strings.Join(args, " "))
+				if err != nil {
+					return nil, err
+				}
+				return operation(order, entry.ID)
+			}
+		}
+	}
+
+	command.AddCommand(
+		&cobra.Command{
+			Use:          "show",
+			Short:        "Print the boot entries in the BootOrder variable",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				backend, err := selectBackend(options.backend)
+				if err != nil {
+					return err
+				}
+				return runOrderShow(backend, options.noElevate)
+			},
+		},
+
+		newModifyCommand(
+			"set selector...",
+			"Replace the boot order with the boot entries matching the supplied selectors, in the order specified",
+			cobra.MinimumNArgs(1),
+			func(args []string) orderTransform {
+				return func(order []string, entries []uefi.BootEntry, resolve entryResolver) ([]string, error) {
+					ids := []string{}
+					for _, expression := range args {
+						entry, err := resolve(expression)
+						if err != nil {
+							return nil, err
+						}
+						ids = append(ids, entry.ID)
+					}
+					return uefi.DedupeOrder(ids), nil
+				}
+			},
+		),
+
+		newModifyCommand(
+			"move-to-front selector...",
+			"Move the boot entry matching the selector to the front of the boot order, making it the default",
+			cobra.MinimumNArgs(1),
+			forMatchingEntry(func(order []string, id string) ([]string, error) {
+				return uefi.MoveToFront(order, id), nil
+			}),
+		),
+
+		newModifyCommand(
+			"move-up selector...",
+			"Move the boot entry matching the selector one position earlier in the boot order",
+			cobra.MinimumNArgs(1),
+			forMatchingEntry(uefi.MoveUp),
+		),
+
+		newModifyCommand(
+			"move-down selector...",
+			"Move the boot entry matching the selector one position later in the boot order",
+			cobra.MinimumNArgs(1),
+			forMatchingEntry(uefi.MoveDown),
+		),
+
+		newModifyCommand(
+			"remove selector...",
+			"Remove the boot entry matching the selector from the boot order",
+			cobra.MinimumNArgs(1),
+			forMatchingEntry(uefi.RemoveFromOrder),
+		),
+
+		newModifyCommand(
+			"dedupe",
+			"Remove duplicate boot entries from the boot order, keeping the first occurrence of each",
+			cobra.NoArgs,
+			func(args []string) orderTransform {
+				return func(order []string, entries []uefi.BootEntry, resolve entryResolver) ([]string, error) {
+					return uefi.DedupeOrder(order), nil
+				}
+			},
+		),
+	)
+
+	return command
+}
+
+// Retrieves the list of UEFI boot entries and the current boot order
+func queryBootOrder(backend uefi.Backend) ([]uefi.BootEntry, []string, error) {
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+	return entries, status.BootOrder, nil
+}
+
+// Prints a boot order, along with the descriptions of the boot entries it references
+func printBootOrder(heading string, order []string, entries []uefi.BootEntry) {
+	if len(order) == 0 {
+		fmt.Printf("%s: not set\n", heading)
+		return
+	}
+
+	fmt.Printf("%s:\n", heading)
+	for index, id := range order {
+		fmt.Printf("%d. %s\n", index+1, describeBootEntryID(entries, id))
+	}
+}
+
+// Prints the boot entries in the BootOrder variable
+func runOrderShow(backend uefi.Backend, noElevate bool) error {
+
+	// Verify that we are able to read UEFI NVRAM variables
+	if err := checkPrerequisites(backend, false, noElevate); err != nil {
+		return err
+	}
+
+	// Retrieve and print the boot order
+	entries, order, err := queryBootOrder(backend)
+	if err != nil {
+		return err
+	}
+	printBootOrder("BootOrder", order, entries)
+	return nil
+}
+
+// Computes a new boot order using the supplied transform and writes it to the BootOrder variable, verifying the result
+func runOrderChange(backend uefi.Backend, noElevate bool, dryRun bool, selection matchOptions, transform orderTransform) error {
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, !dryRun, noElevate); err != nil {
+		return err
+	}
+
+	// Retrieve the current boot order
+	entries, current, err := queryBootOrder(backend)
+	if err != nil {
+		return err
+	}
+
+	// Compute the new boot order, resolving selectors in the same manner as the main command
+	// (Ambiguous selectors are refused unless `--first` was specified)
+	partitions := listPartitionsForAnnotation()
+	resolve := func(expression string) (*uefi.BootEntry, error) {
+		return resolveTarget(backend, entries, partitions, expression, selection)
+	}
+	updated, err := transform(current, entries, resolve)
+	if err != nil {
+		return err
+	}
+
+	// Print the boot order before and after the change
+	printBootOrder("Current BootOrder", current, entries)
+	fmt.Println()
+	printBootOrder("New BootOrder", updated, entries)
+	fmt.Println()
+
+	// Don't modify the BootOrder variable if it is unchanged or if we are performing a dry run
+	if uefi.EqualOrders(current, updated) {
+		fmt.Println("The boot order is unchanged.")
+		return nil
+	} else if dryRun {
+		fmt.Println("Dry run: not modifying the BootOrder variable.")
+		return nil
+	}
+
+	// Write the new boot order
+	fmt.Println("Setting the BootOrder variable...")
+	if err := backend.SetBootOrder(updated); err != nil {
+		return fmt.Errorf("failed to set BootOrder variable value: %v", err)
+	}
+
+	// Read the boot order back to verify that the firmware accepted the change
+	if _, written, err := queryBootOrder(backend); err != nil {
+		return fmt.Errorf("failed to verify BootOrder variable value: %v", err)
+	} else if !uefi.EqualOrders(written, updated) {
+		return fmt.Errorf("failed to verify BootOrder variable value: expected %v but read back %v", updated, written)
+	}
+
+	fmt.Println("Verified the new boot order.")
+	recordSnapshot(backend)
+	return nil
+}
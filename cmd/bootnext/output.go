@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/output"
+	"github.com/tensorworks/bootnext/internal/selector"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The error reported when the command-line flags or arguments are invalid
+var errInvalidArguments = errors.New("invalid arguments")
+
+// Describes a boot entry or other load option in machine-readable output
+type entryRecord struct {
+
+	// The kind of load option (boot, driver, sysprep or recovery)
+	OptionType string `json:"optionType"`
+
+	// The fields of the load option (see `uefi.BootEntry`), with raw bytes encoded as hexadecimal strings
+	ID           string `json:"id"`
+	Description  string `json:"description"`
+	Active       bool   `json:"active"`
+	Kind         string `json:"kind"`
+	Fingerprint  string `json:"fingerprint"`
+	DevicePath   string `json:"devicePath"`
+	FilePathList string `json:"filePathList"`
+	OptionalData string `json:"optionalData"`
+
+	// Specifies whether the boot entry is referenced by the BootCurrent and BootNext variables
+	Current bool `json:"current"`
+	Next    bool `json:"next"`
+
+	// The one-based position of the load option in its order variable (e.g. BootOrder), or 0 if it is not listed
+	OrderPosition int `json:"orderPosition"`
+
+	// The partition referenced by the load option's device path, or nil if it was not found on any disk
+	Partition *partitionRecord `json:"partition"`
+}
+
+// Describes the partition referenced by a load option in machine-readable output
+type partitionRecord struct {
+	Device     string `json:"device"`
+	Disk       string `json:"disk"`
+	Model      string `json:"model"`
+	Serial     string `json:"serial"`
+	MountPoint string `json:"mountPoint"`
+}
+
+// Describes the value of a load option order variable in machine-readable output
+type orderRecord struct {
+	Variable string   `json:"variable"`
+	IDs      []string `json:"ids"`
+}
+
+// The document printed by `bootnext --list` and `bootnext list` in machine-readable output
+type listDocument struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Selector      string        `json:"selector"`
+	BootCurrent   string        `json:"bootCurrent"`
+	BootNext      string        `json:"bootNext"`
+	Orders        []orderRecord `json:"orders"`
+	Entries       []entryRecord `json:"entries"`
+}
+
+// The document printed by `bootnext status` in machine-readable output
+type statusDocument struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	BootCurrent   string           `json:"bootCurrent"`
+	BootNext      string           `json:"bootNext"`
+	BootOrder     []string         `json:"bootOrder"`
+	Timeout       *uint16          `json:"timeout"`
+	SecureBoot    secureBootRecord `json:"secureBoot"`
+	Entries       []entryRecord    `json:"entries"`
+}
+
+// The document printed by `bootnext diff` in machine-readable output, which flattens the fields of the diff
+type diffDocument struct {
+	SchemaVersion int `json:"schemaVersion"`
+	*uefi.BackupDiff
+}
+
+// Describes the Secure Boot configuration in machine-readable output
+type secureBootRecord struct {
+
+	// One of "enabled", "disabled", "setup-mode" or "unknown" (if the configuration could not be read)
+	State string `json:"state"`
+
+	// The number of entries in the db and dbx signature databases
+	DBEntries  int `json:"dbEntries"`
+	DBXEntries int `json:"dbxEntries"`
+
+	// The reason the configuration could not be read, if the state is "unknown"
+	Error string `json:"error,omitempty"`
+}
+
+// The document printed to stderr when a command fails in JSON output
+type errorDocument struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Error         errorRecord `json:"error"`
+}
+
+// Describes an error in machine-readable output
+type errorRecord struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// The columns of CSV output for load options, which correspond to the fields of `entryRecord`
+var entryCSVHeader = []string{
+	"schemaVersion",
+	"optionType",
+	"id",
+	"description",
+	"active",
+	"kind",
+	"fingerprint",
+	"devicePath",
+	"filePathList",
+	"optionalData",
+	"current",
+	"next",
+	"orderPosition",
+	"partitionDevice",
+	"partitionDisk",
+	"partitionModel",
+	"partitionSerial",
+	"partitionMountPoint",
+}
+
+// The columns of CSV output for the boot status, which correspond to the fields of `statusDocument`
+var statusCSVHeader = []string{
+	"schemaVersion",
+	"bootCurrent",
+	"bootNext",
+	"bootOrder",
+	"timeout",
+	"secureBoot",
+	"secureBootDBEntries",
+	"secureBootDBXEntries",
+}
+
+// The columns of CSV output for the differences between boot configurations, with one row per change
+// (The old and new columns hold descriptions, device paths, BootOrder positions or Timeout values depending on the kind
+// of change, and are empty for added and removed boot entries)
+var diffCSVHeader = []string{
+	"schemaVersion",
+	"change",
+	"id",
+	"newId",
+	"description",
+	"old",
+	"new",
+}
+
+// Parses the value of the `--output` flag
+func parseOutputFormat(name string) (output.Format, error) {
+	format, err := output.ParseFormat(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidArguments, err)
+	}
+	return format, nil
+}
+
+// Returns the usage text for the `--output` flag, qualified with the context in which the flag applies (if any)
+func outputUsage(context string) string {
+	names := []string{}
+	for _, format := range output.Formats {
+		names = append(names, string(format))
+	}
+	return fmt.Sprintf("The output format%s (%s), where every format other than table prints a versioned, machine-readable document", context, strings.Join(names, ", "))
+}
+
+// Creates the record that describes a load option, marking whether it is referenced by the boot manager variables
+// (The status is nil for kinds of load options other than boot entries, which are not referenced by those variables)
+func newEntryRecord(kind *uefi.LoadOptionKind, entry *uefi.BootEntry, partitions []disk.Partition, order []string, status *uefi.BootStatus) entryRecord {
+	record := entryRecord{
+		OptionType:    kind.Name,
+		ID:            entry.ID,
+		Description:   entry.Description,
+		Active:        entry.Active,
+		Kind:          entry.Kind,
+		Fingerprint:   selector.Fingerprint(entry),
+		DevicePath:    entry.DevicePathText,
+		FilePathList:  hex.EncodeToString(entry.FilePathList),
+		OptionalData:  hex.EncodeToString(entry.OptionalData),
+		OrderPosition: uefi.IndexInOrder(order, entry.ID) + 1,
+	}
+	if status != nil {
+		record.Current = status.BootCurrent != "" && entry.ID == status.BootCurrent
+		record.Next = status.BootNext != "" && entry.ID == status.BootNext
+	}
+
+	if hardDrive := entry.HardDrive(); hardDrive != nil {
+		guid, _ := hardDrive.PartitionGUID()
+		if partition := disk.FindPartition(partitions, guid); partition != nil {
+			record.Partition = &partitionRecord{
+				Device:     partition.Device,
+				Disk:       partition.Disk.Device,
+				Model:      partition.Disk.Model,
+				Serial:     partition.Disk.Serial,
+				MountPoint: partition.MountPoint,
+			}
+		}
+	}
+
+	return record
+}
+
+// Returns the CSV row for a load option, in the order of `entryCSVHeader`
+func (r *entryRecord) csvRow() []string {
+	partition := &partitionRecord{}
+	if r.Partition != nil {
+		partition = r.Partition
+	}
+	return []string{
+		strconv.Itoa(output.SCHEMA_VERSION),
+		r.OptionType,
+		r.ID,
+		r.Description,
+		strconv.FormatBool(r.Active),
+		r.Kind,
+		r.Fingerprint,
+		r.DevicePath,
+		r.FilePathList,
+		r.OptionalData,
+		strconv.FormatBool(r.Current),
+		strconv.FormatBool(r.Next),
+		strconv.Itoa(r.OrderPosition),
+		partition.Device,
+		partition.Disk,
+		partition.Model,
+		partition.Serial,
+		partition.MountPoint,
+	}
+}
+
+// Returns the record that describes the Secure Boot configuration
+func newSecureBootRecord(backend uefi.Backend) secureBootRecord {
+	state, err := uefi.ReadSecureBootState(backend.RawVariables())
+	switch {
+	case err != nil:
+		return secureBootRecord{State: "unknown", Error: err.Error()}
+	case state.SetupMode:
+		return secureBootRecord{State: "setup-mode", DBEntries: len(state.DB), DBXEntries: len(state.DBX)}
+	case state.Enabled:
+		return secureBootRecord{State: "enabled", DBEntries: len(state.DB), DBXEntries: len(state.DBX)}
+	}
+	return secureBootRecord{State: "disabled", DBEntries: len(state.DB), DBXEntries: len(state.DBX)}
+}
+
+// Prints a list document to stdout in the specified machine-readable format
+func writeListDocument(format output.Format, document *listDocument) error {
+	rows := [][]string{}
+	for index := range document.Entries {
+		rows = append(rows, document.Entries[index].csvRow())
+	}
+	return output.Write(os.Stdout, format, document, entryCSVHeader, rows)
+}
+
+// Prints a status document to stdout in the specified machine-readable format
+func writeStatusDocument(format output.Format, document *statusDocument) error {
+	row := []string{
+		strconv.Itoa(output.SCHEMA_VERSION),
+		document.BootCurrent,
+		document.BootNext,
+		strings.Join(document.BootOrder, " "),
+		formatTimeout(document.Timeout),
+		document.SecureBoot.State,
+		strconv.Itoa(document.SecureBoot.DBEntries),
+		strconv.Itoa(document.SecureBoot.DBXEntries),
+	}
+	return output.Write(os.Stdout, format, document, statusCSVHeader, [][]string{row})
+}
+
+// Prints a diff document to stdout in the specified machine-readable format
+func writeDiffDocument(format output.Format, document *diffDocument) error {
+	rows := [][]string{}
+	row := func(change string, id string, newID string, description string, oldValue string, newValue string) {
+		rows = append(rows, []string{strconv.Itoa(output.SCHEMA_VERSION), change, id, newID, description, oldValue, newValue})
+	}
+	for _, entry := range document.Added {
+		row("added", entry.ID, "", entry.Description, "", "")
+	}
+	for _, entry := range document.Removed {
+		row("removed", entry.ID, "", entry.Description, "", "")
+	}
+	for _, entry := range document.Moved {
+		row("moved", entry.OldID, entry.NewID, entry.Description, "", "")
+	}
+	for _, entry := range document.Renamed {
+		row("renamed", entry.ID, "", entry.NewDescription, entry.OldDescription, entry.NewDescription)
+	}
+	for _, entry := range document.DevicePathChanged {
+		row("devicePathChanged", entry.ID, "", entry.Description, entry.OldDevicePath, entry.NewDevicePath)
+	}
+	for _, entry := range document.Reordered {
+		row("reordered", entry.ID, "", entry.Description, strconv.Itoa(entry.OldPosition), strconv.Itoa(entry.NewPosition))
+	}
+	if document.BootOrder != nil {
+		row("bootOrder", "", "", "", strings.Join(document.BootOrder.Old, " "), strings.Join(document.BootOrder.New, " "))
+	}
+	if document.Timeout != nil {
+		row("timeout", "", "", "", formatTimeout(document.Timeout.Old), formatTimeout(document.Timeout.New))
+	}
+	return output.Write(os.Stdout, format, document, diffCSVHeader, rows)
+}
+
+// Formats an optional Timeout value for CSV output, where an empty string indicates that the variable is not set
+func formatTimeout(timeout *uint16) string {
+	if timeout == nil {
+		return ""
+	}
+	return strconv.Itoa(int(*timeout))
+}
+
+// Returns the stable code that identifies the cause of an error in JSON output
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, selector.ErrInvalidSelector):
+		return "invalid-selector"
+	case errors.Is(err, errInvalidArguments):
+		return "invalid-arguments"
+	case errors.Is(err, errInvalidConfig):
+		return "invalid-config"
+	case errors.Is(err, errNotUEFI):
+		return "uefi-unavailable"
+	case errors.Is(err, errMissingTool):
+		return "missing-tool"
+	}
+	return "failed"
+}
+
+// Prints an error to stderr, as a JSON document if JSON output was requested
+func reportError(err error, format string) {
+	if parsed, _ := output.ParseFormat(format); parsed == output.JSON {
+		document := &errorDocument{
+			SchemaVersion: output.SCHEMA_VERSION,
+			Error:         errorRecord{Code: errorCode(err), Message: err.Error()},
+		}
+		if output.WriteJSON(os.Stderr, document) == nil {
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+}
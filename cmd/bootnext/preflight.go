@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/pe"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The errors reported when the loader for a boot entry fails verification
+var (
+	errPartitionMissing   = errors.New("the partition containing the loader does not exist on any disk")
+	errLoaderMissing      = errors.New("the loader does not exist")
+	errLoaderInvalid      = errors.New("the loader is not a valid PE/COFF image")
+	errLoaderArchitecture = errors.New("the loader does not match the firmware architecture")
+	errLoaderRejected     = errors.New("Secure Boot will reject the loader")
+)
+
+// Reports a failed verification check as an error, or as a warning if `force` is set
+func failCheck(err error, force bool) error {
+	if force {
+		fmt.Fprintf(os.Stderr, "Warning: %v (continuing because --force was specified)\n", err)
+		return nil
+	}
+	return fmt.Errorf("%w (use --force to proceed anyway)", err)
+}
+
+// Returns the decoded device path of a boot entry
+// (Tool backends do not report device paths, so the corresponding load option is read directly from NVRAM when
+// necessary, which requires mapping the GUIDs that `bcdedit` reports to `Boot####` load options)
+func entryDevicePath(backend uefi.Backend, entry *uefi.BootEntry) (uefi.DevicePath, error) {
+	if entry.DevicePath != nil {
+		return entry.DevicePath, nil
+	}
+
+	option, err := uefi.FindBootOption(backend.RawVariables(), entry)
+	if err != nil {
+		return nil, fmt.Errorf("the %s backend does not report device paths, and %v", backend.Name(), err)
+	} else if option.DevicePath == nil {
+		return nil, fmt.Errorf("the device path of UEFI variable %s is malformed", uefi.BootOptions.VariableName(option.ID))
+	}
+	return option.DevicePath, nil
+}
+
+// Verifies that the loader referenced by a boot entry exists, matches the architecture of the firmware and would be
+// permitted to run under Secure Boot
+//
+// Failed checks produce an error unless `force` is set, in which case a warning is printed instead. Boot entries that do
+// not reference a file on a partition (e.g. network boot entries and removable media) are skipped, as are loaders on
+// partitions that are not mounted, since their files cannot be inspected.
+func verifyLoader(backend uefi.Backend, entry *uefi.BootEntry, force bool) error {
+
+	// Identify the partition and the path of the loader, skipping boot entries that do not reference a file
+	devicePath, err := entryDevicePath(backend, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to verify the loader for boot entry \"%s\": %v\n", entry.Description, err)
+		return nil
+	}
+	resolved := &uefi.BootEntry{DevicePath: devicePath}
+	hardDrive, loader := resolved.HardDrive(), resolved.LoaderPath()
+	if hardDrive == nil || loader == "" {
+		fmt.Printf("Boot entry \"%s\" does not reference a file on a GPT partition, skipping loader verification\n", entry.Description)
+		return nil
+	}
+
+	// Locate the partition on the system's disks
+	// (Boot entries in simulated NVRAM do not necessarily reference partitions on the host system's disks)
+	guid, _ := hardDrive.PartitionGUID()
+	partitions, err := disk.ListPartitions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to verify the loader for boot entry \"%s\": failed to list disk partitions: %v\n", entry.Description, err)
+		return nil
+	}
+	partition := disk.FindPartition(partitions, guid)
+	if partition == nil && backend.IsVirtual() {
+		fmt.Fprintf(os.Stderr, "Warning: partition %s does not exist on any disk, skipping loader verification\n", guid)
+		return nil
+	} else if partition == nil {
+		return failCheck(fmt.Errorf("%w: %s", errPartitionMissing, guid), force)
+	} else if partition.MountPoint == "" {
+		fmt.Fprintf(os.Stderr, "Warning: partition %s (%s) is not mounted, skipping loader verification\n", guid, partition.Device)
+		return nil
+	}
+
+	// Verify that the loader exists
+	path := partition.FilePath(loader)
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return failCheck(fmt.Errorf("%w: \"%s\"", errLoaderMissing, path), force)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to verify the loader \"%s\": %v\n", path, err)
+		return nil
+	}
+
+	// Verify that the loader is a PE/COFF image for the firmware's architecture
+	image, err := pe.Parse(contents)
+	if err != nil {
+		return failCheck(fmt.Errorf("%w: \"%s\": %v", errLoaderInvalid, path, err), force)
+	}
+	if expected, err := uefi.FirmwareMachine(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to determine the firmware architecture: %v\n", err)
+	} else if image.Machine != expected {
+		err := fmt.Errorf("%w: \"%s\" is built for %s but the firmware requires %s", errLoaderArchitecture, path, pe.MachineName(image.Machine), pe.MachineName(expected))
+		if err := failCheck(err, force); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Verified that the loader \"%s\" exists and is built for %s\n", path, pe.MachineName(expected))
+	}
+
+	// Verify that Secure Boot will permit the loader to run
+	return checkSecureBoot(backend, path, image, force)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tensorworks/bootnext/internal/pe"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Returns a description of the Secure Boot state, suitable for displaying to the user
+func describeSecureBoot(state *uefi.SecureBootState) string {
+	if state.SetupMode {
+		return "setup mode (signatures are not enforced)"
+	} else if state.Enabled {
+		return fmt.Sprintf("enabled (db: %d entries, dbx: %d entries)", len(state.DB), len(state.DBX))
+	}
+	return "disabled"
+}
+
+// Verifies that the firmware would permit a loader image to run under Secure Boot
+// (Loaders that would be rejected produce an error unless `force` is set, in which case a warning is printed instead)
+func checkSecureBoot(backend uefi.Backend, path string, image *pe.Image, force bool) error {
+
+	// Determine whether the firmware is enforcing Secure Boot
+	state, err := uefi.ReadSecureBootState(backend.RawVariables())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read the Secure Boot configuration: %v\n", err)
+		return nil
+	} else if !state.IsEnforcing() {
+		return nil
+	}
+
+	// Check the image against db and dbx
+	fmt.Printf("Secure Boot is %s, checking the loader against the signature databases\n", describeSecureBoot(state))
+	verdict := state.CheckImage(image)
+	if verdict.Allowed {
+		fmt.Printf("Secure Boot will permit \"%s\": %s\n", path, verdict.Reason)
+		return nil
+	}
+
+	return failCheck(fmt.Errorf("%w: \"%s\": %s", errLoaderRejected, path, verdict.Reason), force)
+}
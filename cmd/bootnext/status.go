@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/output"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Creates the `status` subcommand
+func newStatusCommand(options *globalOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "status",
+		Short: "Print the values of the BootCurrent, BootNext, BootOrder and Timeout variables",
+		Long: "Prints the values of the UEFI boot manager variables, including the boot entry that the system was booted from\n" +
+			"and any boot entry that is pending in the BootNext variable.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := parseOutputFormat(options.output)
+			if err != nil {
+				return err
+			}
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runStatus(backend, options.noElevate, format)
+		},
+	}
+
+	command.Flags().StringVarP(&options.output, "output", "o", string(output.Table), outputUsage(""))
+	return command
+}
+
+// Prints the values of the UEFI boot manager variables, along with the descriptions of the boot entries they reference
+func runStatus(backend uefi.Backend, noElevate bool, format output.Format) error {
+
+	// Verify that we are able to read UEFI NVRAM variables
+	if err := checkPrerequisites(backend, false, noElevate); err != nil {
+		return err
+	}
+
+	// Retrieve the list of UEFI boot entries and the values of the boot manager variables
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list UEFI boot entries: %v", err)
+	}
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+
+	// Print a machine-readable document describing the variables and the boot entries, if requested
+	if format.IsStructured() {
+		document := &statusDocument{
+			SchemaVersion: output.SCHEMA_VERSION,
+			BootCurrent:   status.BootCurrent,
+			BootNext:      status.BootNext,
+			BootOrder:     append([]string{}, status.BootOrder...),
+			Timeout:       status.Timeout,
+			SecureBoot:    newSecureBootRecord(backend),
+			Entries:       []entryRecord{},
+		}
+		partitions := listPartitionsForAnnotation()
+		for index := range entries {
+			document.Entries = append(document.Entries, newEntryRecord(uefi.BootOptions, &entries[index], partitions, status.BootOrder, status))
+		}
+		return writeStatusDocument(format, document)
+	}
+
+	// Print the values of the variables
+	fmt.Printf("BootCurrent: %s\n", describeBootEntryID(entries, status.BootCurrent))
+	fmt.Printf("BootNext: %s\n", describeBootEntryID(entries, status.BootNext))
+	if status.Timeout != nil {
+		fmt.Printf("Timeout: %d seconds\n", *status.Timeout)
+	} else {
+		fmt.Println("Timeout: not set")
+	}
+	if len(status.BootOrder) > 0 {
+		fmt.Println("BootOrder:")
+		for _, id := range status.BootOrder {
+			fmt.Printf("- %s\n", describeBootEntryID(entries, id))
+		}
+	} else {
+		fmt.Println("BootOrder: not set")
+	}
+
+	// Print the Secure Boot configuration
+	if secureBoot, err := uefi.ReadSecureBootState(backend.RawVariables()); err != nil {
+		fmt.Printf("Secure Boot: unknown (%v)\n", err)
+	} else {
+		fmt.Printf("Secure Boot: %s\n", describeSecureBoot(secureBoot))
+	}
+
+	return nil
+}
+
+// Returns a description of the boot entry with the specified identifier, suitable for displaying to the user
+func describeBootEntryID(entries []uefi.BootEntry, id string) string {
+	if id == "" {
+		return "not set"
+	} else if entry := uefi.FindBootEntry(entries, id); entry != nil {
+		return fmt.Sprintf("ID: \"%s\", Description: \"%s\"", entry.ID, entry.Description)
+	} else {
+		return fmt.Sprintf("ID: \"%s\" (no matching boot entry)", id)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tensorworks/bootnext/internal/reboot"
+	"github.com/tensorworks/bootnext/internal/state"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The name of the state record that tracks a Timeout value that should be restored after the next boot
+const pendingTimeoutRestoreRecord = "pending-timeout-restore"
+
+// Represents a Timeout value that was set by `bootnext timeout --once` and should be reverted after the next boot
+type pendingTimeoutRestore struct {
+
+	// The previous value of the Timeout variable, or nil if it was not set
+	Previous *uint16 `json:"previous"`
+
+	// The temporary value of the Timeout variable
+	Temporary uint16 `json:"temporary"`
+
+	// The time at which the temporary value was set
+	Created time.Time `json:"created"`
+}
+
+// Creates the `timeout` subcommand
+func newTimeoutCommand(options *globalOptions) *cobra.Command {
+	once := false
+	deleteTimeout := false
+	command := &cobra.Command{
+		Use:   "timeout [seconds]",
+		Short: "Print or set the firmware boot menu timeout in the Timeout variable",
+		Long: "Prints the value of the UEFI Timeout variable, which controls how long the firmware boot menu waits for input,\n" +
+			"or sets it to the specified number of seconds.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// Parse the new timeout value, if one was specified
+			var seconds *uint16
+			if len(args) > 0 {
+				parsed, err := strconv.ParseUint(args[0], 10, 16)
+				if err != nil {
+					return fmt.Errorf("invalid timeout \"%s\": must be a number of seconds between 0 and 65535", args[0])
+				}
+				value := uint16(parsed)
+				seconds = &value
+			}
+
+			// Verify that the flags are consistent with the arguments
+			if deleteTimeout && seconds != nil {
+				return fmt.Errorf("a timeout value cannot be specified in conjunction with --delete")
+			} else if once && seconds == nil {
+				return fmt.Errorf("a timeout value must be specified in conjunction with --once")
+			}
+
+			backend, err := selectBackend(options.backend)
+			if err != nil {
+				return err
+			}
+			return runTimeout(backend, options.noElevate, seconds, deleteTimeout, once)
+		},
+	}
+
+	command.Flags().BoolVar(&once, "once", false, "Restore the previous value when `bootnext cleanup` runs after the next boot")
+	command.Flags().BoolVar(&deleteTimeout, "delete", false, "Delete the Timeout variable, reverting to the firmware's default behaviour")
+	return command
+}
+
+// Prints the value of the Timeout variable, or sets or deletes it
+func runTimeout(backend uefi.Backend, noElevate bool, seconds *uint16, deleteTimeout bool, once bool) error {
+
+	// Verify that we are able to access UEFI NVRAM variables, requesting elevated privileges if required
+	if err := checkPrerequisites(backend, seconds != nil || deleteTimeout, noElevate); err != nil {
+		return err
+	}
+
+	// Retrieve and print the current value
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+	fmt.Printf("Timeout: %s\n", describeTimeout(status.Timeout))
+
+	// Delete the variable if requested
+	if deleteTimeout {
+		fmt.Println("Deleting the Timeout variable...")
+		if err := backend.ClearTimeout(); err != nil {
+			return fmt.Errorf("failed to delete Timeout variable: %v", err)
+		}
+		recordSnapshot(backend)
+		return nil
+	}
+
+	// If we are just printing the current value then stop here
+	if seconds == nil {
+		return nil
+	}
+
+	// Record the previous value so that it can be restored after the next boot
+	// (If a restore is already pending then we preserve the value that it will restore, rather than our temporary value)
+	if once {
+		record := &pendingTimeoutRestore{}
+		if exists, err := state.Load(pendingTimeoutRestoreRecord, record); err != nil {
+			return fmt.Errorf("failed to read state record: %v", err)
+		} else if !exists {
+			record.Previous = status.Timeout
+		}
+		record.Temporary = *seconds
+		record.Created = time.Now()
+		if err := state.Save(pendingTimeoutRestoreRecord, record); err != nil {
+			return fmt.Errorf("failed to record the previous Timeout value: %v", err)
+		}
+		fmt.Printf("The Timeout will be restored to %s when `bootnext cleanup` runs after the next boot.\n", describeTimeout(record.Previous))
+	}
+
+	// Set the new value
+	fmt.Printf("Setting the Timeout variable to %d seconds...\n", *seconds)
+	if err := backend.SetTimeout(*seconds); err != nil {
+		return fmt.Errorf("failed to set Timeout variable value: %v", err)
+	}
+	recordSnapshot(backend)
+	return nil
+}
+
+// Restores the Timeout value recorded by `bootnext timeout --once` if the system has booted since it was recorded
+func restorePendingTimeout(backend uefi.Backend, dryRun bool) error {
+
+	// Determine whether a restore is pending
+	record := &pendingTimeoutRestore{}
+	if exists, err := state.Load(pendingTimeoutRestoreRecord, record); err != nil {
+		return fmt.Errorf("failed to read state record: %v", err)
+	} else if !exists {
+		return nil
+	}
+
+	// Don't restore the previous value until the system has booted with the temporary value
+	booted, err := reboot.LastBootTime()
+	if err != nil {
+		return fmt.Errorf("failed to determine the last boot time: %v", err)
+	} else if booted.Before(record.Created) {
+		fmt.Printf("The temporary Timeout of %d seconds has not been used yet, leaving it in place\n", record.Temporary)
+		return nil
+	}
+
+	// If the Timeout has been changed since we set it then leave the new value in place
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve UEFI boot manager variables: %v", err)
+	}
+	if status.Timeout == nil || *status.Timeout != record.Temporary {
+		fmt.Printf("The Timeout has been changed to %s since it was set temporarily, leaving it in place\n", describeTimeout(status.Timeout))
+	} else {
+		fmt.Printf("Restoring the Timeout to %s\n", describeTimeout(record.Previous))
+		if dryRun {
+			return nil
+		} else if record.Previous != nil {
+			err = backend.SetTimeout(*record.Previous)
+		} else {
+			err = backend.ClearTimeout()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore Timeout variable value: %v", err)
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	return state.Remove(pendingTimeoutRestoreRecord)
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tensorworks/bootnext/internal/selector"
+)
+
+// The environment variable that can be used to override the path to the per-user configuration file
+const CONFIG_ENV_VAR = "BOOTNEXT_CONFIG"
+
+// The name of the configuration file within the system-wide and per-user configuration directories
+const fileName = "config.yaml"
+
+// Represents a named target defined in a configuration file, which can be specified in place of a selector
+type Target struct {
+
+	// The name of the target
+	Name string
+
+	// The selector that identifies the target boot entry
+	Selector string
+
+	// Overrides the `--no-reboot` flag, if set
+	NoReboot *bool
+
+	// Overrides the `--delay` flag, if set
+	Delay *time.Duration
+
+	// Overrides the `--first` flag, if set
+	First *bool
+
+	// The fingerprint of the target boot entry, used to re-resolve the target if the selector identifies a different
+	// boot entry (e.g. when the selector pins an identifier that the firmware has since reassigned)
+	Fingerprint string
+
+	// The path to the configuration file that defines the target
+	Source string
+}
+
+// Represents the combined contents of the configuration files
+type Config struct {
+
+	// The named targets, indexed by name
+	Targets map[string]*Target
+
+	// The paths to the configuration files that were loaded, in the order they were loaded
+	Files []string
+}
+
+// Returns the sorted names of the named targets
+func (c *Config) TargetNames() []string {
+	names := []string{}
+	for name := range c.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the path to the system-wide configuration file
+func SystemPath() string {
+	return filepath.Join(systemDir(), fileName)
+}
+
+// Returns the path to the per-user configuration file, which can be overridden by the environment variable
+// (An empty string is returned if the user's configuration directory cannot be determined)
+func UserPath() string {
+	if path := os.Getenv(CONFIG_ENV_VAR); path != "" {
+		return path
+	}
+	return DefaultUserPath()
+}
+
+// Returns the default path to the per-user configuration file within the user's configuration directory, or an empty
+// string if the directory cannot be determined
+func DefaultUserPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bootnext", fileName)
+}
+
+// Loads the system-wide configuration file followed by the per-user configuration file, ignoring either file if it
+// does not exist
+// (Targets defined in the per-user file replace targets with the same name in the system-wide file, and the per-user
+// file's path is taken from the argument if it is not empty)
+func Load(userPath string) (*Config, error) {
+	if userPath == "" {
+		userPath = UserPath()
+	}
+	return loadFiles(SystemPath(), userPath)
+}
+
+// Loads the named targets from each of the specified configuration files in turn, ignoring files that do not exist
+// and paths that are empty
+// (Targets defined in later files replace targets with the same name in earlier files)
+func loadFiles(paths ...string) (*Config, error) {
+	config := &Config{Targets: map[string]*Target{}}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		targets, err := LoadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		config.Files = append(config.Files, path)
+		for _, target := range targets {
+			config.Targets[target.Name] = target
+		}
+	}
+
+	return config, nil
+}
+
+// Loads the named targets from a single configuration file
+// (The returned error wraps fs.ErrNotExist if the file does not exist)
+func LoadFile(path string) ([]*Target, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := Parse(string(contents), path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file \"%s\": %v", path, err)
+	}
+	return targets, nil
+}
+
+// Parses the named targets from the contents of a configuration file
+func Parse(contents string, source string) ([]*Target, error) {
+	root, err := parseYAML(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []*Target{}
+	for _, key := range root.keys {
+		value := root.values[key]
+		switch key {
+		case "targets":
+			if value.scalar == "" && !value.isMapping() {
+				continue
+			} else if !value.isMapping() {
+				return nil, fmt.Errorf("line %d: \"targets\" must be a mapping of target names to targets", value.line)
+			}
+			for _, name := range value.keys {
+				target, err := parseTarget(name, value.values[name], source)
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, target)
+			}
+
+		default:
+			return nil, fmt.Errorf("line %d: unknown setting \"%s\"", value.line, key)
+		}
+	}
+
+	return targets, nil
+}
+
+// Parses the settings for a named target
+// (A target can be written as a mapping of settings, or as a scalar that is shorthand for its selector)
+func parseTarget(name string, value *node, source string) (*Target, error) {
+	if strings.ContainsAny(name, " \t") {
+		return nil, fmt.Errorf("line %d: target name \"%s\" cannot contain spaces", value.line, name)
+	}
+
+	target := &Target{Name: name, Source: source}
+	if !value.isMapping() {
+		target.Selector = value.scalar
+	}
+
+	for _, key := range value.keys {
+		setting := value.values[key]
+		if setting.isMapping() {
+			return nil, fmt.Errorf("line %d: the \"%s\" setting of target \"%s\" must be a single value", setting.line, key, name)
+		}
+
+		switch key {
+		case "selector":
+			target.Selector = setting.scalar
+
+		case "noReboot":
+			parsed, err := parseBool(setting.scalar)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid noReboot value for target \"%s\": %v", setting.line, name, err)
+			}
+			target.NoReboot = &parsed
+
+		case "first":
+			parsed, err := parseBool(setting.scalar)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid first value for target \"%s\": %v", setting.line, name, err)
+			}
+			target.First = &parsed
+
+		case "fingerprint":
+			if !selector.IsFingerprint(setting.scalar) {
+				return nil, fmt.Errorf("line %d: invalid fingerprint for target \"%s\": \"%s\" is not %d hexadecimal digits", setting.line, name, setting.scalar, selector.FingerprintLength)
+			}
+			target.Fingerprint = strings.ToLower(setting.scalar)
+
+		case "delay":
+			parsed, err := time.ParseDuration(setting.scalar)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("line %d: invalid delay value for target \"%s\": \"%s\" is not a non-negative duration (e.g. 5m)", setting.line, name, setting.scalar)
+			}
+			target.Delay = &parsed
+
+		default:
+			return nil, fmt.Errorf("line %d: unknown setting \"%s\" for target \"%s\" (expected selector, fingerprint, noReboot, delay or first)", setting.line, key, name)
+		}
+	}
+
+	if strings.TrimSpace(target.Selector) == "" {
+		return nil, fmt.Errorf("line %d: target \"%s\" does not specify a selector", value.line, name)
+	}
+	return target, nil
+}
+
+// Parses a YAML boolean value
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("\"%s\" is not true or false", value)
+}
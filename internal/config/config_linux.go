@@ -0,0 +1,6 @@
+package config
+
+// Returns the directory containing the system-wide configuration file under Linux
+func systemDir() string {
+	return "/etc/bootnext"
+}
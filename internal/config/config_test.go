@@ -0,0 +1,298 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns a summary of a target's settings that can be compared against the expected settings
+// (Settings that are not set are omitted)
+func describeTarget(target *Target) string {
+	settings := []string{fmt.Sprintf("%s: selector=%q", target.Name, target.Selector)}
+	if target.NoReboot != nil {
+		settings = append(settings, fmt.Sprintf("noReboot=%v", *target.NoReboot))
+	}
+	if target.Delay != nil {
+		settings = append(settings, fmt.Sprintf("delay=%v", *target.Delay))
+	}
+	if target.First != nil {
+		settings = append(settings, fmt.Sprintf("first=%v", *target.First))
+	}
+	if target.Fingerprint != "" {
+		settings = append(settings, fmt.Sprintf("fingerprint=%s", target.Fingerprint))
+	}
+	return strings.Join(settings, " ")
+}
+
+// Returns the summaries of a list of targets
+func describeTargets(targets []*Target) []string {
+	described := []string{}
+	for _, target := range targets {
+		described = append(described, describeTarget(target))
+	}
+	return described
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected []string
+	}{
+		{
+			name:     "empty document",
+			contents: "",
+			expected: []string{},
+		},
+		{
+			name:     "comments only",
+			contents: "# No targets yet\n---\n",
+			expected: []string{},
+		},
+		{
+			name:     "empty targets",
+			contents: "targets:\n",
+			expected: []string{},
+		},
+		{
+			name: "readme example",
+			contents: "targets:\n" +
+				"  # `bootnext win` selects the Windows Boot Manager and reboots immediately\n" +
+				"  win: {selector: desc:\"Windows Boot Manager\", noReboot: false}\n" +
+				"\n" +
+				"  # `bootnext ubuntu` selects the Ubuntu installation on the first NVMe disk and reboots in one minute\n" +
+				"  ubuntu:\n" +
+				"    selector: path:~ubuntu disk:nvme0n1\n" +
+				"    delay: 1m\n" +
+				"\n" +
+				"  # `bootnext usb` selects the first USB device\n" +
+				"  usb: type:usb\n",
+			expected: []string{
+				`win: selector="desc:\"Windows Boot Manager\"" noReboot=false`,
+				`ubuntu: selector="path:~ubuntu disk:nvme0n1" delay=1m0s`,
+				`usb: selector="type:usb"`,
+			},
+		},
+		{
+			name: "block mapping with every setting",
+			contents: "targets:\n" +
+				"    win:\n" +
+				"        selector: id:0003\n" +
+				"        fingerprint: 3F2A9C1E5B7D0A48\n" +
+				"        noReboot: yes\n" +
+				"        delay: 90s\n" +
+				"        first: on\n",
+			expected: []string{`win: selector="id:0003" noReboot=true delay=1m30s first=true fingerprint=3f2a9c1e5b7d0a48`},
+		},
+		{
+			name:     "flow mapping",
+			contents: "targets:\n  win: {selector: windows}\n  usb: {selector: 'type:usb', first: true}\n",
+			expected: []string{`win: selector="windows"`, `usb: selector="type:usb" first=true`},
+		},
+		{
+			name:     "flow mapping with commas in quotes",
+			contents: "targets:\n  pxe: {selector: \"desc:\\\"PXE, IPv4\\\"\", first: no,}\n",
+			expected: []string{`pxe: selector="desc:\"PXE, IPv4\"" first=false`},
+		},
+		{
+			name:     "trailing comments",
+			contents: "targets: # named targets\n  win: windows  # the default\n",
+			expected: []string{`win: selector="windows"`},
+		},
+		{
+			name:     "hash within a word",
+			contents: "targets:\n  nvme: path:nvme#1\n",
+			expected: []string{`nvme: selector="path:nvme#1"`},
+		},
+		{
+			name:     "hash inside quotes",
+			contents: "targets:\n  lab: \"desc:lab #2\"\n  box: 'box #3' # comment\n",
+			expected: []string{`lab: selector="desc:lab #2"`, `box: selector="box #3"`},
+		},
+		{
+			name:     "quoted selector value within a plain scalar",
+			contents: "targets:\n  win: desc:\"Windows # Boot Manager\" # comment\n",
+			expected: []string{`win: selector="desc:\"Windows # Boot Manager\""`},
+		},
+		{
+			name:     "single-quoted escapes",
+			contents: "targets:\n  bob: 'desc:Bob''s PC'\n",
+			expected: []string{`bob: selector="desc:Bob's PC"`},
+		},
+		{
+			name:     "double-quoted escapes",
+			contents: "targets:\n  tab: \"desc:a\\tb\"\n",
+			expected: []string{`tab: selector="desc:a\tb"`},
+		},
+		{
+			name:     "apostrophe in a plain scalar",
+			contents: "targets:\n  win: desc:Bob's PC  # lab box\n",
+			expected: []string{`win: selector="desc:Bob's PC"`},
+		},
+		{
+			name:     "apostrophe in a key",
+			contents: "targets:\n  bob's:\n    selector: desc:Bob's PC\n  alice's: {selector: desc:Alice's PC}\n",
+			expected: []string{`bob's: selector="desc:Bob's PC"`, `alice's: selector="desc:Alice's PC"`},
+		},
+		{
+			name:     "quoted keys",
+			contents: "\"targets\":\n  'win': {'selector': windows}\n",
+			expected: []string{`win: selector="windows"`},
+		},
+		{
+			name:     "windows line endings",
+			contents: "targets:\r\n  win: windows\r\n",
+			expected: []string{`win: selector="windows"`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			targets, err := Parse(testCase.contents, "config.yaml")
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			actual := describeTargets(targets)
+			if strings.Join(actual, "\n") != strings.Join(testCase.expected, "\n") {
+				t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(actual, "\n"), strings.Join(testCase.expected, "\n"))
+			}
+			for _, target := range targets {
+				if target.Source != "config.yaml" {
+					t.Errorf("source of target \"%s\": got \"%s\", expected \"config.yaml\"", target.Name, target.Source)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected string
+	}{
+		{name: "tab indentation", contents: "targets:\n\twin: windows\n", expected: "line 2: tabs cannot be used for indentation"},
+		{name: "missing separator", contents: "targets:\n  win windows\n", expected: "line 2: expected \"key: value\""},
+		{name: "empty key", contents: "targets:\n  '': windows\n", expected: "line 2: keys cannot be empty"},
+		{name: "block sequence", contents: "targets:\n  - windows\n", expected: "line 2: sequences are not supported"},
+		{name: "flow sequence", contents: "targets:\n  win: [windows]\n", expected: "line 2: sequences are not supported"},
+		{name: "unclosed flow mapping", contents: "targets:\n  win: {selector: windows\n", expected: "line 2: flow mappings must be closed on the same line"},
+		{name: "nested flow mapping", contents: "targets: {win: {selector: windows}}\n", expected: "line 1: nested flow collections are not supported"},
+		{name: "duplicate key", contents: "targets:\n  win: windows\n\n  win: ubuntu\n", expected: "line 4: duplicate key \"win\""},
+		{name: "duplicate flow key", contents: "targets:\n  win: {selector: a, selector: b}\n", expected: "line 2: duplicate key \"selector\""},
+		{name: "unterminated quote", contents: "targets:\n  win: \"windows\n", expected: "line 2: unterminated quoted string"},
+		{name: "invalid escape", contents: "targets:\n  win: \"\\q\"\n", expected: "line 2: invalid double-quoted string"},
+		{name: "anchor", contents: "targets:\n  win: &win windows\n", expected: "line 2: anchors, aliases and tags are not supported"},
+		{name: "multi-line string", contents: "targets:\n  win: |\n    windows\n", expected: "line 2: multi-line strings are not supported"},
+		{name: "unexpected indentation", contents: "targets:\n  win: windows\n    first: true\n", expected: "line 3: unexpected indentation"},
+		{name: "dedented entry", contents: "  targets:\n    win: windows\n first: true\n", expected: "line 3: unexpected indentation"},
+		{name: "unknown top-level setting", contents: "# Settings\n\ntarget:\n  win: windows\n", expected: "line 3: unknown setting \"target\""},
+		{name: "targets not a mapping", contents: "targets: windows\n", expected: "line 1: \"targets\" must be a mapping"},
+		{name: "unknown target setting", contents: "targets:\n  win:\n    selector: windows\n    reboot: false\n", expected: "line 4: unknown setting \"reboot\" for target \"win\""},
+		{name: "nested target setting", contents: "targets:\n  win:\n    selector:\n      id: 0003\n", expected: "line 3: the \"selector\" setting of target \"win\" must be a single value"},
+		{name: "name with spaces", contents: "targets:\n  'my win': windows\n", expected: "line 2: target name \"my win\" cannot contain spaces"},
+		{name: "missing selector", contents: "targets:\n  win:\n    first: true\n", expected: "line 2: target \"win\" does not specify a selector"},
+		{name: "invalid boolean", contents: "targets:\n  win: {selector: windows, noReboot: maybe}\n", expected: "line 2: invalid noReboot value for target \"win\""},
+		{name: "invalid delay", contents: "targets:\n  win:\n    selector: windows\n    delay: -5m\n", expected: "line 4: invalid delay value for target \"win\""},
+		{name: "invalid fingerprint", contents: "targets:\n  win:\n    selector: windows\n    fingerprint: 3f2a\n", expected: "line 4: invalid fingerprint for target \"win\""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			targets, err := Parse(testCase.contents, "config.yaml")
+			if err == nil {
+				t.Fatalf("expected an error, got targets %q", describeTargets(targets))
+			}
+			if !strings.HasPrefix(err.Error(), testCase.expected) {
+				t.Errorf("got error \"%v\", expected it to start with \"%s\"", err, testCase.expected)
+			}
+		})
+	}
+}
+
+// Writes a configuration file to a temporary directory and returns its path
+func writeConfigFile(t *testing.T, name string, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFilesUserOverridesSystem(t *testing.T) {
+	system := writeConfigFile(t, "system.yaml", "targets:\n  win: {selector: windows, noReboot: true}\n  usb: type:usb\n")
+	user := writeConfigFile(t, "user.yaml", "targets:\n  win: desc:\"Windows Boot Manager\"\n  ubuntu: ubuntu\n")
+
+	config, err := loadFiles(system, user)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	// The per-user target replaces the system-wide target entirely, rather than merging their settings
+	expected := map[string]string{
+		"win":    `win: selector="desc:\"Windows Boot Manager\""`,
+		"usb":    `usb: selector="type:usb"`,
+		"ubuntu": `ubuntu: selector="ubuntu"`,
+	}
+	sources := map[string]string{"win": user, "usb": system, "ubuntu": user}
+	if names := config.TargetNames(); strings.Join(names, ",") != "ubuntu,usb,win" {
+		t.Errorf("target names: got %q, expected [ubuntu usb win]", names)
+	}
+	for name, summary := range expected {
+		target := config.Targets[name]
+		if target == nil {
+			t.Errorf("missing target \"%s\"", name)
+			continue
+		}
+		if actual := describeTarget(target); actual != summary {
+			t.Errorf("target \"%s\": got %s, expected %s", name, actual, summary)
+		}
+		if target.Source != sources[name] {
+			t.Errorf("source of target \"%s\": got %s, expected %s", name, target.Source, sources[name])
+		}
+	}
+	if strings.Join(config.Files, ",") != system+","+user {
+		t.Errorf("files: got %q, expected [%s %s]", config.Files, system, user)
+	}
+}
+
+func TestLoadFilesIgnoresMissingFiles(t *testing.T) {
+	user := writeConfigFile(t, "user.yaml", "targets:\n  win: windows\n")
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	config, err := loadFiles(missing, "", user)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if len(config.Targets) != 1 || config.Targets["win"] == nil {
+		t.Errorf("got targets %q, expected only \"win\"", config.TargetNames())
+	}
+	if len(config.Files) != 1 || config.Files[0] != user {
+		t.Errorf("files: got %q, expected [%s]", config.Files, user)
+	}
+}
+
+func TestLoadFilesReportsInvalidFiles(t *testing.T) {
+	system := writeConfigFile(t, "system.yaml", "targets:\n  win: windows\n")
+	user := writeConfigFile(t, "user.yaml", "targets:\n  win:\n    reboot: false\n")
+
+	_, err := loadFiles(system, user)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if expected := fmt.Sprintf("invalid configuration file \"%s\": line 3:", user); !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("got error \"%v\", expected it to start with \"%s\"", err, expected)
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, expected it to wrap fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Returns the directory containing the system-wide configuration file under Windows
+func systemDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "bootnext")
+}
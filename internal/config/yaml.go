@@ -0,0 +1,272 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Represents a value parsed from a YAML document, which is either a mapping or a scalar
+// (Only the subset of YAML used by configuration files is supported: block and flow mappings, plain and quoted scalars,
+// and comments. Sequences, anchors, tags and multi-line scalars are rejected.)
+type node struct {
+
+	// The line on which the value starts
+	line int
+
+	// The keys of the mapping, in the order they appear in the document (nil for scalars)
+	keys []string
+
+	// The values of the mapping, indexed by key
+	values map[string]*node
+
+	// The value of the scalar
+	scalar string
+}
+
+// Determines whether the node is a mapping
+func (n *node) isMapping() bool {
+	return n.values != nil
+}
+
+// Represents a line of a YAML document that contains content, with comments and indentation removed
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// Parses a YAML document whose top level is a mapping
+func parseYAML(contents string) (*node, error) {
+
+	// Split the document into lines, discarding blank lines and comments
+	lines := []yamlLine{}
+	for index, text := range strings.Split(strings.ReplaceAll(contents, "\r\n", "\n"), "\n") {
+		number := index + 1
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", number)
+		}
+		trimmed = strings.TrimSpace(stripComment(trimmed))
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{number: number, indent: len(text) - len(strings.TrimLeft(text, " ")), text: trimmed})
+	}
+
+	// An empty document is treated as an empty mapping
+	if len(lines) == 0 {
+		return &node{line: 1, values: map[string]*node{}}, nil
+	}
+
+	// Parse the top-level mapping and verify that it consumed the entire document
+	root, next, err := parseBlockMapping(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+	return root, nil
+}
+
+// Parses the block mapping whose entries start at the specified line and share the specified indentation, returning
+// the mapping and the index of the first line that follows it
+func parseBlockMapping(lines []yamlLine, start int, indent int) (*node, int, error) {
+	mapping := &node{line: lines[start].number, values: map[string]*node{}}
+
+	current := start
+	for current < len(lines) && lines[current].indent == indent {
+		line := lines[current]
+
+		// Reject sequences, which are not used by configuration files
+		if line.text == "-" || strings.HasPrefix(line.text, "- ") {
+			return nil, 0, fmt.Errorf("line %d: sequences are not supported", line.number)
+		}
+
+		// Split the entry into its key and value
+		key, value, err := splitEntry(line.text, line.number)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, exists := mapping.values[key]; exists {
+			return nil, 0, fmt.Errorf("line %d: duplicate key \"%s\"", line.number, key)
+		}
+		current++
+
+		// An entry without a value on the same line is either a nested block mapping or an empty scalar
+		var child *node
+		if value == "" {
+			if current < len(lines) && lines[current].indent > indent {
+				child, current, err = parseBlockMapping(lines, current, lines[current].indent)
+				if err != nil {
+					return nil, 0, err
+				}
+
+				// Errors concerning the nested mapping as a whole refer to the line containing its key
+				child.line = line.number
+			} else {
+				child = &node{line: line.number}
+			}
+		} else {
+			child, err = parseValue(value, line.number)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+
+		mapping.keys = append(mapping.keys, key)
+		mapping.values[key] = child
+	}
+
+	// Any remaining line that is indented further than the mapping does not belong to an entry
+	if current < len(lines) && lines[current].indent > indent {
+		return nil, 0, fmt.Errorf("line %d: unexpected indentation", lines[current].number)
+	}
+	return mapping, current, nil
+}
+
+// Parses a value that appears on the same line as its key, which is either a flow mapping or a scalar
+func parseValue(value string, line int) (*node, error) {
+	if !strings.HasPrefix(value, "{") {
+		if strings.HasPrefix(value, "[") || value == "-" || strings.HasPrefix(value, "- ") {
+			return nil, fmt.Errorf("line %d: sequences are not supported", line)
+		}
+		scalar, err := parseScalar(value, line)
+		if err != nil {
+			return nil, err
+		}
+		return &node{line: line, scalar: scalar}, nil
+	}
+
+	// Flow mappings must be closed on the same line, and cannot be nested
+	if !strings.HasSuffix(value, "}") {
+		return nil, fmt.Errorf("line %d: flow mappings must be closed on the same line", line)
+	}
+	mapping := &node{line: line, values: map[string]*node{}}
+	for _, entry := range splitOutsideQuotes(value[1:len(value)-1], ',') {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, text, err := splitEntry(entry, line)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+			return nil, fmt.Errorf("line %d: nested flow collections are not supported", line)
+		}
+		if _, exists := mapping.values[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key \"%s\"", line, key)
+		}
+		scalar, err := parseScalar(text, line)
+		if err != nil {
+			return nil, err
+		}
+		mapping.keys = append(mapping.keys, key)
+		mapping.values[key] = &node{line: line, scalar: scalar}
+	}
+	return mapping, nil
+}
+
+// Splits a mapping entry into its key and its (possibly empty) value
+func splitEntry(text string, line int) (string, string, error) {
+	separator := -1
+	for _, position := range indicesOutsideQuotes(text, ':') {
+		if position == len(text)-1 || text[position+1] == ' ' {
+			separator = position
+			break
+		}
+	}
+	if separator == -1 {
+		return "", "", fmt.Errorf("line %d: expected \"key: value\" but found \"%s\"", line, text)
+	}
+
+	key, err := parseScalar(strings.TrimSpace(text[:separator]), line)
+	if err != nil {
+		return "", "", err
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("line %d: keys cannot be empty", line)
+	}
+	return key, strings.TrimSpace(text[separator+1:]), nil
+}
+
+// Parses a plain, single-quoted or double-quoted scalar
+func parseScalar(text string, line int) (string, error) {
+	switch {
+	case len(text) >= 2 && strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\""):
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return "", fmt.Errorf("line %d: invalid double-quoted string %s", line, text)
+		}
+		return unquoted, nil
+
+	case len(text) >= 2 && strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'"):
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+
+	case strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'"):
+		return "", fmt.Errorf("line %d: unterminated quoted string %s", line, text)
+
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") || strings.HasPrefix(text, "!"):
+		return "", fmt.Errorf("line %d: anchors, aliases and tags are not supported", line)
+
+	case text == "|" || text == ">" || strings.HasPrefix(text, "|-") || strings.HasPrefix(text, ">-"):
+		return "", fmt.Errorf("line %d: multi-line strings are not supported", line)
+
+	case text == "~" || text == "null":
+		return "", nil
+	}
+	return text, nil
+}
+
+// Removes a trailing comment from a line, ignoring any `#` characters that appear inside quotes or within a word
+func stripComment(text string) string {
+	for _, position := range indicesOutsideQuotes(text, '#') {
+		if position == 0 || text[position-1] == ' ' {
+			return text[:position]
+		}
+	}
+	return text
+}
+
+// Splits a string at each occurrence of the separator that does not appear inside quotes
+func splitOutsideQuotes(text string, separator byte) []string {
+	parts := []string{}
+	start := 0
+	for _, position := range indicesOutsideQuotes(text, separator) {
+		parts = append(parts, text[start:position])
+		start = position + 1
+	}
+	return append(parts, text[start:])
+}
+
+// Returns the positions of each occurrence of a character that does not appear inside quotes
+// (A quote only opens a quoted string at the start of a token, so that apostrophes within plain scalars such as
+// `Bob's PC` are treated literally. Selector values also count as tokens, so that selectors such as
+// `desc:"Windows Boot Manager"` can be written as plain scalars.)
+func indicesOutsideQuotes(text string, character byte) []int {
+	positions := []int{}
+	quote := byte(0)
+	for position := 0; position < len(text); position++ {
+		switch {
+		case quote == '"' && text[position] == '\\':
+			position++
+		case quote != 0:
+			if text[position] == quote {
+				quote = 0
+			}
+		case (text[position] == '"' || text[position] == '\'') && startsToken(text, position):
+			quote = text[position]
+		case text[position] == character:
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
+
+// Determines whether a position is the start of a token, which is the case at the start of the text or after
+// whitespace, the delimiters of a flow mapping, or the colon that separates a key or selector field from its value
+func startsToken(text string, position int) bool {
+	return position == 0 || strings.IndexByte(" \t{,:", text[position-1]) != -1
+}
@@ -0,0 +1,95 @@
+package disk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The partition type GUID that identifies an EFI System Partition, from:
+// <https://uefi.org/specs/UEFI/2.10/05_GUID_Partition_Table_Format.html#defined-gpt-partition-entry-partition-type-guids>
+var EFI_SYSTEM_PARTITION_GUID = uefi.MustParseGUID("c12a7328-f81f-11d2-ba4b-00a0c93ec93b")
+
+// Represents a physical or virtual disk on the system
+type Disk struct {
+
+	// The device node for the disk (e.g. `/dev/nvme0n1` under Linux or `\\.\PhysicalDrive0` under Windows)
+	Device string
+
+	// The model name reported by the disk, or an empty string if it is unknown
+	Model string
+
+	// The serial number reported by the disk, or an empty string if it is unknown
+	Serial string
+}
+
+// Represents a GPT partition on one of the system's disks
+type Partition struct {
+
+	// The unique partition GUID, as referenced by the HD() nodes of UEFI device paths
+	GUID uefi.GUID
+
+	// The partition type GUID, which is all zeroes if the type could not be determined
+	TypeGUID uefi.GUID
+
+	// The one-based index of the partition's entry in the partition table, or zero if it is unknown
+	Number int
+
+	// The device node for the partition (e.g. `/dev/nvme0n1p1`), or an empty string if it is unknown
+	Device string
+
+	// The disk that contains the partition
+	Disk Disk
+
+	// The path at which the partition's filesystem is mounted, or an empty string if it is not mounted
+	MountPoint string
+}
+
+// Determines whether the partition is an EFI System Partition
+func (p *Partition) IsESP() bool {
+	return p.TypeGUID == EFI_SYSTEM_PARTITION_GUID
+}
+
+// Returns the EFI System Partitions from the supplied list of partitions
+func FindESPs(partitions []Partition) []Partition {
+	esps := []Partition{}
+	for _, partition := range partitions {
+		if partition.IsESP() {
+			esps = append(esps, partition)
+		}
+	}
+	return esps
+}
+
+// Returns the partition with the specified GUID, or nil if there is no matching partition
+func FindPartition(partitions []Partition, guid uefi.GUID) *Partition {
+	for index := range partitions {
+		if partitions[index].GUID == guid {
+			return &partitions[index]
+		}
+	}
+	return nil
+}
+
+// Determines whether a file exists on a mounted partition, given its path relative to the root of the partition's
+// filesystem in the form used by UEFI file path nodes (e.g. `\EFI\ubuntu\shimx64.efi`)
+// (Errors other than the file not existing are propagated so that callers can distinguish them from missing files)
+func (p *Partition) FileExists(path string) (bool, error) {
+	if _, err := os.Stat(p.FilePath(path)); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Returns the host filesystem path for a file on a mounted partition, given its path relative to the root of the
+// partition's filesystem in the form used by UEFI file path nodes (e.g. `\EFI\ubuntu\shimx64.efi`)
+func (p *Partition) FilePath(path string) string {
+	relative := filepath.FromSlash(strings.ReplaceAll(strings.TrimLeft(path, "\\/"), "\\", "/"))
+	return filepath.Join(p.MountPoint, relative)
+}
@@ -0,0 +1,240 @@
+package disk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The environment variable that can be used to discover partitions from a fixture tree rather than the host system
+// (The fixture tree mirrors the layout of the `/sys`, `/proc`, `/run` and `/dev` directories of a real system)
+const SYSTEM_ROOT_ENV_VAR = "BOOTNEXT_SYSTEM_ROOT"
+
+// The default sector size for disks that do not report their logical block size
+const defaultSectorSize = 512
+
+// Discovers partitions through the sysfs, procfs and udev interfaces exposed under a root directory
+type Sysfs struct {
+
+	// The root directory that contains the `sys`, `proc`, `run` and `dev` directories (`/` for the host system)
+	Root string
+}
+
+// Lists the GPT partitions on the system's disks, along with their mount points
+func ListPartitions() ([]Partition, error) {
+	root := os.Getenv(SYSTEM_ROOT_ENV_VAR)
+	if root == "" {
+		root = "/"
+	}
+	return (&Sysfs{Root: root}).ListPartitions()
+}
+
+// Lists the GPT partitions on the disks under the root directory, along with their mount points
+//
+// Partitions are enumerated through `/sys/class/block`, and their type and unique GUIDs are read from the udev
+// database when it is available, falling back to reading the GPT from the disk's device node. Mount points are
+// identified by matching each partition's device number against the mount table.
+func (s *Sysfs) ListPartitions() ([]Partition, error) {
+
+	// Parse the mount table so we can identify the mount point for each partition
+	mounts, err := s.readMountInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	// List the block devices
+	blockDir := s.path("sys", "class", "block")
+	dirEntries, err := os.ReadDir(blockDir)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := []Partition{}
+	disks := map[string]*Disk{}
+	tables := map[string][]gptEntry{}
+	for _, dirEntry := range dirEntries {
+
+		// Only partitions have a `partition` attribute, which holds the partition number
+		deviceDir := filepath.Join(blockDir, dirEntry.Name())
+		number, err := strconv.Atoi(readAttribute(filepath.Join(deviceDir, "partition")))
+		if err != nil {
+			continue
+		}
+
+		// The sysfs directory for a partition is nested inside the directory for its parent disk
+		resolved, err := filepath.EvalSymlinks(deviceDir)
+		if err != nil {
+			return nil, err
+		}
+		diskName := filepath.Base(filepath.Dir(resolved))
+		partition := Partition{Number: number, Device: deviceNode(dirEntry.Name())}
+		deviceNumber := readAttribute(filepath.Join(deviceDir, "dev"))
+		partition.MountPoint = mounts[deviceNumber]
+
+		// Read the partition's type and unique GUIDs from the udev database, ignoring MBR partitions
+		properties := s.readUdevProperties(deviceNumber)
+		if scheme, exists := properties["ID_PART_ENTRY_SCHEME"]; exists && scheme != "gpt" {
+			continue
+		}
+		guid, guidErr := uefi.ParseGUID(properties["ID_PART_ENTRY_UUID"])
+		typeGUID, typeErr := uefi.ParseGUID(properties["ID_PART_ENTRY_TYPE"])
+
+		// Fall back to reading the partition table from the disk if udev did not provide the GUIDs
+		// (Failing to read the partition table is an error, since callers must be able to trust that a partition that
+		// is missing from the list does not exist)
+		if guidErr != nil || typeErr != nil {
+			table, cached := tables[diskName]
+			if !cached {
+				table, err = s.readPartitionTable(diskName)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the partition table of %s: %v", deviceNode(diskName), err)
+				}
+				tables[diskName] = table
+			}
+			if number < 1 || number > len(table) || table[number-1].TypeGUID == (uefi.GUID{}) {
+				continue
+			}
+			guid, typeGUID = table[number-1].UniqueGUID, table[number-1].TypeGUID
+		}
+		partition.GUID, partition.TypeGUID = guid, typeGUID
+
+		// Identify the disk that contains the partition
+		if _, cached := disks[diskName]; !cached {
+			disks[diskName] = s.readDisk(diskName)
+		}
+		partition.Disk = *disks[diskName]
+
+		partitions = append(partitions, partition)
+	}
+
+	return partitions, nil
+}
+
+// Returns the path of a file relative to the root directory
+func (s *Sysfs) path(elements ...string) string {
+	return filepath.Join(append([]string{s.Root}, elements...)...)
+}
+
+// Returns the device node for a block device, given its name in `/sys/class/block`
+// (The kernel replaces slashes in device names with exclamation marks, e.g. `cciss!c0d0` for `/dev/cciss/c0d0`)
+func deviceNode(name string) string {
+	return "/dev/" + strings.ReplaceAll(name, "!", "/")
+}
+
+// Reads a sysfs attribute, returning an empty string if it does not exist or cannot be read
+func readAttribute(path string) string {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// Reads the properties that udev has recorded for the block device with the specified device number (`major:minor`)
+// (A missing udev database produces an empty map, since udev is not running in all environments)
+func (s *Sysfs) readUdevProperties(deviceNumber string) map[string]string {
+	properties := map[string]string{}
+	contents, err := os.ReadFile(s.path("run", "udev", "data", "b"+deviceNumber))
+	if err != nil {
+		return properties
+	}
+
+	// Properties are recorded on lines of the form `E:KEY=value`
+	for _, line := range strings.Split(string(contents), "\n") {
+		if strings.HasPrefix(line, "E:") {
+			if key, value, found := strings.Cut(line[2:], "="); found {
+				properties[key] = value
+			}
+		}
+	}
+
+	return properties
+}
+
+// Reads the GPT from the device node of the specified disk
+func (s *Sysfs) readPartitionTable(diskName string) ([]gptEntry, error) {
+	sectorSize, err := strconv.ParseInt(readAttribute(s.path("sys", "class", "block", diskName, "queue", "logical_block_size")), 10, 64)
+	if err != nil || sectorSize <= 0 {
+		sectorSize = defaultSectorSize
+	}
+
+	file, err := os.Open(s.path(strings.TrimPrefix(deviceNode(diskName), "/")))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readPartitionTable(file, sectorSize)
+}
+
+// Reads the device node, model and serial number of the specified disk
+// (Not all drivers expose the serial number through sysfs, so we fall back to the value recorded by udev)
+func (s *Sysfs) readDisk(diskName string) *Disk {
+	diskDir := s.path("sys", "class", "block", diskName)
+	disk := &Disk{
+		Device: deviceNode(diskName),
+		Model:  readAttribute(filepath.Join(diskDir, "device", "model")),
+		Serial: readAttribute(filepath.Join(diskDir, "device", "serial")),
+	}
+
+	properties := s.readUdevProperties(readAttribute(filepath.Join(diskDir, "dev")))
+	if disk.Model == "" {
+		disk.Model = strings.ReplaceAll(properties["ID_MODEL"], "_", " ")
+	}
+	if disk.Serial == "" {
+		disk.Serial = properties["ID_SERIAL_SHORT"]
+	}
+
+	return disk
+}
+
+// Parses the mount table, returning a map from device numbers (`major:minor`) to the first mount point for each device
+// (Bind mounts of subdirectories are ignored, since their mount points do not correspond to the root of the filesystem,
+// and mount points are resolved relative to the root directory so that the files in a fixture tree can be accessed)
+func (s *Sysfs) readMountInfo() (map[string]string, error) {
+	mounts := map[string]string{}
+	file, err := os.Open(s.path("proc", "self", "mountinfo"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return mounts, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Each line takes the form `id parent major:minor root mountpoint options [optional fields...] - fstype source superoptions`
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[3] != "/" {
+			continue
+		}
+		if _, exists := mounts[fields[2]]; !exists {
+			mounts[fields[2]] = s.path(unescapeMountInfo(fields[4]))
+		}
+	}
+
+	return mounts, scanner.Err()
+}
+
+// Decodes the octal escape sequences that the kernel uses for whitespace and backslashes in the mount table
+func unescapeMountInfo(field string) string {
+	builder := strings.Builder{}
+	for index := 0; index < len(field); index++ {
+		if field[index] == '\\' && index+3 < len(field) {
+			if value, err := strconv.ParseUint(field[index+1:index+4], 8, 8); err == nil {
+				builder.WriteByte(byte(value))
+				index += 3
+				continue
+			}
+		}
+		builder.WriteByte(field[index])
+	}
+	return builder.String()
+}
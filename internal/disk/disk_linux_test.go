@@ -0,0 +1,148 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The root of the fixture tree, which mirrors a system with a SATA disk described by the udev database, an NVMe disk
+// whose partitions are only described by its GPT, a USB flash drive with an MBR partition table and a loop device
+var fixtureRoot = filepath.Join("testdata", "sysfs")
+
+// Creates a fixture tree that only contains the specified top-level directories of the full fixture tree
+func newPartialFixture(t *testing.T, directories ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	absolute, err := filepath.Abs(fixtureRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, directory := range directories {
+		if err := os.Symlink(filepath.Join(absolute, directory), filepath.Join(root, directory)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestListPartitions(t *testing.T) {
+	partitions, err := (&Sysfs{Root: fixtureRoot}).ListPartitions()
+	if err != nil {
+		t.Fatalf("failed to list partitions: %v", err)
+	}
+
+	sata := Disk{Device: "/dev/sda", Model: "Samsung SSD 870", Serial: "S62BNJ0R123456"}
+	nvme := Disk{Device: "/dev/nvme0n1", Model: "WD_BLACK SN850X 1000GB", Serial: "23117R800123"}
+	expected := []Partition{
+		{
+			GUID:       uefi.MustParseGUID("7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13"),
+			TypeGUID:   EFI_SYSTEM_PARTITION_GUID,
+			Number:     1,
+			Device:     "/dev/nvme0n1p1",
+			Disk:       nvme,
+			MountPoint: filepath.Join(fixtureRoot, "mnt", "second esp"),
+		},
+		{
+			GUID:     uefi.MustParseGUID("a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"),
+			TypeGUID: linuxFilesystemGUID,
+			Number:   2,
+			Device:   "/dev/nvme0n1p2",
+			Disk:     nvme,
+		},
+		{
+			GUID:       uefi.MustParseGUID("5d8f5a3e-5f3c-4c6b-9d2e-1f0e6b4a7c21"),
+			TypeGUID:   EFI_SYSTEM_PARTITION_GUID,
+			Number:     1,
+			Device:     "/dev/sda1",
+			Disk:       sata,
+			MountPoint: filepath.Join(fixtureRoot, "boot", "efi"),
+		},
+		{
+			GUID:       uefi.MustParseGUID("9b2c1d4e-3f5a-4b6c-8d7e-0f1a2b3c4d5e"),
+			TypeGUID:   linuxFilesystemGUID,
+			Number:     2,
+			Device:     "/dev/sda2",
+			Disk:       sata,
+			MountPoint: fixtureRoot,
+		},
+	}
+
+	if len(partitions) != len(expected) {
+		t.Fatalf("expected %d partitions, got %d: %+v", len(expected), len(partitions), partitions)
+	}
+	for index := range expected {
+		if partitions[index] != expected[index] {
+			t.Errorf("partition %d:\n got: %+v\nwant: %+v", index, partitions[index], expected[index])
+		}
+	}
+}
+
+func TestFindPartitions(t *testing.T) {
+	partitions, err := (&Sysfs{Root: fixtureRoot}).ListPartitions()
+	if err != nil {
+		t.Fatalf("failed to list partitions: %v", err)
+	}
+
+	esps := FindESPs(partitions)
+	if len(esps) != 2 || esps[0].Device != "/dev/nvme0n1p1" || esps[1].Device != "/dev/sda1" {
+		t.Errorf("unexpected ESPs: %+v", esps)
+	}
+
+	partition := FindPartition(partitions, uefi.MustParseGUID("5d8f5a3e-5f3c-4c6b-9d2e-1f0e6b4a7c21"))
+	if partition == nil || partition.Device != "/dev/sda1" {
+		t.Fatalf("failed to find partition by GUID: %+v", partition)
+	}
+	if FindPartition(partitions, uefi.MustParseGUID("00000000-0000-0000-0000-000000000001")) != nil {
+		t.Errorf("found a partition that does not exist")
+	}
+
+	// Loader paths use backslashes and are resolved relative to the mount point
+	for path, exists := range map[string]bool{`\EFI\ubuntu\shimx64.efi`: true, `/EFI/ubuntu/shimx64.efi`: true, `\EFI\ubuntu\grubx64.efi`: false} {
+		if actual, err := partition.FileExists(path); err != nil || actual != exists {
+			t.Errorf("FileExists(%s): got %v (%v), expected %v", path, actual, err, exists)
+		}
+	}
+}
+
+func TestListPartitionsWithoutMountTable(t *testing.T) {
+
+	// A missing mount table is not an error, but the GPT fallback must still succeed
+	root := newPartialFixture(t, "sys", "run", "dev")
+	partitions, err := (&Sysfs{Root: root}).ListPartitions()
+	if err != nil {
+		t.Fatalf("failed to list partitions: %v", err)
+	}
+	if len(partitions) != 4 {
+		t.Fatalf("expected 4 partitions, got %+v", partitions)
+	}
+	for _, partition := range partitions {
+		if partition.MountPoint != "" {
+			t.Errorf("expected %s to be unmounted, got %s", partition.Device, partition.MountPoint)
+		}
+	}
+}
+
+func TestListPartitionsFailsWhenGPTCannotBeRead(t *testing.T) {
+
+	// Partitions that are missing from the udev database must not be silently omitted if the GPT cannot be read
+	root := newPartialFixture(t, "sys", "run")
+	if partitions, err := (&Sysfs{Root: root}).ListPartitions(); err == nil {
+		t.Errorf("expected an error, got %+v", partitions)
+	}
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	for field, expected := range map[string]string{
+		`/boot/efi`:            "/boot/efi",
+		`/mnt/second\040esp`:   "/mnt/second esp",
+		`/mnt/tab\011and\134x`: "/mnt/tab\tand\\x",
+		`/mnt/trailing\04`:     `/mnt/trailing\04`,
+	} {
+		if actual := unescapeMountInfo(field); actual != expected {
+			t.Errorf("unescapeMountInfo(%s): got %q, expected %q", field, actual, expected)
+		}
+	}
+}
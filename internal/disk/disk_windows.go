@@ -0,0 +1,67 @@
+package disk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/process"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Lists the GPT partitions on the system's disks, along with their mount points
+// (Partitions without a drive letter are accessed through their volume GUID path, e.g. `\\?\Volume{...}\`)
+func ListPartitions() ([]Partition, error) {
+
+	// Use PowerShell to list the GUIDs, numbers, disk details and access paths of each partition
+	output, err := process.CaptureOutput([]string{
+		"powershell.exe",
+		"-ExecutionPolicy", "Bypass",
+		"-Command", strings.Join([]string{
+			"Get-Partition | ForEach-Object {",
+			"$disk = Get-Disk -Number $_.DiskNumber;",
+			"Write-Host \"$($_.Guid)|$($_.GptType)|$($_.PartitionNumber)|$($_.DiskNumber)|$($disk.FriendlyName)|$($disk.SerialNumber)|$($_.AccessPaths -join '|')\"",
+			"}",
+		}, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the output, ignoring MBR partitions, which do not have GUIDs
+	partitions := []Partition{}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) < 6 {
+			continue
+		}
+		guid, err := uefi.ParseGUID(fields[0])
+		if err != nil {
+			continue
+		}
+
+		// Parse the partition type and the details of the disk that contains the partition
+		partition := Partition{GUID: guid}
+		if typeGUID, err := uefi.ParseGUID(fields[1]); err == nil {
+			partition.TypeGUID = typeGUID
+		}
+		partition.Number, _ = strconv.Atoi(fields[2])
+		partition.Disk = Disk{
+			Device: fmt.Sprintf("\\\\.\\PhysicalDrive%s", fields[3]),
+			Model:  strings.TrimSpace(fields[4]),
+			Serial: strings.TrimSpace(fields[5]),
+		}
+		partition.Device = fmt.Sprintf("\\\\?\\GLOBALROOT\\Device\\Harddisk%s\\Partition%s", fields[3], fields[2])
+
+		// Use the first access path, which is the drive letter if the partition has one
+		for _, path := range fields[6:] {
+			if path != "" {
+				partition.MountPoint = path
+				break
+			}
+		}
+		partitions = append(partitions, partition)
+	}
+
+	return partitions, nil
+}
@@ -0,0 +1,63 @@
+package disk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The signature at the start of a GPT header, from: <https://uefi.org/specs/UEFI/2.10/05_GUID_Partition_Table_Format.html#gpt-header>
+const gptSignature = "EFI PART"
+
+// The maximum number of partition entries that we will read, which guards against corrupt headers
+const maxPartitionEntries = 1024
+
+// Represents an entry in a GUID Partition Table
+type gptEntry struct {
+
+	// The partition type GUID, which is all zeroes for unused entries
+	TypeGUID uefi.GUID
+
+	// The unique partition GUID
+	UniqueGUID uefi.GUID
+}
+
+// Reads the partition entries from the primary GPT of a disk, indexed by partition number minus one
+// (Disks that do not have a GPT produce a nil slice rather than an error)
+func readPartitionTable(disk io.ReaderAt, sectorSize int64) ([]gptEntry, error) {
+
+	// Read the GPT header, which is located in the second logical block
+	header := make([]byte, 92)
+	if _, err := disk.ReadAt(header, sectorSize); errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if string(header[0:8]) != gptSignature {
+		return nil, nil
+	}
+
+	// Locate the partition entry array
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	numberOfEntries := binary.LittleEndian.Uint32(header[80:])
+	entrySize := binary.LittleEndian.Uint32(header[84:])
+	if entrySize < 128 || numberOfEntries > maxPartitionEntries {
+		return nil, fmt.Errorf("GPT header has invalid partition entry array (%d entries of %d bytes)", numberOfEntries, entrySize)
+	}
+
+	// Read the partition entries
+	array := make([]byte, int64(numberOfEntries)*int64(entrySize))
+	if _, err := disk.ReadAt(array, entriesLBA*sectorSize); err != nil {
+		return nil, fmt.Errorf("failed to read GPT partition entries: %v", err)
+	}
+	entries := make([]gptEntry, numberOfEntries)
+	for index := range entries {
+		offset := index * int(entrySize)
+		copy(entries[index].TypeGUID[:], array[offset:offset+16])
+		copy(entries[index].UniqueGUID[:], array[offset+16:offset+32])
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,70 @@
+package disk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The partition type GUID for Linux filesystem data
+var linuxFilesystemGUID = uefi.MustParseGUID("0fc63daf-8483-4772-8e79-3d69d8477de4")
+
+// Builds a disk image with a GPT header at the second logical block and a partition entry array at the third
+func buildGPTImage(sectorSize int, entrySize uint32, entries []gptEntry) []byte {
+	image := make([]byte, sectorSize*2+len(entries)*int(entrySize))
+	header := image[sectorSize:]
+	copy(header, gptSignature)
+	binary.LittleEndian.PutUint64(header[72:], 2)
+	binary.LittleEndian.PutUint32(header[80:], uint32(len(entries)))
+	binary.LittleEndian.PutUint32(header[84:], entrySize)
+	for index, entry := range entries {
+		offset := sectorSize*2 + index*int(entrySize)
+		copy(image[offset:], entry.TypeGUID[:])
+		copy(image[offset+16:], entry.UniqueGUID[:])
+	}
+	return image
+}
+
+func TestReadPartitionTable(t *testing.T) {
+	entries := []gptEntry{
+		{TypeGUID: EFI_SYSTEM_PARTITION_GUID, UniqueGUID: uefi.MustParseGUID("7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13")},
+		{},
+		{TypeGUID: linuxFilesystemGUID, UniqueGUID: uefi.MustParseGUID("a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d")},
+	}
+
+	for _, sectorSize := range []int{512, 4096} {
+		table, err := readPartitionTable(bytes.NewReader(buildGPTImage(sectorSize, 128, entries)), int64(sectorSize))
+		if err != nil {
+			t.Fatalf("failed to read the partition table with %d-byte sectors: %v", sectorSize, err)
+		}
+		if len(table) != len(entries) {
+			t.Fatalf("expected %d entries, got %d", len(entries), len(table))
+		}
+		for index := range entries {
+			if table[index] != entries[index] {
+				t.Errorf("entry %d: got %+v, expected %+v", index, table[index], entries[index])
+			}
+		}
+	}
+}
+
+func TestReadPartitionTableWithoutGPT(t *testing.T) {
+	for name, image := range map[string][]byte{
+		"empty disk":       {},
+		"MBR disk":         make([]byte, 4096),
+		"truncated header": make([]byte, 600),
+	} {
+		if table, err := readPartitionTable(bytes.NewReader(image), 512); err != nil || table != nil {
+			t.Errorf("%s: expected no partition table, got %+v (%v)", name, table, err)
+		}
+	}
+}
+
+func TestReadPartitionTableRejectsInvalidHeaders(t *testing.T) {
+	image := buildGPTImage(512, 64, []gptEntry{{}})
+	if table, err := readPartitionTable(bytes.NewReader(image), 512); err == nil {
+		t.Errorf("expected an error for an undersized partition entry, got %+v", table)
+	}
+}
@@ -12,8 +12,8 @@ func IsElevated() bool {
 	return unix.Geteuid() == 0
 }
 
-// Re-launches the current process with elevated privileges
-func RunElevated() (int, error) {
+// Re-launches the current process with elevated privileges, passing it the specified command-line arguments
+func RunElevated(arguments []string) (int, error) {
 
 	// Retrieve the path to the executable for the current process
 	executable, err := os.Executable()
@@ -22,6 +22,6 @@ func RunElevated() (int, error) {
 	}
 
 	// Attempt to re-run the executable using `sudo`, ensuring it inherits the standard streams from the parent
-	command := append([]string{"sudo", executable}, os.Args[1:]...)
+	command := append([]string{"sudo", executable}, arguments...)
 	return process.RunWithInheritedHandles(command)
 }
@@ -166,8 +166,8 @@ func IsElevated() bool {
 	return windows.GetCurrentProcessToken().IsElevated()
 }
 
-// Re-launches the current process with elevated privileges
-func RunElevated() (int, error) {
+// Re-launches the current process with elevated privileges, passing it the specified command-line arguments
+func RunElevated(arguments []string) (int, error) {
 
 	// Retrieve the path to the executable for the current process
 	executable, err := os.Executable()
@@ -175,9 +175,9 @@ func RunElevated() (int, error) {
 		return -1, err
 	}
 
-	// Escape each of the arguments that were passed to the current process
+	// Escape each of the arguments that will be passed to the elevated process
 	escapedArgs := []string{}
-	for _, arg := range os.Args[1:] {
+	for _, arg := range arguments {
 
 		// Iterate over each character in the argument
 		var builder strings.Builder
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The version number of the schema for machine-readable output, which is incremented whenever a field is removed or
+// its meaning changes (new fields and CSV columns may be added without changing the version)
+const SCHEMA_VERSION = 1
+
+// Identifies the format in which a command prints its results
+type Format string
+
+// The supported output formats
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	CSV   Format = "csv"
+)
+
+// The supported output formats, in the order they are listed in usage text
+var Formats = []Format{Table, JSON, YAML, CSV}
+
+// Parses the name of an output format (case insensitive)
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+
+	names := []string{}
+	for _, format := range Formats {
+		names = append(names, string(format))
+	}
+	return "", fmt.Errorf("unknown output format \"%s\" (expected one of: %s)", name, strings.Join(names, ", "))
+}
+
+// Determines whether the format is intended to be parsed by other programs rather than read by humans
+func (f Format) IsStructured() bool {
+	return f != Table && f != ""
+}
+
+// Writes a document in the specified structured format, using the `json` struct tags of the document's fields for
+// both JSON and YAML output, and the supplied header and rows for CSV output
+func Write(w io.Writer, format Format, document interface{}, header []string, rows [][]string) error {
+	switch format {
+	case JSON:
+		return WriteJSON(w, document)
+	case YAML:
+		return WriteYAML(w, document)
+	case CSV:
+		return WriteCSV(w, header, rows)
+	}
+	return fmt.Errorf("the %s output format does not produce a document", format)
+}
+
+// Writes a document as indented JSON
+func WriteJSON(w io.Writer, document interface{}) error {
+	encoded, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+// Writes a header row followed by the supplied rows as CSV
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
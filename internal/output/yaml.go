@@ -0,0 +1,179 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Represents a JSON object whose keys are kept in the order in which they were encoded
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// Writes a document as YAML
+// (The document is encoded as JSON first, so that YAML output has exactly the same fields, names and ordering as JSON
+// output. Strings are always double-quoted so that values such as "0001" and "true" are never reinterpreted.)
+func WriteYAML(w io.Writer, document interface{}) error {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return err
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("---\n")
+	switch value := value.(type) {
+	case *orderedObject:
+		writeYAMLObject(builder, value, 0)
+	default:
+		builder.WriteString(yamlScalar(value) + "\n")
+	}
+	_, err = io.WriteString(w, builder.String())
+	return err
+}
+
+// Decodes a JSON value, preserving the order of object keys
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := &orderedObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.keys = append(object.keys, key.(string))
+			object.values = append(object.values, value)
+		}
+		_, err := decoder.Token()
+		return object, err
+
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := decoder.Token()
+		return array, err
+	}
+
+	return token, nil
+}
+
+// Writes the entries of an object as a block mapping at the specified indentation
+func writeYAMLObject(builder *strings.Builder, object *orderedObject, indent int) {
+	for index, key := range object.keys {
+		builder.WriteString(strings.Repeat(" ", indent))
+		writeYAMLEntry(builder, key, object.values[index], indent)
+	}
+}
+
+// Writes a single mapping entry, with the key already indented
+func writeYAMLEntry(builder *strings.Builder, key string, value interface{}, indent int) {
+	builder.WriteString(key + ":")
+	switch value := value.(type) {
+	case *orderedObject:
+		if len(value.keys) == 0 {
+			builder.WriteString(" {}\n")
+			return
+		}
+		builder.WriteString("\n")
+		writeYAMLObject(builder, value, indent+2)
+
+	case []interface{}:
+		if len(value) == 0 {
+			builder.WriteString(" []\n")
+			return
+		}
+		builder.WriteString("\n")
+		writeYAMLSequence(builder, value, indent+2)
+
+	default:
+		builder.WriteString(" " + yamlScalar(value) + "\n")
+	}
+}
+
+// Writes the items of an array as a block sequence at the specified indentation
+// (Objects are written with their first entry on the same line as the dash, and nested arrays are written in flow style)
+func writeYAMLSequence(builder *strings.Builder, array []interface{}, indent int) {
+	for _, item := range array {
+		builder.WriteString(strings.Repeat(" ", indent) + "-")
+		switch item := item.(type) {
+		case *orderedObject:
+			if len(item.keys) == 0 {
+				builder.WriteString(" {}\n")
+				continue
+			}
+			for index, key := range item.keys {
+				if index == 0 {
+					builder.WriteString(" ")
+				} else {
+					builder.WriteString(strings.Repeat(" ", indent+2))
+				}
+				writeYAMLEntry(builder, key, item.values[index], indent+2)
+			}
+
+		case []interface{}:
+			encoded, _ := json.Marshal(flatten(item))
+			builder.WriteString(" " + string(encoded) + "\n")
+
+		default:
+			builder.WriteString(" " + yamlScalar(item) + "\n")
+		}
+	}
+}
+
+// Returns the text representation of a scalar value
+// (JSON string literals are also valid double-quoted YAML strings)
+func yamlScalar(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Converts ordered objects back into values that can be encoded as JSON, for writing nested arrays in flow style
+func flatten(value interface{}) interface{} {
+	switch value := value.(type) {
+	case *orderedObject:
+		object := map[string]interface{}{}
+		for index, key := range value.keys {
+			object[key] = flatten(value.values[index])
+		}
+		return object
+	case []interface{}:
+		flattened := []interface{}{}
+		for _, item := range value {
+			flattened = append(flattened, flatten(item))
+		}
+		return flattened
+	}
+	return value
+}
@@ -0,0 +1,249 @@
+package pe
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Object identifiers for the PKCS#7 and Authenticode structures that we parse
+var (
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSpcIndirectData = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+)
+
+// The hash algorithms that may be used by Authenticode signatures, keyed by object identifier
+var digestAlgorithms = map[string]crypto.Hash{
+	"1.3.14.3.2.26":          crypto.SHA1,
+	"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+	"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+	"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+}
+
+// ASN.1 structures from RFC 2315 and the Authenticode specification
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type spcIndirectDataContent struct {
+	Data          asn1.RawValue
+	MessageDigest digestInfo
+}
+
+type digestInfo struct {
+	DigestAlgorithm pkix.AlgorithmIdentifier
+	Digest          []byte
+}
+
+// Represents an Authenticode signature whose signer has been verified against the signed image digest
+type Signature struct {
+
+	// The hash algorithm used to compute the Authenticode digest of the image
+	DigestAlgorithm crypto.Hash
+
+	// The Authenticode digest of the image that the signature covers
+	Digest []byte
+
+	// The certificate whose key produced the signature
+	Signer *x509.Certificate
+
+	// All of the certificates embedded in the signature, including the signer and any intermediate certificates
+	Certificates []*x509.Certificate
+}
+
+// Parses a PKCS#7 signed data structure holding an Authenticode signature, verifying that the signer's key produced
+// the signature over the image digest
+// (This does not verify that the signer is trusted, which is determined by the caller's own trust anchors)
+func ParseSignature(der []byte) (*Signature, error) {
+
+	// Unwrap the signed data from its content info
+	outer := contentInfo{}
+	if _, err := asn1.Unmarshal(der, &outer); err != nil {
+		return nil, fmt.Errorf("malformed PKCS#7 content info: %v", err)
+	} else if !outer.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected PKCS#7 content type %v", outer.ContentType)
+	}
+	signed := signedData{}
+	if _, err := asn1.Unmarshal(outer.Content.Bytes, &signed); err != nil {
+		return nil, fmt.Errorf("malformed PKCS#7 signed data: %v", err)
+	} else if !signed.ContentInfo.ContentType.Equal(oidSpcIndirectData) {
+		return nil, fmt.Errorf("unexpected Authenticode content type %v", signed.ContentInfo.ContentType)
+	} else if len(signed.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected exactly one signer, found %d", len(signed.SignerInfos))
+	}
+
+	// Extract the image digest from the signed content
+	content := asn1.RawValue{}
+	indirect := spcIndirectDataContent{}
+	if _, err := asn1.Unmarshal(signed.ContentInfo.Content.Bytes, &content); err != nil {
+		return nil, fmt.Errorf("malformed Authenticode indirect data: %v", err)
+	} else if _, err := asn1.Unmarshal(content.FullBytes, &indirect); err != nil {
+		return nil, fmt.Errorf("malformed Authenticode indirect data: %v", err)
+	}
+	signature := &Signature{Digest: indirect.MessageDigest.Digest}
+	if algorithm, known := digestAlgorithms[indirect.MessageDigest.DigestAlgorithm.Algorithm.String()]; known {
+		signature.DigestAlgorithm = algorithm
+	} else {
+		return nil, fmt.Errorf("unsupported image digest algorithm %v", indirect.MessageDigest.DigestAlgorithm.Algorithm)
+	}
+
+	// Parse the embedded certificates and identify the signer
+	certificates, err := x509.ParseCertificates(signed.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed certificate in signature: %v", err)
+	}
+	signature.Certificates = certificates
+	signer := signed.SignerInfos[0]
+	for _, certificate := range certificates {
+		if bytes.Equal(certificate.RawIssuer, signer.IssuerAndSerialNumber.Issuer.FullBytes) &&
+			certificate.SerialNumber.Cmp(signer.IssuerAndSerialNumber.SerialNumber) == 0 {
+			signature.Signer = certificate
+			break
+		}
+	}
+	if signature.Signer == nil {
+		return nil, errors.New("signing certificate is not embedded in the signature")
+	}
+
+	// Verify that the signed attributes hold the digest of the content (excluding its tag and length)
+	hash, known := digestAlgorithms[signer.DigestAlgorithm.Algorithm.String()]
+	if !known || !hash.Available() {
+		return nil, fmt.Errorf("unsupported signer digest algorithm %v", signer.DigestAlgorithm.Algorithm)
+	} else if len(signer.AuthenticatedAttributes.FullBytes) == 0 {
+		return nil, errors.New("signature has no authenticated attributes")
+	}
+	messageDigest, err := findMessageDigest(signer.AuthenticatedAttributes.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	digest := hash.New()
+	digest.Write(content.Bytes)
+	if !bytes.Equal(digest.Sum(nil), messageDigest) {
+		return nil, errors.New("message digest does not match the signed content")
+	}
+
+	// Verify the signature over the authenticated attributes, which are signed using their explicit SET OF encoding
+	// rather than the implicitly-tagged encoding that appears in the signer info
+	attributes := append([]byte{}, signer.AuthenticatedAttributes.FullBytes...)
+	attributes[0] = 0x31
+	algorithm := signatureAlgorithm(signature.Signer.PublicKeyAlgorithm, hash)
+	if err := signature.Signer.CheckSignature(algorithm, attributes, signer.EncryptedDigest); err != nil {
+		return nil, fmt.Errorf("signature verification failed: %v", err)
+	}
+
+	return signature, nil
+}
+
+// Extracts the value of the message digest attribute from the DER-encoded contents of a set of attributes
+func findMessageDigest(attributes []byte) ([]byte, error) {
+	for len(attributes) > 0 {
+		current := attribute{}
+		rest, err := asn1.Unmarshal(attributes, &current)
+		if err != nil {
+			return nil, fmt.Errorf("malformed authenticated attribute: %v", err)
+		}
+		if current.Type.Equal(oidMessageDigest) {
+			value := []byte{}
+			if _, err := asn1.Unmarshal(current.Values.Bytes, &value); err != nil {
+				return nil, fmt.Errorf("malformed message digest attribute: %v", err)
+			}
+			return value, nil
+		}
+		attributes = rest
+	}
+	return nil, errors.New("signature has no message digest attribute")
+}
+
+// Returns the X.509 signature algorithm for a combination of public key algorithm and hash algorithm
+func signatureAlgorithm(key x509.PublicKeyAlgorithm, hash crypto.Hash) x509.SignatureAlgorithm {
+	switch key {
+	case x509.RSA:
+		switch hash {
+		case crypto.SHA1:
+			return x509.SHA1WithRSA
+		case crypto.SHA256:
+			return x509.SHA256WithRSA
+		case crypto.SHA384:
+			return x509.SHA384WithRSA
+		case crypto.SHA512:
+			return x509.SHA512WithRSA
+		}
+	case x509.ECDSA:
+		switch hash {
+		case crypto.SHA1:
+			return x509.ECDSAWithSHA1
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512
+		}
+	}
+	return x509.UnknownSignatureAlgorithm
+}
+
+// Returns the chain of certificates from the signer to the last issuer that is embedded in the signature
+// (Issuers are identified by verifying each certificate's signature, since that is how firmware builds the chain)
+func (s *Signature) Chain() []*x509.Certificate {
+	chain := []*x509.Certificate{s.Signer}
+	for current := s.Signer; len(chain) <= len(s.Certificates); {
+		var issuer *x509.Certificate
+		for _, candidate := range s.Certificates {
+			if candidate != current && IsIssuedBy(current, candidate) {
+				issuer = candidate
+				break
+			}
+		}
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, issuer)
+		current = issuer
+	}
+	return chain
+}
+
+// Determines whether a certificate was signed by the key of the specified issuer certificate
+// (Unlike `x509.Certificate.CheckSignatureFrom()`, this does not require the issuer to be marked as a CA, since UEFI
+// firmware does not enforce basic constraints for certificates in the signature database)
+func IsIssuedBy(certificate *x509.Certificate, issuer *x509.Certificate) bool {
+	return bytes.Equal(certificate.RawIssuer, issuer.RawSubject) &&
+		issuer.CheckSignature(certificate.SignatureAlgorithm, certificate.RawTBSCertificate, certificate.Signature) == nil
+}
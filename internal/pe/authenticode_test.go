@@ -0,0 +1,285 @@
+package pe
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/pe/petest"
+)
+
+// Parses an image, failing the test if it cannot be parsed
+func mustParse(t *testing.T, data []byte) *Image {
+	t.Helper()
+	image, err := Parse(data)
+	if err != nil {
+		t.Fatalf("failed to parse image: %v", err)
+	}
+	return image
+}
+
+// Computes the SHA-256 Authenticode digest of an image, failing the test if it cannot be computed
+func mustHash(t *testing.T, data []byte) []byte {
+	t.Helper()
+	digest, err := mustParse(t, data).AuthenticodeHash(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("failed to hash image: %v", err)
+	}
+	return digest
+}
+
+// Returns the names of a list of certificates, for comparison in tests
+func commonNames(certificates []*x509.Certificate) string {
+	names := []string{}
+	for _, certificate := range certificates {
+		names = append(names, certificate.Subject.CommonName)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestAuthenticodeHash(t *testing.T) {
+	unsigned := petest.NewImage(IMAGE_FILE_MACHINE_AMD64, IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("unsigned loader"))
+	image := mustParse(t, unsigned)
+
+	// The digest covers the headers and sections, skipping the CheckSum field and the certificate table entry
+	expected := sha256.New()
+	expected.Write(unsigned[:image.checksumOffset])
+	expected.Write(unsigned[image.checksumOffset+4 : image.certDirectoryOffset])
+	expected.Write(unsigned[image.certDirectoryOffset+8:])
+	if digest := mustHash(t, unsigned); !bytes.Equal(digest, expected.Sum(nil)) {
+		t.Errorf("got %x, expected %x", digest, expected.Sum(nil))
+	}
+
+	// Changing the CheckSum field or appending a certificate table does not change the digest
+	checksummed := append([]byte{}, unsigned...)
+	checksummed[image.checksumOffset] = 0xff
+	if digest := mustHash(t, checksummed); !bytes.Equal(digest, mustHash(t, unsigned)) {
+		t.Errorf("changing the CheckSum field changed the digest")
+	}
+	signer, key := petest.NewCertificate("Signer", nil, nil)
+	signed := petest.AppendSignatures(unsigned, petest.NewSignature(crypto.SHA256, mustHash(t, unsigned), signer, key, signer))
+	if digest := mustHash(t, signed); !bytes.Equal(digest, mustHash(t, unsigned)) {
+		t.Errorf("appending a certificate table changed the digest")
+	}
+
+	// Changing the section data does change the digest
+	modified := append([]byte{}, unsigned...)
+	modified[len(modified)-1] ^= 0xff
+	if digest := mustHash(t, modified); bytes.Equal(digest, mustHash(t, unsigned)) {
+		t.Errorf("changing the section data did not change the digest")
+	}
+
+	// Unavailable hash algorithms are reported as errors
+	if _, err := image.AuthenticodeHash(crypto.MD4); err == nil {
+		t.Errorf("got no error for an unavailable hash algorithm")
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	unsigned := petest.NewImage(IMAGE_FILE_MACHINE_AMD64, IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("signed loader"))
+	digest := mustHash(t, unsigned)
+	first, firstKey := petest.NewCertificate("First", nil, nil)
+	second, secondKey := petest.NewCertificate("Second", nil, nil)
+
+	testCases := []struct {
+		name    string
+		image   []byte
+		signers string
+	}{
+		{
+			name:    "unsigned",
+			image:   unsigned,
+			signers: "",
+		},
+		{
+			name:    "one signature",
+			image:   petest.AppendSignatures(unsigned, petest.NewSignature(crypto.SHA256, digest, first, firstKey, first)),
+			signers: "First",
+		},
+		{
+			name: "two signatures",
+			image: petest.AppendSignatures(
+				unsigned,
+				petest.NewSignature(crypto.SHA256, digest, first, firstKey, first),
+				petest.NewSignature(crypto.SHA256, digest, second, secondKey, second),
+			),
+			signers: "First,Second",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			image := mustParse(t, testCase.image)
+			if image.IsSigned() != (testCase.signers != "") {
+				t.Errorf("got IsSigned() %v, expected %v", image.IsSigned(), testCase.signers != "")
+			}
+			signatures, err := image.Signatures()
+			if err != nil {
+				t.Fatal(err)
+			}
+			signers := []*x509.Certificate{}
+			for _, signature := range signatures {
+				if signature.DigestAlgorithm != crypto.SHA256 || !bytes.Equal(signature.Digest, digest) {
+					t.Errorf("got digest %v %x, expected %v %x", signature.DigestAlgorithm, signature.Digest, crypto.SHA256, digest)
+				}
+				signers = append(signers, signature.Signer)
+			}
+			if names := commonNames(signers); names != testCase.signers {
+				t.Errorf("got signers %q, expected %q", names, testCase.signers)
+			}
+		})
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	digest := bytes.Repeat([]byte{0xab}, sha256.Size)
+	signer, key := petest.NewCertificate("Signer", nil, nil)
+	_, otherKey := petest.NewCertificate("Other", nil, nil)
+
+	testCases := []struct {
+		name      string
+		der       []byte
+		algorithm crypto.Hash
+		err       string
+	}{
+		{
+			name:      "SHA-256",
+			der:       petest.NewSignature(crypto.SHA256, digest, signer, key, signer),
+			algorithm: crypto.SHA256,
+		},
+		{
+			name:      "SHA-1",
+			der:       petest.NewSignature(crypto.SHA1, digest[:sha256.Size-12], signer, key, signer),
+			algorithm: crypto.SHA1,
+		},
+		{
+			name: "signed by a different key",
+			der:  petest.NewSignature(crypto.SHA256, digest, signer, otherKey, signer),
+			err:  "signature verification failed",
+		},
+		{
+			name: "signer not embedded",
+			der:  petest.NewSignature(crypto.SHA256, digest, signer, key),
+			err:  "signing certificate is not embedded",
+		},
+		{
+			name: "not DER",
+			der:  []byte("not a signature"),
+			err:  "malformed PKCS#7 content info",
+		},
+		{
+			name: "empty",
+			der:  []byte{},
+			err:  "malformed PKCS#7 content info",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			signature, err := ParseSignature(testCase.der)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("got error %v, expected %q", err, testCase.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if signature.DigestAlgorithm != testCase.algorithm {
+				t.Errorf("got algorithm %v, expected %v", signature.DigestAlgorithm, testCase.algorithm)
+			}
+			if !signature.Signer.Equal(signer) {
+				t.Errorf("got signer %q, expected %q", signature.Signer.Subject.CommonName, signer.Subject.CommonName)
+			}
+		})
+	}
+}
+
+func TestChainAndIsIssuedBy(t *testing.T) {
+	root, rootKey := petest.NewCertificate("Root", nil, nil)
+	intermediate, intermediateKey := petest.NewCertificate("Intermediate", root, rootKey)
+	leaf, leafKey := petest.NewCertificate("Leaf", intermediate, intermediateKey)
+	impostor, _ := petest.NewCertificate("Intermediate", root, rootKey)
+	digest := bytes.Repeat([]byte{0xcd}, sha256.Size)
+
+	// The chain follows the issuers in the order of the signatures on the certificates, regardless of the order in
+	// which they are embedded, and stops at the last issuer that is embedded
+	chains := []struct {
+		name         string
+		certificates []*x509.Certificate
+		expected     string
+	}{
+		{name: "complete", certificates: []*x509.Certificate{root, leaf, intermediate}, expected: "Leaf,Intermediate,Root"},
+		{name: "without root", certificates: []*x509.Certificate{intermediate, leaf}, expected: "Leaf,Intermediate"},
+		{name: "impostor with the issuer's name", certificates: []*x509.Certificate{impostor, leaf}, expected: "Leaf"},
+		{name: "signer only", certificates: []*x509.Certificate{leaf}, expected: "Leaf"},
+	}
+	for _, testCase := range chains {
+		t.Run(testCase.name, func(t *testing.T) {
+			signature, err := ParseSignature(petest.NewSignature(crypto.SHA256, digest, leaf, leafKey, testCase.certificates...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chain := commonNames(signature.Chain()); chain != testCase.expected {
+				t.Errorf("got chain %q, expected %q", chain, testCase.expected)
+			}
+		})
+	}
+
+	// Issuers are identified by their keys rather than their names alone
+	issuers := []struct {
+		certificate *x509.Certificate
+		issuer      *x509.Certificate
+		expected    bool
+	}{
+		{certificate: leaf, issuer: intermediate, expected: true},
+		{certificate: intermediate, issuer: root, expected: true},
+		{certificate: root, issuer: root, expected: true},
+		{certificate: leaf, issuer: root, expected: false},
+		{certificate: leaf, issuer: impostor, expected: false},
+		{certificate: intermediate, issuer: leaf, expected: false},
+	}
+	for _, testCase := range issuers {
+		if issued := IsIssuedBy(testCase.certificate, testCase.issuer); issued != testCase.expected {
+			t.Errorf("%s issued by %s: got %v, expected %v", testCase.certificate.Subject.CommonName, testCase.issuer.Subject.CommonName, issued, testCase.expected)
+		}
+	}
+}
+
+func TestMalformedInputDoesNotPanic(t *testing.T) {
+	unsigned := petest.NewImage(IMAGE_FILE_MACHINE_AMD64, IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("signed loader"))
+	signer, key := petest.NewCertificate("Signer", nil, nil)
+	signature := petest.NewSignature(crypto.SHA256, mustHash(t, unsigned), signer, key, signer)
+	signed := petest.AppendSignatures(unsigned, signature)
+
+	// Every truncation of a signature is rejected
+	for length := 0; length < len(signature); length++ {
+		if _, err := ParseSignature(signature[:length]); err == nil {
+			t.Errorf("got no error for a signature truncated to %d bytes", length)
+		}
+	}
+
+	// Every truncation of a signed image is either rejected or yields an image that can be hashed and whose signatures
+	// can be parsed without panicking
+	for length := 0; length < len(signed); length++ {
+		image, err := Parse(signed[:length])
+		if err != nil {
+			continue
+		}
+		image.AuthenticodeHash(crypto.SHA256)
+		image.Signatures()
+	}
+
+	// Certificate table entries with invalid lengths are rejected
+	for _, length := range []uint32{0, 7, uint32(len(signature)) + 64} {
+		corrupt := append([]byte{}, signed...)
+		corrupt[len(unsigned)] = byte(length)
+		corrupt[len(unsigned)+1] = byte(length >> 8)
+		if _, err := mustParse(t, corrupt).Signatures(); err == nil {
+			t.Errorf("got no error for a certificate table entry with length %d", length)
+		}
+	}
+}
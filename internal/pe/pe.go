@@ -0,0 +1,251 @@
+package pe
+
+import (
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Machine types for the architectures supported by UEFI, from: <https://learn.microsoft.com/en-us/windows/win32/debug/pe-format#machine-types>
+const (
+	IMAGE_FILE_MACHINE_I386           uint16 = 0x014c
+	IMAGE_FILE_MACHINE_ARMTHUMB_MIXED uint16 = 0x01c2
+	IMAGE_FILE_MACHINE_IA64           uint16 = 0x0200
+	IMAGE_FILE_MACHINE_RISCV64        uint16 = 0x5064
+	IMAGE_FILE_MACHINE_LOONGARCH64    uint16 = 0x6264
+	IMAGE_FILE_MACHINE_AMD64          uint16 = 0x8664
+	IMAGE_FILE_MACHINE_ARM64          uint16 = 0xaa64
+)
+
+// The short architecture names used by UEFI for each machine type (e.g. in the default loader path `\EFI\BOOT\BOOTX64.EFI`)
+var machineNames = map[uint16]string{
+	IMAGE_FILE_MACHINE_I386:           "IA32",
+	IMAGE_FILE_MACHINE_ARMTHUMB_MIXED: "ARM",
+	IMAGE_FILE_MACHINE_IA64:           "IA64",
+	IMAGE_FILE_MACHINE_RISCV64:        "RISCV64",
+	IMAGE_FILE_MACHINE_LOONGARCH64:    "LOONGARCH64",
+	IMAGE_FILE_MACHINE_AMD64:          "X64",
+	IMAGE_FILE_MACHINE_ARM64:          "AA64",
+}
+
+// Returns the short UEFI architecture name for a machine type (e.g. "X64"), or its hexadecimal value if it is unknown
+func MachineName(machine uint16) string {
+	if name, known := machineNames[machine]; known {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", machine)
+}
+
+// Magic numbers for the optional header, from: <https://learn.microsoft.com/en-us/windows/win32/debug/pe-format#optional-header-image-only>
+const (
+	IMAGE_NT_OPTIONAL_HDR32_MAGIC uint16 = 0x010b
+	IMAGE_NT_OPTIONAL_HDR64_MAGIC uint16 = 0x020b
+)
+
+// The index of the certificate table in the optional header's data directories, from: <https://learn.microsoft.com/en-us/windows/win32/debug/pe-format#optional-header-data-directories-image-only>
+const IMAGE_DIRECTORY_ENTRY_SECURITY = 4
+
+// Certificate types for entries in the certificate table, from: <https://learn.microsoft.com/en-us/windows/win32/debug/pe-format#the-attribute-certificate-table-image-only>
+const (
+	WIN_CERT_TYPE_X509             uint16 = 0x0001
+	WIN_CERT_TYPE_PKCS_SIGNED_DATA uint16 = 0x0002
+)
+
+// The size of a section header in the section table
+const sectionHeaderSize = 40
+
+// Represents a range of bytes within an image file
+type fileRange struct {
+	Offset uint32
+	Size   uint32
+}
+
+// Represents a parsed PE/COFF image, such as a UEFI application
+type Image struct {
+
+	// The machine type from the COFF file header (e.g. 0x8664 for x64)
+	Machine uint16
+
+	// The raw bytes of the image file
+	data []byte
+
+	// The offset of the CheckSum field in the optional header
+	checksumOffset uint32
+
+	// The offset of the certificate table entry in the optional header's data directories
+	certDirectoryOffset uint32
+
+	// The combined size of the headers and the section table
+	sizeOfHeaders uint32
+
+	// The ranges of the file that hold the raw data for each section
+	sections []fileRange
+
+	// The range of the file that holds the certificate table, which is empty if the image is not signed
+	certTable fileRange
+}
+
+// Parses the headers of a PE/COFF image
+func Parse(data []byte) (*Image, error) {
+
+	// Locate the PE signature using the offset stored in the MS-DOS stub
+	if len(data) < 0x40 || data[0] != 'M' || data[1] != 'Z' {
+		return nil, errors.New("file does not start with an MS-DOS header")
+	}
+	signatureOffset := binary.LittleEndian.Uint32(data[0x3c:])
+	if uint64(signatureOffset)+24 > uint64(len(data)) || string(data[signatureOffset:signatureOffset+4]) != "PE\x00\x00" {
+		return nil, errors.New("file does not contain a PE signature")
+	}
+
+	// Parse the COFF file header
+	coff := data[signatureOffset+4:]
+	image := &Image{Machine: binary.LittleEndian.Uint16(coff[0:]), data: data}
+	numberOfSections := uint32(binary.LittleEndian.Uint16(coff[2:]))
+	sizeOfOptionalHeader := uint32(binary.LittleEndian.Uint16(coff[16:]))
+
+	// Locate the fields of the optional header that we need, which are at different offsets for PE32 and PE32+ images
+	optionalHeader := signatureOffset + 24
+	if uint64(optionalHeader)+uint64(sizeOfOptionalHeader) > uint64(len(data)) || sizeOfOptionalHeader < 2 {
+		return nil, errors.New("optional header is truncated")
+	}
+	directoriesOffset := uint32(0)
+	switch magic := binary.LittleEndian.Uint16(data[optionalHeader:]); magic {
+	case IMAGE_NT_OPTIONAL_HDR32_MAGIC:
+		directoriesOffset = 96
+	case IMAGE_NT_OPTIONAL_HDR64_MAGIC:
+		directoriesOffset = 112
+	default:
+		return nil, fmt.Errorf("unknown optional header magic 0x%04x", magic)
+	}
+	if sizeOfOptionalHeader < directoriesOffset {
+		return nil, errors.New("optional header is truncated")
+	}
+	image.checksumOffset = optionalHeader + 64
+	image.sizeOfHeaders = binary.LittleEndian.Uint32(data[optionalHeader+60:])
+	if uint64(image.sizeOfHeaders) > uint64(len(data)) {
+		return nil, errors.New("headers extend beyond the end of the file")
+	}
+
+	// Locate the certificate table, if the image has one
+	// (The certificate table entry holds a file offset rather than a relative virtual address)
+	numberOfDirectories := binary.LittleEndian.Uint32(data[optionalHeader+directoriesOffset-4:])
+	image.certDirectoryOffset = optionalHeader + directoriesOffset + IMAGE_DIRECTORY_ENTRY_SECURITY*8
+	if numberOfDirectories > IMAGE_DIRECTORY_ENTRY_SECURITY && image.certDirectoryOffset+8 <= optionalHeader+sizeOfOptionalHeader {
+		image.certTable = fileRange{
+			Offset: binary.LittleEndian.Uint32(data[image.certDirectoryOffset:]),
+			Size:   binary.LittleEndian.Uint32(data[image.certDirectoryOffset+4:]),
+		}
+		if uint64(image.certTable.Offset)+uint64(image.certTable.Size) > uint64(len(data)) {
+			return nil, errors.New("certificate table extends beyond the end of the file")
+		}
+	} else {
+		image.certDirectoryOffset = 0
+	}
+	if image.sizeOfHeaders < image.checksumOffset+4 || image.sizeOfHeaders < image.certDirectoryOffset+8 {
+		return nil, errors.New("size of headers is smaller than the optional header")
+	}
+
+	// Parse the section table
+	sectionTable := optionalHeader + sizeOfOptionalHeader
+	if uint64(sectionTable)+uint64(numberOfSections)*sectionHeaderSize > uint64(len(data)) {
+		return nil, errors.New("section table is truncated")
+	}
+	for index := uint32(0); index < numberOfSections; index++ {
+		header := data[sectionTable+index*sectionHeaderSize:]
+		section := fileRange{
+			Offset: binary.LittleEndian.Uint32(header[20:]),
+			Size:   binary.LittleEndian.Uint32(header[16:]),
+		}
+		if uint64(section.Offset)+uint64(section.Size) > uint64(len(data)) {
+			return nil, fmt.Errorf("section %d extends beyond the end of the file", index)
+		}
+		image.sections = append(image.sections, section)
+	}
+
+	return image, nil
+}
+
+// Determines whether the image has a certificate table
+func (i *Image) IsSigned() bool {
+	return i.certTable.Size > 0
+}
+
+// Computes the Authenticode digest of the image using the specified hash algorithm
+//
+// This follows the algorithm described in the "Windows Authenticode Portable Executable Signature Format" specification,
+// hashing the headers (excluding the CheckSum field and the certificate table entry), each section in order of file
+// offset, and any remaining data other than the certificate table.
+func (i *Image) AuthenticodeHash(algorithm crypto.Hash) ([]byte, error) {
+	if !algorithm.Available() {
+		return nil, fmt.Errorf("hash algorithm %v is not available", algorithm)
+	}
+	hash := algorithm.New()
+
+	// Hash the headers, skipping the CheckSum field and the certificate table entry
+	if i.certDirectoryOffset != 0 {
+		hash.Write(i.data[:i.checksumOffset])
+		hash.Write(i.data[i.checksumOffset+4 : i.certDirectoryOffset])
+		hash.Write(i.data[i.certDirectoryOffset+8 : i.sizeOfHeaders])
+	} else {
+		hash.Write(i.data[:i.checksumOffset])
+		hash.Write(i.data[i.checksumOffset+4 : i.sizeOfHeaders])
+	}
+
+	// Hash each section in order of its offset within the file
+	sections := append([]fileRange{}, i.sections...)
+	sort.Slice(sections, func(a, b int) bool { return sections[a].Offset < sections[b].Offset })
+	hashed := uint64(i.sizeOfHeaders)
+	for _, section := range sections {
+		if section.Size == 0 {
+			continue
+		}
+		hash.Write(i.data[section.Offset : section.Offset+section.Size])
+		hashed += uint64(section.Size)
+	}
+
+	// Hash any data that follows the sections, excluding the certificate table at the end of the file
+	if end := uint64(len(i.data)) - uint64(i.certTable.Size); end > hashed {
+		hash.Write(i.data[hashed:end])
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// Parses the Authenticode signatures in the image's certificate table
+// (Images that are not signed have no signatures, and certificate types other than PKCS#7 signed data are ignored)
+func (i *Image) Signatures() ([]*Signature, error) {
+	signatures := []*Signature{}
+	table := i.data[i.certTable.Offset : i.certTable.Offset+i.certTable.Size]
+	for len(table) > 0 {
+
+		// Parse the WIN_CERTIFICATE header for the entry
+		if len(table) < 8 {
+			return nil, errors.New("certificate table entry is truncated")
+		}
+		length := binary.LittleEndian.Uint32(table[0:])
+		certificateType := binary.LittleEndian.Uint16(table[6:])
+		if length < 8 || uint64(length) > uint64(len(table)) {
+			return nil, fmt.Errorf("certificate table entry has invalid length %d", length)
+		}
+
+		// Parse the signature if the entry holds PKCS#7 signed data
+		if certificateType == WIN_CERT_TYPE_PKCS_SIGNED_DATA {
+			signature, err := ParseSignature(table[8:length])
+			if err != nil {
+				return nil, err
+			}
+			signatures = append(signatures, signature)
+		}
+
+		// Entries are aligned to 8-byte boundaries
+		next := (uint64(length) + 7) &^ 7
+		if next >= uint64(len(table)) {
+			break
+		}
+		table = table[next:]
+	}
+
+	return signatures, nil
+}
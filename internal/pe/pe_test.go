@@ -0,0 +1,109 @@
+package pe
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/pe/petest"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		machine uint16
+		magic   uint16
+		arch    string
+	}{
+		{name: "x64 PE32+", machine: IMAGE_FILE_MACHINE_AMD64, magic: IMAGE_NT_OPTIONAL_HDR64_MAGIC, arch: "X64"},
+		{name: "ia32 PE32", machine: IMAGE_FILE_MACHINE_I386, magic: IMAGE_NT_OPTIONAL_HDR32_MAGIC, arch: "IA32"},
+		{name: "aa64 PE32+", machine: IMAGE_FILE_MACHINE_ARM64, magic: IMAGE_NT_OPTIONAL_HDR64_MAGIC, arch: "AA64"},
+		{name: "arm PE32", machine: IMAGE_FILE_MACHINE_ARMTHUMB_MIXED, magic: IMAGE_NT_OPTIONAL_HDR32_MAGIC, arch: "ARM"},
+		{name: "unknown machine", machine: 0x1234, magic: IMAGE_NT_OPTIONAL_HDR64_MAGIC, arch: "0x1234"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			image, err := Parse(petest.NewImage(testCase.machine, testCase.magic, []byte("loader")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if image.Machine != testCase.machine {
+				t.Errorf("got machine 0x%04x, expected 0x%04x", image.Machine, testCase.machine)
+			}
+			if name := MachineName(image.Machine); name != testCase.arch {
+				t.Errorf("got machine name %s, expected %s", name, testCase.arch)
+			}
+			if len(image.sections) != 1 || image.sections[0].Offset != image.sizeOfHeaders {
+				t.Errorf("got sections %v, expected one section at offset 0x%x", image.sections, image.sizeOfHeaders)
+			}
+			if image.IsSigned() {
+				t.Errorf("got IsSigned() true for an unsigned image")
+			}
+		})
+	}
+}
+
+func TestParseCorruptHeaders(t *testing.T) {
+	valid := petest.NewImage(IMAGE_FILE_MACHINE_AMD64, IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("loader"))
+	const optionalHeader = 0x58
+	sectionTable := optionalHeader + int(binary.LittleEndian.Uint16(valid[0x54:]))
+
+	// Returns a copy of the valid image with the specified modification applied
+	corrupt := func(modify func(data []byte) []byte) []byte {
+		return modify(append([]byte{}, valid...))
+	}
+	put16 := func(offset int, value uint16) []byte {
+		return corrupt(func(data []byte) []byte { binary.LittleEndian.PutUint16(data[offset:], value); return data })
+	}
+	put32 := func(offset int, value uint32) []byte {
+		return corrupt(func(data []byte) []byte { binary.LittleEndian.PutUint32(data[offset:], value); return data })
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{name: "empty", data: []byte{}, err: "MS-DOS header"},
+		{name: "truncated MS-DOS header", data: valid[:0x3f], err: "MS-DOS header"},
+		{name: "missing MZ signature", data: put16(0, 0x5a4e), err: "MS-DOS header"},
+		{name: "PE signature offset beyond the end of the file", data: put32(0x3c, 0xfffffff0), err: "PE signature"},
+		{name: "missing PE signature", data: put32(0x40, 0x00004551), err: "PE signature"},
+		{name: "truncated COFF header", data: valid[:0x50], err: "PE signature"},
+		{name: "unknown optional header magic", data: put16(optionalHeader, 0x0107), err: "unknown optional header magic 0x0107"},
+		{name: "optional header smaller than its data directories", data: put16(0x54, 64), err: "optional header is truncated"},
+		{name: "optional header smaller than its magic number", data: put16(0x54, 1), err: "optional header is truncated"},
+		{name: "optional header beyond the end of the file", data: put16(0x54, 0xffff), err: "optional header is truncated"},
+		{name: "truncated optional header", data: valid[:optionalHeader+100], err: "optional header is truncated"},
+		{name: "headers beyond the end of the file", data: put32(optionalHeader+60, uint32(len(valid))+1), err: "headers extend beyond"},
+		{name: "headers smaller than the optional header", data: put32(optionalHeader+60, optionalHeader+32), err: "size of headers is smaller"},
+		{name: "section table beyond the end of the file", data: put16(0x46, 0xffff), err: "section table is truncated"},
+		{name: "section beyond the end of the file", data: put32(sectionTable+16, uint32(len(valid))), err: "section 0 extends beyond"},
+		{name: "section offset overflows", data: put32(sectionTable+20, 0xffffffff), err: "section 0 extends beyond"},
+		{
+			name: "certificate table beyond the end of the file",
+			data: corrupt(func(data []byte) []byte {
+				binary.LittleEndian.PutUint32(data[optionalHeader+112+4*8:], uint32(len(data))-4)
+				binary.LittleEndian.PutUint32(data[optionalHeader+112+4*8+4:], 8)
+				return data
+			}),
+			err: "certificate table extends beyond",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := Parse(testCase.data); err == nil || !strings.Contains(err.Error(), testCase.err) {
+				t.Errorf("got error %v, expected %q", err, testCase.err)
+			}
+		})
+	}
+
+	// Every truncation of a valid image is rejected without panicking
+	for length := 0; length < len(valid); length++ {
+		if _, err := Parse(valid[:length]); err == nil {
+			t.Errorf("got no error for an image truncated to %d bytes", length)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package petest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"sync/atomic"
+	"time"
+)
+
+// The optional header magic number for PE32+ images, from: <https://learn.microsoft.com/en-us/windows/win32/debug/pe-format#optional-header-image-only>
+const IMAGE_NT_OPTIONAL_HDR64_MAGIC uint16 = 0x020b
+
+// The alignment of the headers and section data within the images that we build
+const fileAlignment = 0x200
+
+// The offset of the PE signature within the images that we build, which immediately follows the MS-DOS header
+const signatureOffset = 0x40
+
+// The offset of the optional header within the images that we build
+const optionalHeaderOffset = signatureOffset + 24
+
+// Object identifiers for the PKCS#7 and Authenticode structures that we build
+var (
+	oidSignedData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSpcIndirectData  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+	oidSpcPEImageData   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 15}
+	oidECPublicKey      = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidDigestAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA1:   {1, 3, 14, 3, 2, 26},
+		crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+		crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+		crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+	}
+)
+
+// ASN.1 structures from RFC 2315 and the Authenticode specification, with the tagged fields represented as raw values
+// so that their tags can be encoded explicitly
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type spcAttributeTypeAndOptionalValue struct {
+	Type asn1.ObjectIdentifier
+}
+
+type spcIndirectDataContent struct {
+	Data          spcAttributeTypeAndOptionalValue
+	MessageDigest digestInfo
+}
+
+type digestInfo struct {
+	DigestAlgorithm pkix.AlgorithmIdentifier
+	Digest          []byte
+}
+
+// The serial number of the most recently created certificate
+var serialNumber int64
+
+// Panics if an error occurred while building a fixture, since fixtures are only built by tests
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Builds a minimal PE32 or PE32+ image for the specified machine type and optional header magic number, with a single
+// section holding the specified data
+// (The image has a full set of data directories, all of which are empty, so it can be signed with `AppendSignatures()`)
+func NewImage(machine uint16, magic uint16, contents []byte) []byte {
+
+	// Determine the size of the optional header, which includes the 16 data directories
+	sizeOfOptionalHeader := 96 + 16*8
+	if magic == IMAGE_NT_OPTIONAL_HDR64_MAGIC {
+		sizeOfOptionalHeader = 112 + 16*8
+	}
+	sectionTable := optionalHeaderOffset + sizeOfOptionalHeader
+	sizeOfHeaders := align(sectionTable+40, fileAlignment)
+	sizeOfRawData := align(len(contents), fileAlignment)
+	image := make([]byte, sizeOfHeaders+sizeOfRawData)
+
+	// Write the MS-DOS header and the PE signature
+	copy(image[0:], "MZ")
+	binary.LittleEndian.PutUint32(image[0x3c:], signatureOffset)
+	copy(image[signatureOffset:], "PE\x00\x00")
+
+	// Write the COFF file header
+	coff := image[signatureOffset+4:]
+	binary.LittleEndian.PutUint16(coff[0:], machine)
+	binary.LittleEndian.PutUint16(coff[2:], 1)
+	binary.LittleEndian.PutUint16(coff[16:], uint16(sizeOfOptionalHeader))
+	binary.LittleEndian.PutUint16(coff[18:], 0x0002)
+
+	// Write the fields of the optional header that describe the layout of the file
+	optional := image[optionalHeaderOffset:]
+	binary.LittleEndian.PutUint16(optional[0:], magic)
+	binary.LittleEndian.PutUint32(optional[32:], fileAlignment)
+	binary.LittleEndian.PutUint32(optional[36:], fileAlignment)
+	binary.LittleEndian.PutUint32(optional[56:], uint32(sizeOfHeaders+sizeOfRawData))
+	binary.LittleEndian.PutUint32(optional[60:], uint32(sizeOfHeaders))
+	binary.LittleEndian.PutUint16(optional[68:], 10)
+	binary.LittleEndian.PutUint32(optional[sizeOfOptionalHeader-16*8-4:], 16)
+
+	// Write the section header and the section data
+	section := image[sectionTable:]
+	copy(section[0:], ".text")
+	binary.LittleEndian.PutUint32(section[8:], uint32(len(contents)))
+	binary.LittleEndian.PutUint32(section[12:], uint32(sizeOfHeaders))
+	binary.LittleEndian.PutUint32(section[16:], uint32(sizeOfRawData))
+	binary.LittleEndian.PutUint32(section[20:], uint32(sizeOfHeaders))
+	binary.LittleEndian.PutUint32(section[36:], 0x60000020)
+	copy(image[sizeOfHeaders:], contents)
+
+	return image
+}
+
+// Returns a copy of an image built by `NewImage()` with a certificate table holding the specified PKCS#7 signatures
+// (The Authenticode digest of the image is unaffected, since it excludes the certificate table and its directory entry)
+func AppendSignatures(image []byte, signatures ...[]byte) []byte {
+
+	// Build the certificate table, aligning each WIN_CERTIFICATE entry to an 8-byte boundary
+	table := []byte{}
+	for _, signature := range signatures {
+		entry := make([]byte, align(8+len(signature), 8))
+		binary.LittleEndian.PutUint32(entry[0:], uint32(8+len(signature)))
+		binary.LittleEndian.PutUint16(entry[4:], 0x0200)
+		binary.LittleEndian.PutUint16(entry[6:], 0x0002)
+		copy(entry[8:], signature)
+		table = append(table, entry...)
+	}
+
+	// Append the table to the image and point the certificate table directory entry at it
+	directories := optionalHeaderOffset + 96
+	if binary.LittleEndian.Uint16(image[optionalHeaderOffset:]) == IMAGE_NT_OPTIONAL_HDR64_MAGIC {
+		directories = optionalHeaderOffset + 112
+	}
+	signed := append(append([]byte{}, image...), table...)
+	binary.LittleEndian.PutUint32(signed[directories+4*8:], uint32(len(image)))
+	binary.LittleEndian.PutUint32(signed[directories+4*8+4:], uint32(len(table)))
+	return signed
+}
+
+// Creates an ECDSA certificate with the specified common name, issued by the specified certificate and key
+// (The certificate is self-signed if the issuer is nil, and every certificate is marked as a CA so that it can issue
+// further certificates)
+func NewCertificate(commonName string, issuer *x509.Certificate, issuerKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	check(err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(atomic.AddInt64(&serialNumber, 1)),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if issuer == nil {
+		issuer, issuerKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, key.Public(), issuerKey)
+	check(err)
+	certificate, err := x509.ParseCertificate(der)
+	check(err)
+	return certificate, key
+}
+
+// Creates a PKCS#7 signed data structure holding an Authenticode signature over the specified image digest, signed
+// by the specified certificate and key and embedding the specified certificates
+// (The signer is only embedded if it is included in the list of certificates, so that signatures with a missing
+// signer can be built)
+func NewSignature(algorithm crypto.Hash, digest []byte, signer *x509.Certificate, key crypto.Signer, certificates ...*x509.Certificate) []byte {
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: oidDigestAlgorithms[algorithm], Parameters: asn1.NullRawValue}
+
+	// Encode the indirect data content that holds the image digest
+	indirect, err := asn1.Marshal(spcIndirectDataContent{
+		Data:          spcAttributeTypeAndOptionalValue{Type: oidSpcPEImageData},
+		MessageDigest: digestInfo{DigestAlgorithm: digestAlgorithm, Digest: digest},
+	})
+	check(err)
+	content := asn1.RawValue{}
+	_, err = asn1.Unmarshal(indirect, &content)
+	check(err)
+
+	// Encode the authenticated attributes, which hold the digest of the indirect data content (excluding its tag and length)
+	hash := algorithm.New()
+	hash.Write(content.Bytes)
+	contentType, err := asn1.Marshal(oidSpcIndirectData)
+	check(err)
+	messageDigest, err := asn1.Marshal(hash.Sum(nil))
+	check(err)
+	attributes := []byte{}
+	for _, current := range []attribute{
+		{Type: oidContentType, Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: contentType}},
+		{Type: oidMessageDigest, Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: messageDigest}},
+	} {
+		encoded, err := asn1.Marshal(current)
+		check(err)
+		attributes = append(attributes, encoded...)
+	}
+
+	// Sign the authenticated attributes using their explicit SET OF encoding
+	set, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attributes})
+	check(err)
+	hash = algorithm.New()
+	hash.Write(set)
+	encryptedDigest, err := key.Sign(rand.Reader, hash.Sum(nil), algorithm)
+	check(err)
+
+	// Encode the signed data, embedding the certificates and wrapping it in a content info
+	embedded := []byte{}
+	for _, certificate := range certificates {
+		embedded = append(embedded, certificate.Raw...)
+	}
+	signed, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		ContentInfo: contentInfo{
+			ContentType: oidSpcIndirectData,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: indirect},
+		},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: embedded},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			IssuerAndSerialNumber: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: signer.RawIssuer},
+				SerialNumber: signer.SerialNumber,
+			},
+			DigestAlgorithm:           digestAlgorithm,
+			AuthenticatedAttributes:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attributes},
+			DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECPublicKey},
+			EncryptedDigest:           encryptedDigest,
+		}},
+	})
+	check(err)
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+	check(err)
+	return der
+}
+
+// Rounds a size up to the next multiple of the specified alignment
+func align(size int, alignment int) int {
+	return (size + alignment - 1) / alignment * alignment
+}
@@ -0,0 +1,203 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/terminal"
+)
+
+// The error returned when the user cancels a prompt
+var ErrCancelled = errors.New("cancelled by the user")
+
+// The number of rows used by the title, the filter, the status line and the blank line that follows the list
+const reservedRows = 4
+
+// The number of items displayed at once when the height of the terminal is unknown
+const defaultVisibleRows = 10
+
+// Represents an item that the user can choose from the list
+type Item struct {
+
+	// The text displayed for the item, which is matched against the filter that the user types
+	Label string
+
+	// Short annotations displayed after the label (e.g. "current"), which are also matched against the filter
+	Tags []string
+}
+
+// The outcome of handling a key press
+type Result int
+
+const (
+	Continue Result = iota
+	Selected
+	Cancelled
+)
+
+// Holds the state of an interactive list that can be navigated with the arrow keys and filtered by typing
+// (The state is separate from the terminal so that the key handling can be driven by a fake terminal)
+type Picker struct {
+
+	// The prompt displayed above the list
+	Title string
+
+	// The items that the user can choose from
+	items []Item
+
+	// The text that the user has typed to filter the items
+	filter []rune
+
+	// The indices of the items that match the filter
+	matches []int
+
+	// The position of the highlighted item within the matching items
+	cursor int
+
+	// The position of the first visible item within the matching items
+	offset int
+
+	// The maximum number of items that are visible at once
+	rows int
+}
+
+// Creates a picker for the specified items, with the first item highlighted
+func New(title string, items []Item) *Picker {
+	p := &Picker{Title: title, items: items, rows: defaultVisibleRows}
+	p.applyFilter()
+	return p
+}
+
+// Sets the maximum number of items that are visible at once, based on the height of the terminal
+func (p *Picker) SetHeight(height int) {
+	if height > reservedRows {
+		p.rows = height - reservedRows
+	}
+	p.scrollToCursor()
+}
+
+// Returns the index of the highlighted item, or -1 if no items match the filter
+func (p *Picker) Current() int {
+	if len(p.matches) == 0 {
+		return -1
+	}
+	return p.matches[p.cursor]
+}
+
+// Updates the state of the picker in response to a key press
+// (Selecting is ignored when no items match the filter, and Escape clears the filter before it cancels the prompt)
+func (p *Picker) HandleKey(key terminal.Key) Result {
+	switch key.Type {
+	case terminal.KeyEnter:
+		if p.Current() != -1 {
+			return Selected
+		}
+	case terminal.KeyInterrupt:
+		return Cancelled
+	case terminal.KeyEscape:
+		if len(p.filter) == 0 {
+			return Cancelled
+		}
+		p.filter = nil
+		p.applyFilter()
+	case terminal.KeyRune:
+		p.filter = append(p.filter, key.Rune)
+		p.applyFilter()
+	case terminal.KeyBackspace:
+		if len(p.filter) > 0 {
+			p.filter = p.filter[:len(p.filter)-1]
+			p.applyFilter()
+		}
+	case terminal.KeyUp:
+		p.moveCursor(-1)
+	case terminal.KeyDown:
+		p.moveCursor(1)
+	case terminal.KeyPageUp:
+		p.moveCursor(-p.rows)
+	case terminal.KeyPageDown:
+		p.moveCursor(p.rows)
+	case terminal.KeyHome:
+		p.moveCursor(-len(p.matches))
+	case terminal.KeyEnd:
+		p.moveCursor(len(p.matches))
+	}
+	return Continue
+}
+
+// Moves the highlight by the specified number of items, stopping at the start and end of the list
+func (p *Picker) moveCursor(delta int) {
+	p.cursor += delta
+	if p.cursor >= len(p.matches) {
+		p.cursor = len(p.matches) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	p.scrollToCursor()
+}
+
+// Adjusts the visible portion of the list so that it contains the highlighted item
+func (p *Picker) scrollToCursor() {
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+p.rows {
+		p.offset = p.cursor - p.rows + 1
+	}
+}
+
+// Identifies the items that contain the filter text (case insensitive), keeping the highlight on the same item if it
+// still matches
+func (p *Picker) applyFilter() {
+	highlighted := p.Current()
+	filter := strings.ToLower(string(p.filter))
+
+	p.matches = []int{}
+	p.cursor, p.offset = 0, 0
+	for index, item := range p.items {
+		text := strings.ToLower(item.Label + " " + strings.Join(item.Tags, " "))
+		if strings.Contains(text, filter) {
+			if index == highlighted {
+				p.cursor = len(p.matches)
+			}
+			p.matches = append(p.matches, index)
+		}
+	}
+	p.scrollToCursor()
+}
+
+// Returns the lines that display the current state of the picker, truncated to the specified width (if it is known)
+func (p *Picker) Render(width int) []string {
+	lines := []string{
+		p.Title,
+		fmt.Sprintf("Filter: %s", string(p.filter)),
+	}
+
+	for position := p.offset; position < len(p.matches) && position < p.offset+p.rows; position++ {
+		item := p.items[p.matches[position]]
+		line := "  " + item.Label
+		if len(item.Tags) > 0 {
+			line += fmt.Sprintf(" [%s]", strings.Join(item.Tags, ", "))
+		}
+		line = truncate(line, width)
+		if position == p.cursor {
+			line = "\x1b[7m>" + line[1:] + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+
+	if len(p.matches) == 0 {
+		lines = append(lines, "  (no matching items)")
+	}
+	lines = append(lines, fmt.Sprintf("(%d of %d shown; type to filter, Up/Down to move, Enter to select, Esc to cancel)", len(p.matches), len(p.items)))
+	return lines
+}
+
+// Truncates a line to fit within the specified width, so that it does not wrap onto the next line of the terminal
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if width <= 1 || len(runes) < width {
+		return line
+	}
+	return string(runes[:width-1])
+}
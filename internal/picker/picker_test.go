@@ -0,0 +1,381 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/terminal"
+)
+
+// The items used by most of the tests, resembling a typical list of boot entries
+var testItems = []Item{
+	{Label: "ubuntu", Tags: []string{"current"}},
+	{Label: "Windows Boot Manager"},
+	{Label: "UEFI: USB", Tags: []string{"removable"}},
+	{Label: "Fedora Linux"},
+	{Label: "UEFI: PXE IPv4 Intel(R) Ethernet", Tags: []string{"network"}},
+}
+
+// Creates a picker with the specified number of numbered items
+func newNumberedPicker(count int) *Picker {
+	items := []Item{}
+	for index := 0; index < count; index++ {
+		items = append(items, Item{Label: fmt.Sprintf("Item %02d", index)})
+	}
+	return New("Select an item:", items)
+}
+
+// Sends a sequence of key presses to a picker, failing the test if any of them finishes the prompt
+func press(t *testing.T, p *Picker, keys ...terminal.Key) {
+	t.Helper()
+	for _, key := range keys {
+		if result := p.HandleKey(key); result != Continue {
+			t.Fatalf("key %v: got result %d, expected Continue", key, result)
+		}
+	}
+}
+
+// Returns the key presses for typing the specified text
+func typed(text string) []terminal.Key {
+	keys := []terminal.Key{}
+	for _, character := range text {
+		keys = append(keys, terminal.Key{Type: terminal.KeyRune, Rune: character})
+	}
+	return keys
+}
+
+// Returns the key press for a key that does not type a character
+func key(keyType terminal.KeyType) terminal.Key {
+	return terminal.Key{Type: keyType}
+}
+
+// Returns the labels of the items that match the filter, in the order they are displayed
+func matchingLabels(p *Picker) []string {
+	labels := []string{}
+	for _, index := range p.matches {
+		labels = append(labels, p.items[index].Label)
+	}
+	return labels
+}
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   string
+		expected []string
+	}{
+		{name: "empty", filter: "", expected: []string{"ubuntu", "Windows Boot Manager", "UEFI: USB", "Fedora Linux", "UEFI: PXE IPv4 Intel(R) Ethernet"}},
+		{name: "case insensitive", filter: "uefi", expected: []string{"UEFI: USB", "UEFI: PXE IPv4 Intel(R) Ethernet"}},
+		{name: "substring", filter: "boot man", expected: []string{"Windows Boot Manager"}},
+		{name: "tags", filter: "REMOVABLE", expected: []string{"UEFI: USB"}},
+		{name: "label and tag", filter: "usb removable", expected: []string{"UEFI: USB"}},
+		{name: "no matches", filter: "macos", expected: []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			p := New("Select a boot entry:", testItems)
+			press(t, p, typed(testCase.filter)...)
+			if actual := matchingLabels(p); strings.Join(actual, "|") != strings.Join(testCase.expected, "|") {
+				t.Errorf("got %q, expected %q", actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestBackspaceWidensFilter(t *testing.T) {
+	p := New("Select a boot entry:", testItems)
+	press(t, p, typed("uefi:x")...)
+	if len(p.matches) != 0 {
+		t.Fatalf("expected no matches, got %q", matchingLabels(p))
+	}
+
+	press(t, p, key(terminal.KeyBackspace))
+	if actual := matchingLabels(p); len(actual) != 2 {
+		t.Errorf("got %q, expected both UEFI items", actual)
+	}
+
+	// Backspace does nothing once the filter is empty
+	press(t, p, key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace))
+	if string(p.filter) != "" || len(p.matches) != len(testItems) {
+		t.Errorf("got filter %q with %d matches, expected an empty filter matching every item", string(p.filter), len(p.matches))
+	}
+}
+
+func TestFilterKeepsHighlightedItem(t *testing.T) {
+	p := New("Select a boot entry:", testItems)
+
+	// Highlight the USB item, then type a filter that still matches it
+	press(t, p, key(terminal.KeyDown), key(terminal.KeyDown))
+	press(t, p, typed("uefi")...)
+	if current := p.Current(); current != 2 {
+		t.Errorf("after filtering: got item %d, expected item 2", current)
+	}
+
+	// Clearing the filter keeps the highlight on the same item rather than returning to the top of the list
+	press(t, p, key(terminal.KeyEscape))
+	if current := p.Current(); current != 2 {
+		t.Errorf("after clearing the filter: got item %d, expected item 2", current)
+	}
+
+	// A filter that excludes the highlighted item moves the highlight to the first match
+	press(t, p, typed("linux")...)
+	if current := p.Current(); current != 3 {
+		t.Errorf("after excluding the highlighted item: got item %d, expected item 3", current)
+	}
+
+	// The highlight then follows the new item as the filter widens
+	press(t, p, key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace), key(terminal.KeyBackspace))
+	if current := p.Current(); current != 3 {
+		t.Errorf("after widening the filter: got item %d, expected item 3", current)
+	}
+}
+
+func TestNavigationClamps(t *testing.T) {
+
+	// 25 items with a terminal height of 9, which leaves room for 5 visible items
+	testCases := []struct {
+		name     string
+		keys     []terminal.KeyType
+		expected int
+		offset   int
+	}{
+		{name: "up at start", keys: []terminal.KeyType{terminal.KeyUp}, expected: 0, offset: 0},
+		{name: "page up at start", keys: []terminal.KeyType{terminal.KeyPageUp}, expected: 0, offset: 0},
+		{name: "home at start", keys: []terminal.KeyType{terminal.KeyHome}, expected: 0, offset: 0},
+		{name: "down", keys: []terminal.KeyType{terminal.KeyDown, terminal.KeyDown}, expected: 2, offset: 0},
+		{name: "down past visible rows", keys: []terminal.KeyType{terminal.KeyDown, terminal.KeyDown, terminal.KeyDown, terminal.KeyDown, terminal.KeyDown, terminal.KeyDown}, expected: 6, offset: 2},
+		{name: "page down", keys: []terminal.KeyType{terminal.KeyPageDown}, expected: 5, offset: 1},
+		{name: "page down to end", keys: []terminal.KeyType{terminal.KeyPageDown, terminal.KeyPageDown, terminal.KeyPageDown, terminal.KeyPageDown, terminal.KeyPageDown, terminal.KeyPageDown}, expected: 24, offset: 20},
+		{name: "end", keys: []terminal.KeyType{terminal.KeyEnd}, expected: 24, offset: 20},
+		{name: "down at end", keys: []terminal.KeyType{terminal.KeyEnd, terminal.KeyDown}, expected: 24, offset: 20},
+		{name: "page down at end", keys: []terminal.KeyType{terminal.KeyEnd, terminal.KeyPageDown}, expected: 24, offset: 20},
+		{name: "page up from end", keys: []terminal.KeyType{terminal.KeyEnd, terminal.KeyPageUp}, expected: 19, offset: 19},
+		{name: "page up to start", keys: []terminal.KeyType{terminal.KeyDown, terminal.KeyDown, terminal.KeyPageUp}, expected: 0, offset: 0},
+		{name: "home from end", keys: []terminal.KeyType{terminal.KeyEnd, terminal.KeyHome}, expected: 0, offset: 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			p := newNumberedPicker(25)
+			p.SetHeight(9)
+			for _, keyType := range testCase.keys {
+				press(t, p, key(keyType))
+			}
+			if current := p.Current(); current != testCase.expected {
+				t.Errorf("highlight: got item %d, expected item %d", current, testCase.expected)
+			}
+			if p.offset != testCase.offset {
+				t.Errorf("first visible item: got %d, expected %d", p.offset, testCase.offset)
+			}
+		})
+	}
+}
+
+func TestNavigationClampsToFilteredItems(t *testing.T) {
+
+	// "Item 1" matches items 10 to 19, so End must stop at the last of them rather than the last item overall
+	p := newNumberedPicker(25)
+	p.SetHeight(9)
+	press(t, p, typed("item 1")...)
+	press(t, p, key(terminal.KeyEnd))
+	if current := p.Current(); current != 19 {
+		t.Errorf("end: got item %d, expected item 19", current)
+	}
+	press(t, p, key(terminal.KeyPageDown))
+	if current := p.Current(); current != 19 {
+		t.Errorf("page down: got item %d, expected item 19", current)
+	}
+	press(t, p, key(terminal.KeyHome))
+	if current := p.Current(); current != 10 {
+		t.Errorf("home: got item %d, expected item 10", current)
+	}
+}
+
+func TestEscapeClearsFilterBeforeCancelling(t *testing.T) {
+	p := New("Select a boot entry:", testItems)
+	press(t, p, typed("windows")...)
+
+	// The first Escape clears the filter
+	if result := p.HandleKey(key(terminal.KeyEscape)); result != Continue {
+		t.Fatalf("first escape: got result %d, expected Continue", result)
+	}
+	if string(p.filter) != "" || len(p.matches) != len(testItems) {
+		t.Errorf("first escape: got filter %q with %d matches, expected an empty filter matching every item", string(p.filter), len(p.matches))
+	}
+
+	// The second Escape cancels the prompt
+	if result := p.HandleKey(key(terminal.KeyEscape)); result != Cancelled {
+		t.Errorf("second escape: got result %d, expected Cancelled", result)
+	}
+}
+
+func TestInterruptCancelsImmediately(t *testing.T) {
+	p := New("Select a boot entry:", testItems)
+	press(t, p, typed("windows")...)
+	if result := p.HandleKey(key(terminal.KeyInterrupt)); result != Cancelled {
+		t.Errorf("got result %d, expected Cancelled", result)
+	}
+}
+
+func TestEnterIgnoredWithoutMatches(t *testing.T) {
+	p := New("Select a boot entry:", testItems)
+	press(t, p, typed("macos")...)
+	if result := p.HandleKey(key(terminal.KeyEnter)); result != Continue {
+		t.Errorf("got result %d, expected Continue", result)
+	}
+	if current := p.Current(); current != -1 {
+		t.Errorf("got item %d, expected no highlighted item", current)
+	}
+
+	// Enter selects the highlighted item once the filter matches something again
+	press(t, p, key(terminal.KeyEscape), key(terminal.KeyDown))
+	if result := p.HandleKey(key(terminal.KeyEnter)); result != Selected {
+		t.Errorf("got result %d, expected Selected", result)
+	}
+	if current := p.Current(); current != 1 {
+		t.Errorf("got item %d, expected item 1", current)
+	}
+}
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keys     []terminal.Key
+		width    int
+		expected []string
+	}{
+		{
+			name:  "unfiltered",
+			width: 0,
+			expected: []string{
+				"Select a boot entry:",
+				"Filter: ",
+				"\x1b[7m> ubuntu [current]\x1b[0m",
+				"  Windows Boot Manager",
+				"  UEFI: USB [removable]",
+				"  Fedora Linux",
+				"  UEFI: PXE IPv4 Intel(R) Ethernet [network]",
+				"(5 of 5 shown; type to filter, Up/Down to move, Enter to select, Esc to cancel)",
+			},
+		},
+		{
+			name:  "filtered",
+			keys:  append(typed("uefi"), key(terminal.KeyDown)),
+			width: 0,
+			expected: []string{
+				"Select a boot entry:",
+				"Filter: uefi",
+				"  UEFI: USB [removable]",
+				"\x1b[7m> UEFI: PXE IPv4 Intel(R) Ethernet [network]\x1b[0m",
+				"(2 of 5 shown; type to filter, Up/Down to move, Enter to select, Esc to cancel)",
+			},
+		},
+		{
+			name:  "no matches",
+			keys:  typed("macos"),
+			width: 0,
+			expected: []string{
+				"Select a boot entry:",
+				"Filter: macos",
+				"  (no matching items)",
+				"(0 of 5 shown; type to filter, Up/Down to move, Enter to select, Esc to cancel)",
+			},
+		},
+		{
+			name:  "truncated",
+			keys:  typed("o"),
+			width: 16,
+			expected: []string{
+				"Select a boot entry:",
+				"Filter: o",
+				"\x1b[7m> Windows Boot \x1b[0m",
+				"  UEFI: USB [re",
+				"  Fedora Linux",
+				"  UEFI: PXE IPv",
+				"(4 of 5 shown; type to filter, Up/Down to move, Enter to select, Esc to cancel)",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			p := New("Select a boot entry:", testItems)
+			press(t, p, testCase.keys...)
+			actual := p.Render(testCase.width)
+			if strings.Join(actual, "\n") != strings.Join(testCase.expected, "\n") {
+				t.Errorf("got:\n%q\nexpected:\n%q", actual, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestRenderScrollsToHighlightedItem(t *testing.T) {
+	p := newNumberedPicker(25)
+	p.SetHeight(7)
+	press(t, p, key(terminal.KeyEnd), key(terminal.KeyUp))
+
+	expected := []string{
+		"Select an item:",
+		"Filter: ",
+		"  Item 22",
+		"\x1b[7m> Item 23\x1b[0m",
+		"  Item 24",
+		"(25 of 25 shown; type to filter, Up/Down to move, Enter to select, Esc to cancel)",
+	}
+	if actual := p.Render(0); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got:\n%q\nexpected:\n%q", actual, expected)
+	}
+}
+
+// Replays a scripted sequence of key presses and records the output written to the terminal
+type fakeTerminal struct {
+	strings.Builder
+	keys []terminal.Key
+}
+
+func (f *fakeTerminal) ReadKey() (terminal.Key, error) {
+	if len(f.keys) == 0 {
+		return terminal.Key{}, io.EOF
+	}
+	key := f.keys[0]
+	f.keys = f.keys[1:]
+	return key, nil
+}
+
+func (f *fakeTerminal) Size() (int, int) {
+	return 80, 24
+}
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keys     []terminal.Key
+		expected int
+		err      error
+	}{
+		{name: "select", keys: append(typed("fedora"), key(terminal.KeyEnter)), expected: 3},
+		{name: "enter without matches", keys: append(typed("macos"), key(terminal.KeyEnter), key(terminal.KeyEscape), key(terminal.KeyEnter)), expected: 0},
+		{name: "cancel", keys: append(typed("fedora"), key(terminal.KeyEscape), key(terminal.KeyEscape)), expected: -1, err: ErrCancelled},
+		{name: "input closed", keys: typed("fedora"), expected: -1, err: io.EOF},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			term := &fakeTerminal{keys: testCase.keys}
+			selected, err := Run(term, "Select a boot entry:", testItems)
+			if selected != testCase.expected || !errors.Is(err, testCase.err) {
+				t.Errorf("got (%d, %v), expected (%d, %v)", selected, err, testCase.expected, testCase.err)
+			}
+
+			// The cursor is hidden while the list is displayed and restored afterwards
+			output := term.String()
+			if !strings.HasPrefix(output, hideCursor) || !strings.HasSuffix(output, showCursor) {
+				t.Errorf("expected the cursor to be hidden and restored, got %q", output)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package picker
+
+import (
+	"fmt"
+
+	"github.com/tensorworks/bootnext/internal/terminal"
+)
+
+// ANSI escape sequences for hiding and showing the cursor while the list is displayed
+const (
+	hideCursor = "\x1b[?25l"
+	showCursor = "\x1b[?25h"
+)
+
+// Displays a picker on a terminal and waits for the user to choose an item, returning its index
+// (The list is redrawn in place after each key press, and is erased once the user has chosen an item or cancelled)
+func Run(term terminal.Terminal, title string, items []Item) (int, error) {
+	p := New(title, items)
+	fmt.Fprint(term, hideCursor)
+	defer fmt.Fprint(term, showCursor)
+
+	drawn := 0
+	for {
+
+		// Draw the list, sized to fit the terminal
+		width, height := term.Size()
+		p.SetHeight(height)
+		erase(term, drawn)
+		lines := p.Render(width)
+		for _, line := range lines {
+			fmt.Fprintf(term, "%s\r\n", line)
+		}
+		drawn = len(lines)
+
+		// Wait for the next key press and update the list
+		key, err := term.ReadKey()
+		if err != nil {
+			erase(term, drawn)
+			return -1, err
+		}
+		switch p.HandleKey(key) {
+		case Selected:
+			erase(term, drawn)
+			return p.Current(), nil
+		case Cancelled:
+			erase(term, drawn)
+			return -1, ErrCancelled
+		}
+	}
+}
+
+// Erases the specified number of lines above the cursor, leaving the cursor at the start of the first erased line
+func erase(term terminal.Terminal, lines int) {
+	if lines > 0 {
+		fmt.Fprintf(term, "\x1b[%dA\r\x1b[J", lines)
+	}
+}
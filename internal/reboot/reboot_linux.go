@@ -1,9 +1,49 @@
 package reboot
 
-import "github.com/tensorworks/bootnext/internal/process"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tensorworks/bootnext/internal/process"
+)
 
 // Attempts to reboot the system
 func Reboot() error {
 	_, err := process.CaptureOutput([]string{"reboot", "now"})
 	return err
 }
+
+// Schedules a reboot after the specified delay, returning the delay that was actually applied
+// (`shutdown` only accepts delays in whole minutes, so the delay is rounded up to the next minute)
+func rebootAfter(delay time.Duration) (time.Duration, error) {
+	minutes := int((delay + time.Minute - 1) / time.Minute)
+	_, err := process.CaptureOutput([]string{"shutdown", "-r", fmt.Sprintf("+%d", minutes)})
+	return time.Duration(minutes) * time.Minute, err
+}
+
+// Cancels a pending scheduled reboot
+func cancelReboot() error {
+	_, err := process.CaptureOutput([]string{"shutdown", "-c"})
+	return err
+}
+
+// Returns the time at which the system was last booted, as reported by the `btime` field of `/proc/stat`
+func LastBootTime() (time.Time, error) {
+	contents, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "btime" {
+			seconds, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse boot time \"%s\": %v", fields[1], err)
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("failed to find the boot time in /proc/stat")
+}
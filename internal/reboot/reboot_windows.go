@@ -1,9 +1,49 @@
 package reboot
 
-import "github.com/tensorworks/bootnext/internal/process"
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/tensorworks/bootnext/internal/process"
+	"golang.org/x/sys/windows"
+)
+
+// The GetTickCount64() function, which reports the number of milliseconds since the system was booted
+var procGetTickCount64 = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetTickCount64")
 
 // Attempts to reboot the system
 func Reboot() error {
 	_, err := process.CaptureOutput([]string{"shutdown", "/r", "/t", "0"})
 	return err
 }
+
+// Schedules a reboot after the specified delay, returning the delay that was actually applied
+// (`shutdown` only accepts delays in whole seconds, so the delay is rounded up to the next second)
+func rebootAfter(delay time.Duration) (time.Duration, error) {
+	seconds := int((delay + time.Second - 1) / time.Second)
+	_, err := process.CaptureOutput([]string{"shutdown", "/r", "/t", fmt.Sprintf("%d", seconds)})
+	return time.Duration(seconds) * time.Second, err
+}
+
+// Cancels a pending scheduled reboot
+func cancelReboot() error {
+	_, err := process.CaptureOutput([]string{"shutdown", "/a"})
+	return err
+}
+
+// Returns the time at which the system was last booted, based on the number of milliseconds since the system was booted
+func LastBootTime() (time.Time, error) {
+	if err := procGetTickCount64.Find(); err != nil {
+		return time.Time{}, err
+	}
+
+	// Under 32-bit architectures the 64-bit return value is split across the EAX and EDX registers
+	low, high, _ := procGetTickCount64.Call()
+	milliseconds := uint64(low)
+	if unsafe.Sizeof(low) == 4 {
+		milliseconds |= uint64(high) << 32
+	}
+
+	return time.Now().Add(-time.Duration(milliseconds) * time.Millisecond), nil
+}
@@ -0,0 +1,70 @@
+package reboot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tensorworks/bootnext/internal/state"
+)
+
+// The name of the state record that tracks the reboot scheduled by bootnext
+const scheduledRebootRecord = "scheduled-reboot"
+
+// Represents a delayed reboot that has been scheduled by bootnext
+type ScheduledReboot struct {
+
+	// The time at which the reboot will take place
+	Time time.Time `json:"time"`
+}
+
+// Schedules a reboot after the specified delay and records it so that it can be cancelled later
+func Schedule(delay time.Duration) (*ScheduledReboot, error) {
+
+	// Schedule the reboot
+	applied, err := rebootAfter(delay)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the reboot, cancelling it if the record cannot be saved
+	// (Otherwise the reboot would still take place after we had reported a failure, and `bootnext cancel` would not be
+	// able to find it)
+	scheduled := &ScheduledReboot{Time: time.Now().Add(applied)}
+	if err := state.Save(scheduledRebootRecord, scheduled); err != nil {
+		if cancelErr := cancelReboot(); cancelErr != nil {
+			return nil, fmt.Errorf("failed to record the scheduled reboot (%v) and failed to cancel it: %v", err, cancelErr)
+		}
+		return nil, fmt.Errorf("failed to record the scheduled reboot, so it has been cancelled: %v", err)
+	}
+
+	return scheduled, nil
+}
+
+// Retrieves the reboot scheduled by bootnext, or nil if no scheduled reboot is pending
+func Pending() (*ScheduledReboot, error) {
+	scheduled := &ScheduledReboot{}
+	if exists, err := state.Load(scheduledRebootRecord, scheduled); err != nil || !exists {
+		return nil, err
+	} else if scheduled.Time.Before(time.Now()) {
+		return nil, nil
+	}
+	return scheduled, nil
+}
+
+// Cancels the reboot scheduled by bootnext, returning the cancelled reboot or nil if no scheduled reboot was pending
+func CancelScheduled() (*ScheduledReboot, error) {
+	scheduled, err := Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancel the reboot if it is still pending
+	if scheduled != nil {
+		if err := cancelReboot(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove the record, including any stale record for a reboot that has already taken place
+	return scheduled, state.Remove(scheduledRebootRecord)
+}
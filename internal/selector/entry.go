@@ -0,0 +1,67 @@
+package selector
+
+import (
+	"regexp"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The device types that can be used in `type:` terms
+var DeviceTypes = []string{"usb", "sata", "nvme", "disk", "cdrom", "network", "pxe", "http", "firmware", "legacy"}
+
+// The device types implied by each device path node, keyed by the name used in the node's text representation
+var nodeDeviceTypes = map[string][]string{
+	"USB":      {"usb"},
+	"UsbClass": {"usb"},
+	"UsbWwid":  {"usb"},
+	"Sata":     {"sata"},
+	"NVMe":     {"nvme"},
+	"HD":       {"disk"},
+	"CDROM":    {"cdrom"},
+	"MAC":      {"network"},
+	"IPv4":     {"network"},
+	"IPv6":     {"network"},
+	"Uri":      {"network", "http"},
+	"FvFile":   {"firmware"},
+	"FvVol":    {"firmware"},
+	"Fv":       {"firmware"},
+	"BBS":      {"legacy"},
+}
+
+// Extracts the partition GUID from the text representation of device paths, for backends that do not provide the
+// decoded device path (e.g. `efibootmgr`)
+var hardDrivePattern = regexp.MustCompile(`HD\([0-9]+,GPT,([0-9A-Fa-f-]{36})`)
+
+// Returns the unique GUID of the GPT partition in a boot entry's device path, or false if there is none
+func partitionGUID(entry *uefi.BootEntry) (uefi.GUID, bool) {
+	if hardDrive := entry.HardDrive(); hardDrive != nil {
+		guid, _ := hardDrive.PartitionGUID()
+		return guid, true
+	} else if entry.DevicePath == nil {
+		if match := hardDrivePattern.FindStringSubmatch(entry.DevicePathText); match != nil {
+			if guid, err := uefi.ParseGUID(match[1]); err == nil {
+				return guid, true
+			}
+		}
+	}
+	return uefi.GUID{}, false
+}
+
+// Returns the types of the devices in a boot entry's device path (see `DeviceTypes`)
+// (Network boot entries without a URI node boot using PXE, while those with a URI node boot using HTTP)
+func deviceTypes(entry *uefi.BootEntry) []string {
+	types := []string{}
+	seen := map[string]bool{}
+	for _, name := range entry.NodeNames() {
+		for _, deviceType := range nodeDeviceTypes[name] {
+			if !seen[deviceType] {
+				seen[deviceType] = true
+				types = append(types, deviceType)
+			}
+		}
+	}
+	if seen["network"] && !seen["http"] {
+		types = append(types, "pxe")
+	}
+	return types
+}
@@ -0,0 +1,72 @@
+package selector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The number of hexadecimal digits in a fingerprint
+const FingerprintLength = 16
+
+// Patterns for validating fingerprints and for extracting MAC addresses from the text representation of device paths
+var (
+	fingerprintPattern = regexp.MustCompile(fmt.Sprintf(`^[0-9A-Fa-f]{%d}$`, FingerprintLength))
+	macAddressPattern  = regexp.MustCompile(`MAC\(([0-9A-Fa-f]+),`)
+)
+
+// Returns a fingerprint that identifies the target of a boot entry independently of its identifier and description, or
+// an empty string if the device path does not identify a partition or network interface
+// (Disk boot entries are identified by their partition GUID and loader path, and network boot entries are identified by
+// the MAC address of their network interface and the protocols they use, so the fingerprint survives the firmware
+// renumbering or relabelling boot entries when it rebuilds its NVRAM variables)
+func Fingerprint(entry *uefi.BootEntry) string {
+	identity := ""
+	if guid, found := partitionGUID(entry); found {
+		loader := entry.LoaderPath()
+		if loader != "" {
+			loader = strings.ToLower(uefi.NormaliseLoaderPath(loader))
+		}
+		identity = fmt.Sprintf("hd:%s:%s", guid, loader)
+	} else if mac := macAddress(entry); mac != "" {
+		protocols := []string{}
+		for _, name := range entry.NodeNames() {
+			if name == "IPv4" || name == "IPv6" || name == "Uri" {
+				protocols = append(protocols, name)
+			}
+		}
+		identity = fmt.Sprintf("mac:%s:%s", mac, strings.Join(protocols, ","))
+	} else {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(hash[:])[:FingerprintLength]
+}
+
+// Determines whether a string is a well-formed fingerprint
+func IsFingerprint(value string) bool {
+	return fingerprintPattern.MatchString(value)
+}
+
+// Returns the MAC address of the network interface in a boot entry's device path as lowercase hexadecimal digits, or an
+// empty string if there is none
+func macAddress(entry *uefi.BootEntry) string {
+	if entry.DevicePath == nil {
+		if match := macAddressPattern.FindStringSubmatch(entry.DevicePathText); match != nil {
+			return strings.ToLower(match[1])
+		}
+		return ""
+	}
+
+	for _, node := range entry.DevicePath {
+		if mac, ok := node.(*uefi.MACNode); ok {
+			return hex.EncodeToString(mac.HardwareAddress())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,215 @@
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The error wrapped by every error that reports a malformed selector expression
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// A token in a selector expression
+type token struct {
+
+	// The text of the token, with any quotes removed
+	text string
+
+	// Specifies whether any part of the token was quoted, which prevents it from being treated as a keyword or parenthesis
+	quoted bool
+}
+
+// Determines whether the token is the specified keyword or parenthesis
+func (t token) is(keyword string) bool {
+	return !t.quoted && strings.EqualFold(t.text, keyword)
+}
+
+// Splits a selector expression into whitespace-separated tokens
+// (Double quotes group text containing whitespace into a single token, e.g. `desc:"Windows Boot Manager"`)
+func tokenize(expression string) ([]token, error) {
+	tokens := []token{}
+	current := token{}
+	inToken, inQuotes := false, false
+	for _, character := range expression {
+		switch {
+		case character == '"':
+			inQuotes = !inQuotes
+			current.quoted = true
+			inToken = true
+		case unicode.IsSpace(character) && !inQuotes:
+			if inToken {
+				tokens = append(tokens, current)
+				current, inToken = token{}, false
+			}
+		default:
+			current.text += string(character)
+			inToken = true
+		}
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	} else if inToken {
+		tokens = append(tokens, current)
+	}
+	return tokens, nil
+}
+
+// Determines whether an expression is a plain regular expression pattern rather than selector syntax
+// (Expressions are only treated as selector syntax if they contain at least one term with a known field prefix, so that
+// existing patterns continue to match boot entry descriptions as before)
+func IsPattern(expression string) bool {
+	for _, word := range strings.Fields(strings.ReplaceAll(expression, "\"", "")) {
+		if _, _, isField := splitField(word); isField {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses a selector expression
+//
+// Expressions consist of terms of the form `field:value`, combined using `and`, `or` and `not` and grouped using
+// parentheses, which must be separated from the surrounding terms by whitespace. Adjacent terms are implicitly combined
+// using `and`. Terms without a field prefix are case-insensitive regular expressions that match boot entry
+// descriptions, and expressions that do not contain any field prefixes are treated as a single regular expression.
+func Parse(expression string) (Selector, error) {
+
+	// Treat plain patterns as a single regular expression that matches boot entry descriptions
+	if IsPattern(expression) {
+		selector, err := parseTerm("desc", "~"+expression)
+		if err != nil {
+			return nil, fmt.Errorf("%w \"%s\": %v", ErrInvalidSelector, expression, err)
+		}
+		return selector, nil
+	}
+
+	// Parse the tokens, verifying that the entire expression was consumed
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, fmt.Errorf("%w \"%s\": %v", ErrInvalidSelector, expression, err)
+	}
+	p := &parser{tokens: tokens}
+	selector, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("%w \"%s\": %v", ErrInvalidSelector, expression, err)
+	} else if p.position < len(p.tokens) {
+		return nil, fmt.Errorf("%w \"%s\": unexpected \"%s\"", ErrInvalidSelector, expression, p.tokens[p.position].text)
+	}
+	return selector, nil
+}
+
+// A recursive descent parser for selector expressions, where `not` binds more tightly than `and`, which binds more
+// tightly than `or`
+type parser struct {
+	tokens   []token
+	position int
+}
+
+// Returns the next token without consuming it, or false if all of the tokens have been consumed
+func (p *parser) peek() (token, bool) {
+	if p.position >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.position], true
+}
+
+// Parses a list of operands separated by `or`
+func (p *parser) parseOr() (Selector, error) {
+	operands := []Selector{}
+	for {
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+
+		if next, exists := p.peek(); !exists || !next.is("or") {
+			break
+		}
+		p.position++
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &orSelector{operands: operands}, nil
+}
+
+// Parses a list of operands separated by `and` or by whitespace alone
+func (p *parser) parseAnd() (Selector, error) {
+	operands := []Selector{}
+	for {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+
+		next, exists := p.peek()
+		if !exists || next.is("or") || next.is(")") {
+			break
+		} else if next.is("and") {
+			p.position++
+		}
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &andSelector{operands: operands}, nil
+}
+
+// Parses an operand that is optionally negated using `not`
+func (p *parser) parseNot() (Selector, error) {
+	if next, exists := p.peek(); exists && next.is("not") {
+		p.position++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notSelector{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// Parses a parenthesised expression or an individual term
+func (p *parser) parsePrimary() (Selector, error) {
+	next, exists := p.peek()
+	if !exists {
+		return nil, errors.New("expected a term at the end of the expression")
+	}
+	p.position++
+
+	switch {
+	case next.is("("):
+		selector, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, exists := p.peek(); !exists || !closing.is(")") {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.position++
+		return selector, nil
+
+	case next.is(")"), next.is("and"), next.is("or"), next.is("not"):
+		return nil, fmt.Errorf("expected a term but found \"%s\"", next.text)
+
+	default:
+		if field, value, isField := splitField(next.text); isField {
+			return parseTerm(field, value)
+		}
+		return parseTerm("desc", "~"+next.text)
+	}
+}
+
+// Splits a term into its field name and value, returning false if it does not start with a known field prefix
+func splitField(text string) (string, string, bool) {
+	field, value, found := strings.Cut(text, ":")
+	field = strings.ToLower(field)
+	if _, known := fields[field]; !found || !known {
+		return "", "", false
+	}
+	return field, value, true
+}
@@ -0,0 +1,235 @@
+package selector
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// The boot entries that the test expressions are evaluated against, which only have text device paths, as reported
+// by backends that do not decode device paths
+var testEntries = []uefi.BootEntry{
+	{ID: "0001", Description: "ubuntu", DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\ubuntu\shimx64.efi)`, Kind: "shim/ubuntu"},
+	{ID: "0002", Description: "Windows Boot Manager", DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\Microsoft\Boot\bootmgfw.efi)`, Kind: uefi.KindWindows},
+	{ID: "0003", Description: "UEFI: USB Drive", DevicePathText: `PciRoot(0x0)/Pci(0x14,0x0)/USB(3,0)/HD(1,GPT,5d3c2b1a-0f9e-4d8c-b7a6-958473625140,0x800,0x12c000)`, Kind: uefi.KindUSB},
+	{ID: "0004", Description: "UEFI: PXE IPv4", DevicePathText: `PciRoot(0x0)/Pci(0x1c,0x0)/MAC(525400123456,1)/IPv4(0.0.0.0)`, Kind: uefi.KindPXE},
+}
+
+// Returns the identifiers of the test boot entries that a selector matches
+func matchingIDs(selector Selector) string {
+	ids := []string{}
+	for _, candidate := range Filter(selector, NewCandidates(testEntries, nil)) {
+		ids = append(ids, candidate.Entry.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestIsPattern(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{expression: "ubuntu", expected: true},
+		{expression: "Windows Boot Manager", expected: true},
+		{expression: "^UEFI: ", expected: true},
+		{expression: "(?i:ubuntu)", expected: true},
+		{expression: "^.*id:0001", expected: true},
+		{expression: `\EFI\path:x`, expected: true},
+		{expression: "unknown:field", expected: true},
+		{expression: "", expected: true},
+		{expression: "not", expected: true},
+		{expression: "id:0001", expected: false},
+		{expression: "ID:0001", expected: false},
+		{expression: "path:~shimx64", expected: false},
+		{expression: "ubuntu or id:3", expected: false},
+		{expression: `desc:"Windows Boot Manager"`, expected: false},
+		{expression: `"kind:windows"`, expected: false},
+	}
+
+	for _, testCase := range testCases {
+		if isPattern := IsPattern(testCase.expression); isPattern != testCase.expected {
+			t.Errorf("%q: got %v, expected %v", testCase.expression, isPattern, testCase.expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		expected   string
+		matches    string
+	}{
+		{
+			name:       "plain pattern",
+			expression: "ubuntu",
+			expected:   "desc:~ubuntu",
+			matches:    "0001",
+		},
+		{
+			name:       "plain pattern containing whitespace and a colon",
+			expression: "UEFI: USB",
+			expected:   `desc:"~UEFI: USB"`,
+			matches:    "0003",
+		},
+		{
+			name:       "plain pattern that is a regular expression",
+			expression: "^(ubuntu|windows)",
+			expected:   "desc:~^(ubuntu|windows)",
+			matches:    "0001,0002",
+		},
+		{
+			name:       "single term",
+			expression: "id:2",
+			expected:   "id:2",
+			matches:    "0002",
+		},
+		{
+			name:       "quoted value",
+			expression: `desc:"Windows Boot Manager"`,
+			expected:   `desc:"Windows Boot Manager"`,
+			matches:    "0002",
+		},
+		{
+			name:       "quoted regular expression",
+			expression: `desc:"~uefi: "`,
+			expected:   `desc:"~uefi: "`,
+			matches:    "0003,0004",
+		},
+		{
+			name:       "quoted keyword is a term",
+			expression: `kind:usb or "or"`,
+			expected:   "kind:usb or desc:~or",
+			matches:    "0003",
+		},
+		{
+			name:       "bare term alongside a field",
+			expression: "uefi type:usb",
+			expected:   "desc:~uefi and type:usb",
+			matches:    "0003",
+		},
+		{
+			name:       "implicit and",
+			expression: "type:disk path:~shimx64",
+			expected:   "type:disk and path:~shimx64",
+			matches:    "0001",
+		},
+		{
+			name:       "and binds more tightly than or",
+			expression: "id:1 or type:disk and kind:windows",
+			expected:   "id:1 or ( type:disk and kind:windows )",
+			matches:    "0001,0002",
+		},
+		{
+			name:       "implicit and binds more tightly than or",
+			expression: "type:disk kind:windows or id:4",
+			expected:   "( type:disk and kind:windows ) or id:4",
+			matches:    "0002,0004",
+		},
+		{
+			name:       "not binds more tightly than and",
+			expression: "not type:usb and type:disk",
+			expected:   "not type:usb and type:disk",
+			matches:    "0001,0002",
+		},
+		{
+			name:       "not binds more tightly than or",
+			expression: "not type:disk or id:1",
+			expected:   "not type:disk or id:1",
+			matches:    "0001,0004",
+		},
+		{
+			name:       "parentheses override precedence",
+			expression: "( id:1 or id:3 ) and type:usb",
+			expected:   "( id:1 or id:3 ) and type:usb",
+			matches:    "0003",
+		},
+		{
+			name:       "negated group",
+			expression: "not ( kind:shim or kind:windows )",
+			expected:   "not ( kind:shim or kind:windows )",
+			matches:    "0003,0004",
+		},
+		{
+			name:       "double negation",
+			expression: "not not id:1",
+			expected:   "not not id:1",
+			matches:    "0001",
+		},
+		{
+			name:       "keywords and fields are case-insensitive",
+			expression: "ID:1 OR Kind:windows",
+			expected:   "id:1 or kind:windows",
+			matches:    "0001,0002",
+		},
+		{
+			name:       "nested groups",
+			expression: "( ( id:1 ) )",
+			expected:   "id:1",
+			matches:    "0001",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			selector, err := Parse(testCase.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := selector.String(); text != testCase.expected {
+				t.Errorf("got %s, expected %s", text, testCase.expected)
+			}
+			if ids := matchingIDs(selector); ids != testCase.matches {
+				t.Errorf("got matches %q, expected %q", ids, testCase.matches)
+			}
+
+			// The text representation parses to an equivalent selector
+			reparsed, err := Parse(selector.String())
+			if err != nil {
+				t.Fatalf("failed to parse the text representation: %v", err)
+			} else if text := reparsed.String(); text != testCase.expected {
+				t.Errorf("reparsed: got %s, expected %s", text, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		expression string
+		err        string
+	}{
+		{expression: `desc:"Windows Boot Manager`, err: "unterminated quote"},
+		{expression: "id:1 and", err: "expected a term at the end of the expression"},
+		{expression: "id:1 and not", err: "expected a term at the end of the expression"},
+		{expression: "id:1 or or id:2", err: `expected a term but found "or"`},
+		{expression: "and id:1", err: `expected a term but found "and"`},
+		{expression: "( id:1", err: "missing closing parenthesis"},
+		{expression: "id:1 )", err: `unexpected ")"`},
+		{expression: "( ) id:1", err: `expected a term but found ")"`},
+		{expression: "id:", err: "the id: term requires a value"},
+		{expression: `desc:""`, err: "the desc: term requires a value"},
+		{expression: "index:0", err: `invalid index "0"`},
+		{expression: "index:first", err: `invalid index "first"`},
+		{expression: "type:floppy", err: `unknown device type "floppy"`},
+		{expression: "partuuid:not-a-guid", err: "invalid partition GUID"},
+		{expression: "fingerprint:xyz", err: `invalid fingerprint "xyz"`},
+		{expression: "desc:~(", err: "failed to compile regular expression"},
+		{expression: "id:1 or (", err: "expected a term at the end of the expression"},
+		{expression: "ubuntu[", err: "failed to compile regular expression"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.expression, func(t *testing.T) {
+			_, err := Parse(testCase.expression)
+			if err == nil || !strings.Contains(err.Error(), testCase.err) {
+				t.Fatalf("got error %v, expected %q", err, testCase.err)
+			}
+			if !errors.Is(err, ErrInvalidSelector) {
+				t.Errorf("got error %v, expected it to wrap ErrInvalidSelector", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package selector
+
+import (
+	"fmt"
+
+	"github.com/tensorworks/bootnext/internal/disk"
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Represents a boot entry that a selector is evaluated against, along with the context that selector terms depend upon
+type Candidate struct {
+
+	// The boot entry
+	Entry *uefi.BootEntry
+
+	// The one-based position of the boot entry in the list of boot entries
+	Index int
+
+	// The partition that the boot entry's device path references, or nil if it does not reference a partition that
+	// exists on the system's disks
+	Partition *disk.Partition
+}
+
+// Creates the candidates for a list of boot entries, resolving the partitions that their device paths reference
+// (The candidates reference the supplied boot entries rather than copies of them)
+func NewCandidates(entries []uefi.BootEntry, partitions []disk.Partition) []Candidate {
+	candidates := []Candidate{}
+	for index := range entries {
+		candidate := Candidate{Entry: &entries[index], Index: index + 1}
+		if guid, found := partitionGUID(&entries[index]); found {
+			candidate.Partition = disk.FindPartition(partitions, guid)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// Returns the loader path in the boot entry's device path, or an empty string if there is none
+func (c *Candidate) LoaderPath() string {
+	return c.Entry.LoaderPath()
+}
+
+// Determines whether the boot entry's device path references a GPT partition, regardless of whether the partition exists
+func (c *Candidate) ReferencesPartition() bool {
+	_, found := partitionGUID(c.Entry)
+	return found
+}
+
+// Represents a parsed selector expression, which identifies boot entries by their attributes
+type Selector interface {
+
+	// Determines whether the selector matches a candidate boot entry
+	Matches(candidate *Candidate) bool
+
+	// Describes the reasons that the selector matches a candidate boot entry (e.g. which fields matched which values),
+	// returning an empty list if the selector does not match the candidate
+	Explain(candidate *Candidate) []string
+
+	// Returns the text representation of the selector, in a form that can be parsed again
+	String() string
+}
+
+// Returns the candidates that match a selector, preserving their order
+func Filter(selector Selector, candidates []Candidate) []Candidate {
+	matches := []Candidate{}
+	for index := range candidates {
+		if selector.Matches(&candidates[index]) {
+			matches = append(matches, candidates[index])
+		}
+	}
+	return matches
+}
+
+// Matches candidates that satisfy every one of its operands
+type andSelector struct {
+	operands []Selector
+}
+
+func (s *andSelector) Matches(candidate *Candidate) bool {
+	for _, operand := range s.operands {
+		if !operand.Matches(candidate) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *andSelector) Explain(candidate *Candidate) []string {
+	if !s.Matches(candidate) {
+		return []string{}
+	}
+	reasons := []string{}
+	for _, operand := range s.operands {
+		reasons = append(reasons, operand.Explain(candidate)...)
+	}
+	return reasons
+}
+
+func (s *andSelector) String() string {
+	return joinOperands(s.operands, " and ")
+}
+
+// Matches candidates that satisfy at least one of its operands
+type orSelector struct {
+	operands []Selector
+}
+
+func (s *orSelector) Matches(candidate *Candidate) bool {
+	for _, operand := range s.operands {
+		if operand.Matches(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *orSelector) Explain(candidate *Candidate) []string {
+	reasons := []string{}
+	for _, operand := range s.operands {
+		reasons = append(reasons, operand.Explain(candidate)...)
+	}
+	return reasons
+}
+
+func (s *orSelector) String() string {
+	return joinOperands(s.operands, " or ")
+}
+
+// Matches candidates that do not satisfy its operand
+type notSelector struct {
+	operand Selector
+}
+
+func (s *notSelector) Matches(candidate *Candidate) bool {
+	return !s.operand.Matches(candidate)
+}
+
+func (s *notSelector) Explain(candidate *Candidate) []string {
+	if !s.Matches(candidate) {
+		return []string{}
+	}
+	return []string{fmt.Sprintf("%s did not match", parenthesise(s.operand))}
+}
+
+func (s *notSelector) String() string {
+	return "not " + parenthesise(s.operand)
+}
+
+// Joins the text representations of a list of operands, parenthesising any compound operands
+func joinOperands(operands []Selector, separator string) string {
+	text := ""
+	for index, operand := range operands {
+		if index > 0 {
+			text += separator
+		}
+		text += parenthesise(operand)
+	}
+	return text
+}
+
+// Returns the text representation of a selector, wrapped in parentheses if it combines other selectors
+func parenthesise(selector Selector) string {
+	switch selector.(type) {
+	case *andSelector, *orSelector:
+		return "( " + selector.String() + " )"
+	}
+	return selector.String()
+}
@@ -0,0 +1,125 @@
+package selector
+
+import (
+	"sort"
+	"strings"
+)
+
+// The minimum similarity between a term and an attribute value for the boot entry to be suggested
+const minimumSimilarity = 0.5
+
+// The minimum length of a term's value for it to be used when suggesting boot entries
+// (Shorter values resemble too many attribute values for the suggestions to be useful)
+const minimumQueryLength = 3
+
+// Represents a boot entry that resembles a selector that did not match any boot entries
+type Suggestion struct {
+
+	// The boot entry
+	Candidate Candidate
+
+	// The value of the term that the boot entry resembles, without any tilde prefix
+	Query string
+
+	// The name of the attribute that resembles the term (e.g. "description")
+	Label string
+
+	// The value of the attribute that resembles the term
+	Value string
+
+	// The similarity between the term and the attribute value, from 0 to 1
+	Score float64
+}
+
+// Suggests the boot entries that most closely resemble the terms of a selector that did not match any boot entries
+// (Each term is compared against the attribute values of its field using approximate substring matching, so that a
+// typo such as "windwos" suggests "Windows Boot Manager", and negated terms are ignored)
+func Suggest(selector Selector, candidates []Candidate, limit int) []Suggestion {
+	terms := positiveTerms(selector)
+	suggestions := []Suggestion{}
+	for index := range candidates {
+
+		// Identify the term and attribute value that are the most similar
+		best := Suggestion{}
+		for _, term := range terms {
+			query := strings.TrimPrefix(term.value, "~")
+			if len([]rune(query)) < minimumQueryLength {
+				continue
+			}
+			for _, value := range fields[term.field].values(&candidates[index]) {
+				if score := similarity(strings.ToLower(query), strings.ToLower(value)); score > best.Score {
+					best = Suggestion{Candidate: candidates[index], Query: query, Label: fields[term.field].label, Value: value, Score: score}
+				}
+			}
+		}
+
+		if best.Score >= minimumSimilarity {
+			suggestions = append(suggestions, best)
+		}
+	}
+
+	// Order the suggestions from most to least similar, preserving the order of the boot entries for ties
+	sort.SliceStable(suggestions, func(a, b int) bool { return suggestions[a].Score > suggestions[b].Score })
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// Returns the terms of a selector that are not negated
+func positiveTerms(selector Selector) []*term {
+	switch current := selector.(type) {
+	case *term:
+		return []*term{current}
+	case *andSelector:
+		return operandTerms(current.operands)
+	case *orSelector:
+		return operandTerms(current.operands)
+	}
+	return []*term{}
+}
+
+// Returns the terms of a list of operands that are not negated
+func operandTerms(operands []Selector) []*term {
+	terms := []*term{}
+	for _, operand := range operands {
+		terms = append(terms, positiveTerms(operand)...)
+	}
+	return terms
+}
+
+// Returns the similarity between a query and the most similar substring of a value, from 0 (nothing in common) to 1
+// (the value contains the query), based on the number of single-character edits required to transform one into the other
+func similarity(query string, value string) float64 {
+	queryRunes, valueRunes := []rune(query), []rune(value)
+
+	// Compute the edit distances row by row, where the first row is all zeroes because the substring can start anywhere
+	previous := make([]int, len(valueRunes)+1)
+	for row := 1; row <= len(queryRunes); row++ {
+		current := make([]int, len(valueRunes)+1)
+		current[0] = row
+		for column := 1; column <= len(valueRunes); column++ {
+			substitution := previous[column-1]
+			if queryRunes[row-1] != valueRunes[column-1] {
+				substitution++
+			}
+			current[column] = minimum(substitution, previous[column]+1, current[column-1]+1)
+		}
+		previous = current
+	}
+
+	// The substring can end anywhere, so use the smallest distance in the final row
+	distance := minimum(previous...)
+	return 1 - float64(distance)/float64(len(queryRunes))
+}
+
+// Returns the smallest of a list of integers
+func minimum(values ...int) int {
+	smallest := values[0]
+	for _, value := range values[1:] {
+		if value < smallest {
+			smallest = value
+		}
+	}
+	return smallest
+}
@@ -0,0 +1,108 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		limit      int
+		expected   string
+	}{
+		{
+			name:       "typo in a description",
+			expression: "windwos",
+			limit:      3,
+			expected:   "0002 description \"Windows Boot Manager\" resembles \"windwos\"",
+		},
+		{
+			name:       "typo in a kind",
+			expression: "kind:ubunut",
+			limit:      3,
+			expected:   "0001 kind \"shim/ubuntu\" resembles \"ubunut\"",
+		},
+		{
+			name:       "typo in a quoted description",
+			expression: `desc:"Windows Boot Manger"`,
+			limit:      3,
+			expected:   "0002 description \"Windows Boot Manager\" resembles \"Windows Boot Manger\"",
+		},
+		{
+			name:       "most similar term is reported",
+			expression: "desc:~zzzzzz or desc:~ubunt",
+			limit:      3,
+			expected:   "0001 description \"ubuntu\" resembles \"ubunt\"",
+		},
+		{
+			name:       "ties preserve the order of the boot entries",
+			expression: "desc:~uefi and id:1",
+			limit:      3,
+			expected:   "0003 description \"UEFI: USB Drive\" resembles \"uefi\"; 0004 description \"UEFI: PXE IPv4\" resembles \"uefi\"",
+		},
+		{
+			name:       "suggestions are limited",
+			expression: "desc:~uefi and id:1",
+			limit:      1,
+			expected:   "0003 description \"UEFI: USB Drive\" resembles \"uefi\"",
+		},
+		{
+			name:       "short values are ignored",
+			expression: "id:5",
+			limit:      3,
+			expected:   "",
+		},
+		{
+			name:       "negated terms are ignored",
+			expression: "desc:~zzzzzz and not desc:~windwos",
+			limit:      3,
+			expected:   "",
+		},
+		{
+			name:       "dissimilar values are not suggested",
+			expression: "macos",
+			limit:      3,
+			expected:   "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			parsed, err := Parse(testCase.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			suggestions := []string{}
+			for _, suggestion := range Suggest(parsed, NewCandidates(testEntries, nil), testCase.limit) {
+				suggestions = append(suggestions, fmt.Sprintf("%s %s \"%s\" resembles \"%s\"", suggestion.Candidate.Entry.ID, suggestion.Label, suggestion.Value, suggestion.Query))
+			}
+			if joined := strings.Join(suggestions, "; "); joined != testCase.expected {
+				t.Errorf("got %q, expected %q", joined, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	testCases := []struct {
+		query    string
+		value    string
+		expected float64
+	}{
+		{query: "windows", value: "windows boot manager", expected: 1},
+		{query: "boot", value: "windows boot manager", expected: 1},
+		{query: "windwos", value: "windows boot manager", expected: 1 - 2.0/7},
+		{query: "ubunut", value: "ubuntu", expected: 1 - 1.0/6},
+		{query: "abc", value: "xyz", expected: 0},
+		{query: "abc", value: "", expected: 0},
+	}
+
+	for _, testCase := range testCases {
+		if score := similarity(testCase.query, testCase.value); score != testCase.expected {
+			t.Errorf("%q in %q: got %v, expected %v", testCase.query, testCase.value, score, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/uefi"
+)
+
+// Represents a field that can be used in selector terms
+type field struct {
+
+	// The name of the attribute that the field matches, for use in explanations (e.g. "description")
+	label string
+
+	// Compiles the value of a term into a function that determines whether a candidate matches the term
+	parse func(value string) (func(candidate *Candidate) bool, error)
+
+	// Returns the values of the attribute for a candidate, for use in explanations and suggestions
+	values func(candidate *Candidate) []string
+}
+
+// The fields that can be used in selector terms, keyed by their prefix
+var fields = map[string]*field{
+	"id":          {label: "identifier", parse: parseID, values: idValues},
+	"index":       {label: "position", parse: parseIndex, values: indexValues},
+	"desc":        {label: "description", parse: parseDescription, values: descriptionValues},
+	"description": {label: "description", parse: parseDescription, values: descriptionValues},
+	"path":        {label: "device path", parse: parsePath, values: pathValues},
+	"partuuid":    {label: "partition GUID", parse: parsePartUUID, values: partUUIDValues},
+	"disk":        {label: "disk", parse: parseDisk, values: diskValues},
+	"type":        {label: "device types", parse: parseType, values: typeValues},
+	"fingerprint": {label: "fingerprint", parse: parseFingerprint, values: fingerprintValues},
+	"kind":        {label: "kind", parse: parseKind, values: kindValues},
+}
+
+// Matches candidates against the value of a single field
+type term struct {
+	field   string
+	value   string
+	matches func(candidate *Candidate) bool
+}
+
+func (t *term) Matches(candidate *Candidate) bool {
+	return t.matches(candidate)
+}
+
+func (t *term) Explain(candidate *Candidate) []string {
+	if !t.matches(candidate) {
+		return []string{}
+	}
+	return []string{fmt.Sprintf("%s matched the %s \"%s\"", t, fields[t.field].label, strings.Join(fields[t.field].values(candidate), "\", \""))}
+}
+
+func (t *term) String() string {
+	if strings.ContainsAny(t.value, " \t") {
+		return fmt.Sprintf("%s:\"%s\"", t.field, t.value)
+	}
+	return t.field + ":" + t.value
+}
+
+// Parses a term for the specified field
+func parseTerm(field string, value string) (Selector, error) {
+	if value == "" {
+		return nil, fmt.Errorf("the %s: term requires a value", field)
+	}
+	matches, err := fields[field].parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &term{field: field, value: value, matches: matches}, nil
+}
+
+// Compiles a case-insensitive regular expression from a term value that starts with a tilde (e.g. `~shimx64`), returning
+// nil if the value should be matched exactly
+func compileRegex(value string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(value, "~") {
+		return nil, nil
+	}
+	regex, err := regexp.Compile("(?i)" + value[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile regular expression \"%s\": %v", value[1:], err)
+	}
+	return regex, nil
+}
+
+// Parses a term that matches boot entry identifiers (e.g. `id:0003`)
+func parseID(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool { return regex.MatchString(candidate.Entry.ID) }, nil
+	}
+	return func(candidate *Candidate) bool { return sameID(candidate.Entry.ID, value) }, nil
+}
+
+// Determines whether two boot entry identifiers are equal, ignoring case, braces around GUIDs and leading zeroes in
+// hexadecimal numbers (so that `id:3` matches `Boot0003`)
+func sameID(a string, b string) bool {
+	a, b = strings.Trim(a, "{}"), strings.Trim(b, "{}")
+	if first, err := strconv.ParseUint(a, 16, 16); err == nil {
+		if second, err := strconv.ParseUint(b, 16, 16); err == nil {
+			return first == second
+		}
+	}
+	return strings.EqualFold(a, b)
+}
+
+// Parses a term that matches the one-based position of a boot entry in the list of boot entries (e.g. `index:2`)
+func parseIndex(value string) (func(candidate *Candidate) bool, error) {
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 1 {
+		return nil, fmt.Errorf("invalid index \"%s\" (indices are positive integers, starting from 1)", value)
+	}
+	return func(candidate *Candidate) bool { return candidate.Index == index }, nil
+}
+
+// Parses a term that matches boot entry descriptions (e.g. `desc:~windows`)
+func parseDescription(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool { return regex.MatchString(candidate.Entry.Description) }, nil
+	}
+	return func(candidate *Candidate) bool { return strings.EqualFold(candidate.Entry.Description, value) }, nil
+}
+
+// Parses a term that matches the loader path in a boot entry's device path (e.g. `path:\EFI\ubuntu\shimx64.efi`)
+// (Regular expressions are also matched against the text of the full device path, e.g. `path:~Uri\(https`)
+func parsePath(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool {
+			loader := candidate.Entry.LoaderPath()
+			return (loader != "" && regex.MatchString(loader)) || regex.MatchString(candidate.Entry.DevicePathText)
+		}, nil
+	}
+
+	path := uefi.NormaliseLoaderPath(value)
+	return func(candidate *Candidate) bool { return strings.EqualFold(candidate.Entry.LoaderPath(), path) }, nil
+}
+
+// Parses a term that matches the unique GUID of the GPT partition in a boot entry's device path
+func parsePartUUID(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool {
+			guid, found := partitionGUID(candidate.Entry)
+			return found && regex.MatchString(guid.String())
+		}, nil
+	}
+
+	expected, err := uefi.ParseGUID(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partition GUID: %v", err)
+	}
+	return func(candidate *Candidate) bool {
+		guid, found := partitionGUID(candidate.Entry)
+		return found && guid == expected
+	}, nil
+}
+
+// Parses a term that matches the disk containing the partition in a boot entry's device path, either by its device node
+// with or without the leading directory (e.g. `disk:nvme0n1`), or by a regular expression that is matched against its
+// device node, model and serial number (e.g. `disk:~samsung`)
+func parseDisk(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool {
+			if candidate.Partition == nil {
+				return false
+			}
+			disk := candidate.Partition.Disk
+			return regex.MatchString(disk.Device) || regex.MatchString(disk.Model) || regex.MatchString(disk.Serial)
+		}, nil
+	}
+
+	return func(candidate *Candidate) bool {
+		if candidate.Partition == nil {
+			return false
+		}
+		device := candidate.Partition.Disk.Device
+		name := device[strings.LastIndexAny(device, "/\\")+1:]
+		return strings.EqualFold(device, value) || strings.EqualFold(name, value)
+	}, nil
+}
+
+// Parses a term that matches the types of device in a boot entry's device path (e.g. `type:usb`)
+func parseType(value string) (func(candidate *Candidate) bool, error) {
+	value = strings.ToLower(value)
+	known := false
+	for _, name := range DeviceTypes {
+		known = known || name == value
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown device type \"%s\" (expected one of: %s)", value, strings.Join(DeviceTypes, ", "))
+	}
+
+	return func(candidate *Candidate) bool {
+		for _, deviceType := range deviceTypes(candidate.Entry) {
+			if deviceType == value {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Parses a term that matches the fingerprint of a boot entry's device path (e.g. `fingerprint:3f2a9c1e5b7d0a48`)
+func parseFingerprint(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool {
+			fingerprint := Fingerprint(candidate.Entry)
+			return fingerprint != "" && regex.MatchString(fingerprint)
+		}, nil
+	}
+
+	if !IsFingerprint(value) {
+		return nil, fmt.Errorf("invalid fingerprint \"%s\" (fingerprints are %d hexadecimal digits, as printed by `bootnext --list`)", value, FingerprintLength)
+	}
+	return func(candidate *Candidate) bool { return strings.EqualFold(Fingerprint(candidate.Entry), value) }, nil
+}
+
+// Parses a term that matches the kind of a boot entry, either in full or by one of its components (e.g. `kind:shim`,
+// `kind:ubuntu` and `kind:shim/ubuntu` all match a boot entry of the kind `shim/ubuntu`)
+func parseKind(value string) (func(candidate *Candidate) bool, error) {
+	regex, err := compileRegex(value)
+	if err != nil {
+		return nil, err
+	} else if regex != nil {
+		return func(candidate *Candidate) bool { return regex.MatchString(candidate.Entry.Kind) }, nil
+	}
+
+	return func(candidate *Candidate) bool {
+		if strings.EqualFold(candidate.Entry.Kind, value) {
+			return true
+		}
+		for _, component := range strings.Split(candidate.Entry.Kind, "/") {
+			if strings.EqualFold(component, value) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Returns the values of the attributes matched by each field, excluding any that are empty
+func idValues(candidate *Candidate) []string {
+	return []string{candidate.Entry.ID}
+}
+
+func indexValues(candidate *Candidate) []string {
+	return []string{strconv.Itoa(candidate.Index)}
+}
+
+func descriptionValues(candidate *Candidate) []string {
+	return []string{candidate.Entry.Description}
+}
+
+func pathValues(candidate *Candidate) []string {
+	if candidate.Entry.DevicePathText != "" {
+		return []string{candidate.Entry.DevicePathText}
+	} else if loader := candidate.Entry.LoaderPath(); loader != "" {
+		return []string{loader}
+	}
+	return []string{}
+}
+
+func partUUIDValues(candidate *Candidate) []string {
+	if guid, found := partitionGUID(candidate.Entry); found {
+		return []string{guid.String()}
+	}
+	return []string{}
+}
+
+func typeValues(candidate *Candidate) []string {
+	return deviceTypes(candidate.Entry)
+}
+
+func diskValues(candidate *Candidate) []string {
+	values := []string{}
+	if candidate.Partition != nil {
+		for _, value := range []string{candidate.Partition.Disk.Device, candidate.Partition.Disk.Model, candidate.Partition.Disk.Serial} {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func fingerprintValues(candidate *Candidate) []string {
+	if fingerprint := Fingerprint(candidate.Entry); fingerprint != "" {
+		return []string{fingerprint}
+	}
+	return []string{}
+}
+
+func kindValues(candidate *Candidate) []string {
+	if candidate.Entry.Kind != "" {
+		return []string{candidate.Entry.Kind}
+	}
+	return []string{}
+}
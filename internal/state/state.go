@@ -0,0 +1,61 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// The environment variable that can be used to override the directory in which state records are stored
+const STATE_DIR_ENV_VAR = "BOOTNEXT_STATE_DIR"
+
+// Returns the directory in which bootnext stores records of the changes it has made to the system
+func Dir() string {
+	if dir := os.Getenv(STATE_DIR_ENV_VAR); dir != "" {
+		return dir
+	}
+	return defaultDir()
+}
+
+// Reads the state record with the specified name into the supplied value, reporting whether the record exists
+func Load(name string, value interface{}) (bool, error) {
+	contents, err := os.ReadFile(recordPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(contents, value); err != nil {
+		return false, fmt.Errorf("failed to parse state record \"%s\": %v", recordPath(name), err)
+	}
+	return true, nil
+}
+
+// Writes the supplied value to the state record with the specified name, creating the state directory if needed
+func Save(name string, value interface{}) error {
+	contents, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(recordPath(name), append(contents, '\n'), 0644)
+}
+
+// Removes the state record with the specified name, if it exists
+func Remove(name string) error {
+	if err := os.Remove(recordPath(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Returns the path to the file for the state record with the specified name
+func recordPath(name string) string {
+	return filepath.Join(Dir(), name+".json")
+}
@@ -0,0 +1,6 @@
+package state
+
+// Returns the default state directory under Linux
+func defaultDir() string {
+	return "/var/lib/bootnext"
+}
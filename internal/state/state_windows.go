@@ -0,0 +1,15 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Returns the default state directory under Windows
+func defaultDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "bootnext")
+}
@@ -0,0 +1,162 @@
+package terminal
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"unicode"
+)
+
+// Identifies the keys that interactive prompts respond to
+type KeyType int
+
+const (
+	KeyUnknown KeyType = iota
+	KeyRune
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyInterrupt
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+)
+
+// Represents an individual key press
+type Key struct {
+
+	// The type of key that was pressed
+	Type KeyType
+
+	// The character that was typed, for keys of type KeyRune
+	Rune rune
+}
+
+// Provides the input and output for interactive prompts
+// (Prompts only interact with the terminal through this interface, so a fake terminal that replays a scripted sequence
+// of key presses and records the output can be substituted for the console)
+type Terminal interface {
+	io.Writer
+
+	// Reads the next key press, blocking until one is available
+	ReadKey() (Key, error)
+
+	// Returns the width and height of the terminal in characters, or zeroes if they are unknown
+	Size() (int, int)
+}
+
+// Determines whether the standard input and output streams are both attached to a terminal
+func IsInteractive() bool {
+	return isTerminal(os.Stdin.Fd()) && isTerminal(os.Stdout.Fd())
+}
+
+// Provides access to the console attached to the standard input and output streams, with input in raw mode so that
+// individual key presses can be read without waiting for the user to press Enter
+type Console struct {
+	decoder *keyDecoder
+	restore func() error
+}
+
+// Places the console in raw mode, which must be reverted by calling `Close()`
+func OpenConsole() (*Console, error) {
+	restore, err := makeRaw(os.Stdin.Fd(), os.Stdout.Fd())
+	if err != nil {
+		return nil, err
+	}
+	return &Console{decoder: &keyDecoder{reader: bufio.NewReader(os.Stdin)}, restore: restore}, nil
+}
+
+func (c *Console) Write(data []byte) (int, error) {
+	return os.Stdout.Write(data)
+}
+
+func (c *Console) ReadKey() (Key, error) {
+	return c.decoder.ReadKey()
+}
+
+func (c *Console) Size() (int, int) {
+	return terminalSize(os.Stdout.Fd())
+}
+
+// Restores the console to the mode it was in before it was opened
+func (c *Console) Close() error {
+	return c.restore()
+}
+
+// Decodes key presses from the input of a terminal in raw mode, including the ANSI escape sequences that terminals
+// send for navigation keys (e.g. `ESC [ A` for the up arrow)
+type keyDecoder struct {
+	reader *bufio.Reader
+}
+
+func (d *keyDecoder) ReadKey() (Key, error) {
+	character, _, err := d.reader.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch character {
+	case '\r', '\n':
+		return Key{Type: KeyEnter}, nil
+	case 0x7f, 0x08:
+		return Key{Type: KeyBackspace}, nil
+	case 0x03, 0x04:
+		return Key{Type: KeyInterrupt}, nil
+	case 0x10:
+		return Key{Type: KeyUp}, nil
+	case 0x0e:
+		return Key{Type: KeyDown}, nil
+	case 0x1b:
+		return d.readEscapeSequence()
+	}
+
+	if unicode.IsPrint(character) {
+		return Key{Type: KeyRune, Rune: character}, nil
+	}
+	return Key{Type: KeyUnknown}, nil
+}
+
+// Decodes the remainder of an escape sequence, treating an escape character that is not immediately followed by the
+// rest of a sequence as a press of the Escape key
+func (d *keyDecoder) readEscapeSequence() (Key, error) {
+	if d.reader.Buffered() == 0 {
+		return Key{Type: KeyEscape}, nil
+	}
+	introducer, err := d.reader.ReadByte()
+	if err != nil {
+		return Key{}, err
+	} else if introducer != '[' && introducer != 'O' {
+		return Key{Type: KeyUnknown}, nil
+	}
+
+	// Control sequences consist of any number of parameter bytes followed by a final byte in the range 0x40-0x7e
+	parameters := ""
+	for {
+		current, err := d.reader.ReadByte()
+		if err != nil {
+			return Key{}, err
+		} else if current < 0x40 || current > 0x7e {
+			parameters += string(current)
+			continue
+		}
+
+		switch {
+		case current == 'A':
+			return Key{Type: KeyUp}, nil
+		case current == 'B':
+			return Key{Type: KeyDown}, nil
+		case current == 'H', current == '~' && (parameters == "1" || parameters == "7"):
+			return Key{Type: KeyHome}, nil
+		case current == 'F', current == '~' && (parameters == "4" || parameters == "8"):
+			return Key{Type: KeyEnd}, nil
+		case current == '~' && parameters == "5":
+			return Key{Type: KeyPageUp}, nil
+		case current == '~' && parameters == "6":
+			return Key{Type: KeyPageDown}, nil
+		}
+		return Key{Type: KeyUnknown}, nil
+	}
+}
@@ -0,0 +1,45 @@
+package terminal
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Determines whether a file descriptor refers to a terminal
+func isTerminal(fd uintptr) bool {
+	_, err := unix.IoctlGetTermios(int(fd), unix.TCGETS)
+	return err == nil
+}
+
+// Disables line buffering, echoing and signal generation for a terminal, returning a function that restores its
+// original settings
+// (Output processing is left enabled so that newlines continue to return the cursor to the start of the line)
+func makeRaw(input uintptr, output uintptr) (func() error, error) {
+	original, err := unix.IoctlGetTermios(int(input), unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *original
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(int(input), unix.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(int(input), unix.TCSETS, original)
+	}, nil
+}
+
+// Returns the width and height of a terminal in characters, or zeroes if they cannot be determined
+func terminalSize(fd uintptr) (int, int) {
+	size, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0
+	}
+	return int(size.Col), int(size.Row)
+}
@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// Determines whether a handle refers to a console
+func isTerminal(handle uintptr) bool {
+	mode := uint32(0)
+	return windows.GetConsoleMode(windows.Handle(handle), &mode) == nil
+}
+
+// Disables line buffering, echoing and Ctrl+C processing for console input and enables virtual terminal sequences for
+// both input and output, returning a function that restores the original console modes
+// (Virtual terminal input causes navigation keys to be reported using the same ANSI escape sequences as under Linux)
+func makeRaw(input uintptr, output uintptr) (func() error, error) {
+	originalInput, originalOutput := uint32(0), uint32(0)
+	if err := windows.GetConsoleMode(windows.Handle(input), &originalInput); err != nil {
+		return nil, err
+	}
+	if err := windows.GetConsoleMode(windows.Handle(output), &originalOutput); err != nil {
+		return nil, err
+	}
+
+	rawInput := originalInput&^(windows.ENABLE_ECHO_INPUT|windows.ENABLE_PROCESSED_INPUT|windows.ENABLE_LINE_INPUT) | windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(windows.Handle(input), rawInput); err != nil {
+		return nil, err
+	}
+	if err := windows.SetConsoleMode(windows.Handle(output), originalOutput|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		windows.SetConsoleMode(windows.Handle(input), originalInput)
+		return nil, err
+	}
+
+	return func() error {
+		if err := windows.SetConsoleMode(windows.Handle(input), originalInput); err != nil {
+			return err
+		}
+		return windows.SetConsoleMode(windows.Handle(output), originalOutput)
+	}, nil
+}
+
+// Returns the width and height of the visible console window in characters, or zeroes if they cannot be determined
+func terminalSize(handle uintptr) (int, int) {
+	info := windows.ConsoleScreenBufferInfo{}
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(handle), &info); err != nil {
+		return 0, 0
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1
+}
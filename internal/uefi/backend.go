@@ -0,0 +1,112 @@
+package uefi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The environment variable that can be used to select a backend when the `--backend` flag is not specified
+const BACKEND_ENV_VAR = "BOOTNEXT_BACKEND"
+
+// The error returned by backends for operations that they do not support
+var ErrUnsupported = errors.New("operation is not supported by the selected backend")
+
+// Provides access to the UEFI boot entries and boot manager variables of a system
+type Backend interface {
+
+	// Returns the name of the backend, as accepted by `NewBackend()`
+	Name() string
+
+	// Determines whether the backend operates on simulated variables rather than the host system's firmware
+	// (Virtual backends do not require elevated privileges, and rebooting is skipped when they are in use)
+	IsVirtual() bool
+
+	// Determines whether the operating system has been booted in UEFI mode
+	IsUEFIEnabled() (bool, error)
+
+	// Returns the list of system tools that the backend requires in order to interact with UEFI NVRAM variables
+	RequiredTools() []string
+
+	// Returns the store that provides raw access to the UEFI variables underlying the backend's boot entries
+	RawVariables() VariableStore
+
+	// Lists the UEFI boot entries for the system
+	ListBootEntries() ([]BootEntry, error)
+
+	// Lists the load options of the specified kind (boot entries, drivers, system preparation applications or
+	// platform recovery applications)
+	ListLoadOptions(kind *LoadOptionKind) ([]BootEntry, error)
+
+	// Retrieves the identifiers of the load options of the specified kind in order of priority
+	LoadOptionOrder(kind *LoadOptionKind) ([]string, error)
+
+	// Retrieves the values of the BootCurrent, BootNext, BootOrder and Timeout variables
+	GetBootStatus() (*BootStatus, error)
+
+	// Sets the value of the BootNext UEFI NVRAM variable
+	SetBootNext(entry BootEntry) error
+
+	// Deletes the BootNext UEFI NVRAM variable, if it is set
+	ClearBootNext() error
+
+	// Sets the value of the Timeout UEFI NVRAM variable, in seconds
+	SetTimeout(seconds uint16) error
+
+	// Deletes the Timeout UEFI NVRAM variable, if it is set
+	ClearTimeout() error
+
+	// Replaces the value of the BootOrder UEFI NVRAM variable with the specified list of boot entry identifiers
+	SetBootOrder(ids []string) error
+
+	// Writes a load option to an unused boot entry without adding it to BootOrder, returning the new boot entry
+	CreateBootEntry(option *LoadOption) (BootEntry, error)
+
+	// Deletes the specified boot entry
+	DeleteBootEntry(entry BootEntry) error
+
+	// Determines whether the firmware supports booting into its setup user interface when requested via OsIndications
+	IsFirmwareSetupSupported() (bool, error)
+
+	// Sets or clears the request for the firmware to boot into its setup user interface on the next boot
+	SetFirmwareSetup(enabled bool) error
+}
+
+// Creates the backend described by the supplied specification string
+//
+// Specifications take the form `name` or `name:argument`, where the supported names are:
+//
+//   - `auto`: the default backend for the host system
+//   - `efibootmgr`: runs the `efibootmgr` command (Linux only)
+//   - `efivarfs[:root]`: reads and writes variables directly through efivarfs (Linux only)
+//   - `bcdedit`: runs the `bcdedit` command (Windows only)
+//   - `firmware`: reads and writes variables directly through the Windows firmware environment API (Windows only)
+//   - `memory[:file]`: simulates NVRAM in memory, optionally persisting the variables to the specified JSON file
+func NewBackend(spec string) (Backend, error) {
+
+	// Split the specification into its name and optional argument
+	name, argument := spec, ""
+	if index := strings.Index(spec, ":"); index != -1 {
+		name, argument = spec[:index], spec[index+1:]
+	}
+
+	// Handle the platform-independent backends
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return defaultBackend(), nil
+	case "memory":
+		return NewMemoryBackend(argument)
+	}
+
+	// Attempt to create a platform-specific backend
+	if backend, err := newPlatformBackend(strings.ToLower(name), argument); backend != nil || err != nil {
+		return backend, err
+	}
+
+	return nil, fmt.Errorf("unknown or unsupported backend \"%s\" (supported backends: %s)", name, strings.Join(BackendNames(), ", "))
+}
+
+// Returns the names of the backends that are supported on the host system
+func BackendNames() []string {
+	return append(append([]string{"auto"}, platformBackendNames...), "memory")
+}
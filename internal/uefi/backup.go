@@ -0,0 +1,213 @@
+package uefi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// The version number of the backup file format
+const BACKUP_FORMAT_VERSION = 1
+
+// Represents a snapshot of the complete UEFI boot configuration, as stored in backup files
+type Backup struct {
+
+	// The version number of the backup file format
+	Version int `json:"version"`
+
+	// The time at which the backup was created
+	Created time.Time `json:"created"`
+
+	// The identifiers of the boot entries in the BootOrder variable, in order of priority
+	BootOrder []string `json:"bootOrder"`
+
+	// The identifier of the boot entry in the BootNext variable, or an empty string if BootNext was not set
+	BootNext string `json:"bootNext,omitempty"`
+
+	// The boot manager timeout in seconds, or nil if the Timeout variable was not set
+	Timeout *uint16 `json:"timeout,omitempty"`
+
+	// The `Boot####` load option variables
+	Entries []BackupEntry `json:"entries"`
+}
+
+// Represents an individual `Boot####` load option variable in a backup
+type BackupEntry struct {
+
+	// The four-digit hexadecimal identifier of the boot entry
+	ID string `json:"id"`
+
+	// The attributes of the UEFI variable, which are included for reference and are ignored when restoring
+	// (Boot entries are always restored with the standard attributes for boot manager variables)
+	VariableAttributes uint32 `json:"variableAttributes"`
+
+	// The raw bytes of the EFI_LOAD_OPTION structure, which are used when restoring the boot entry
+	Raw []byte `json:"raw"`
+
+	// The decoded fields of the load option, which are included for readability and are ignored when restoring
+	Attributes     uint32 `json:"attributes"`
+	Description    string `json:"description"`
+	Active         bool   `json:"active"`
+	DevicePathText string `json:"devicePath,omitempty"`
+	OptionalData   []byte `json:"optionalData,omitempty"`
+}
+
+// Represents an individual change that restoring a backup will make to the UEFI variables
+type RestoreAction struct {
+
+	// A human-readable description of the change
+	Description string
+
+	// The name of the UEFI variable that will be written
+	Name string
+
+	// The attributes and data that will be written to the variable
+	Attributes uint32
+	Data       []byte
+}
+
+// Captures the complete UEFI boot configuration from the supplied variable store
+func CreateBackup(store VariableStore) (*Backup, error) {
+	backend := &NativeBackend{Store: store}
+	backup := &Backup{Version: BACKUP_FORMAT_VERSION, Created: time.Now().UTC(), Entries: []BackupEntry{}}
+
+	// Capture the BootOrder, BootNext and Timeout variables
+	status, err := backend.GetBootStatus()
+	if err != nil {
+		return nil, err
+	}
+	backup.BootOrder = status.BootOrder
+	backup.BootNext = status.BootNext
+	backup.Timeout = status.Timeout
+
+	// Capture the raw bytes and decoded fields of each load option
+	entries, err := backend.ListBootEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := "Boot" + entry.ID
+		attributes, data, err := store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read UEFI variable %s: %v", name, err)
+		}
+		option, err := ParseLoadOption(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse UEFI variable %s: %v", name, err)
+		}
+
+		backup.Entries = append(backup.Entries, BackupEntry{
+			ID:                 entry.ID,
+			VariableAttributes: attributes,
+			Raw:                data,
+			Attributes:         option.Attributes,
+			Description:        option.Description,
+			Active:             option.Active(),
+			DevicePathText:     entry.DevicePathText,
+			OptionalData:       option.OptionalData,
+		})
+	}
+
+	return backup, nil
+}
+
+// Parses a backup file and validates its contents
+func ParseBackup(contents []byte) (*Backup, error) {
+	backup := &Backup{}
+	if err := json.Unmarshal(contents, backup); err != nil {
+		return nil, err
+	} else if backup.Version != BACKUP_FORMAT_VERSION {
+		return nil, fmt.Errorf("unsupported backup format version %d", backup.Version)
+	}
+
+	// Verify that the identifiers are valid and the raw load options can be parsed
+	for _, id := range backup.BootOrder {
+		if _, err := parseOptionNumber(id); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range backup.Entries {
+		if _, err := parseOptionNumber(entry.ID); err != nil {
+			return nil, err
+		} else if _, err := ParseLoadOption(entry.Raw); err != nil {
+			return nil, fmt.Errorf("failed to parse load option for boot entry %s: %v", entry.ID, err)
+		}
+	}
+
+	return backup, nil
+}
+
+// Determines the changes required to restore a backup to the supplied variable store
+// (Boot entries that are missing or differ from the backup are rewritten, as are BootOrder and Timeout if they differ.
+// Boot entries that are not present in the backup are left untouched, and BootNext is never restored, so restoring
+// the same backup a second time makes no changes. Every variable is written with the standard attributes for boot
+// manager variables, since the attributes recorded in a backup file can be edited and are not trusted)
+func PlanRestore(store VariableStore, backup *Backup) ([]RestoreAction, error) {
+	actions := []RestoreAction{}
+
+	// Determine which load options are missing or have changed
+	for _, entry := range backup.Entries {
+		name := "Boot" + entry.ID
+		option, _ := ParseLoadOption(entry.Raw)
+		_, current, err := store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+		if errors.Is(err, fs.ErrNotExist) {
+			actions = append(actions, RestoreAction{
+				Description: fmt.Sprintf("Recreate missing boot entry %s (\"%s\")", entry.ID, option.Description),
+				Name:        name,
+				Attributes:  bootVariableAttributes,
+				Data:        entry.Raw,
+			})
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read UEFI variable %s: %v", name, err)
+		} else if !bytes.Equal(current, entry.Raw) {
+			actions = append(actions, RestoreAction{
+				Description: fmt.Sprintf("Rewrite changed boot entry %s (\"%s\")", entry.ID, option.Description),
+				Name:        name,
+				Attributes:  bootVariableAttributes,
+				Data:        entry.Raw,
+			})
+		}
+	}
+
+	// Determine whether the BootOrder and Timeout variables have changed
+	status, err := (&NativeBackend{Store: store}).GetBootStatus()
+	if err != nil {
+		return nil, err
+	}
+	if !EqualOrders(status.BootOrder, backup.BootOrder) {
+		numbers := []uint16{}
+		for _, id := range backup.BootOrder {
+			number, _ := parseOptionNumber(id)
+			numbers = append(numbers, number)
+		}
+		actions = append(actions, RestoreAction{
+			Description: fmt.Sprintf("Reset BootOrder to %v", backup.BootOrder),
+			Name:        "BootOrder",
+			Attributes:  bootVariableAttributes,
+			Data:        encodeUint16Array(numbers),
+		})
+	}
+	if backup.Timeout != nil && (status.Timeout == nil || *status.Timeout != *backup.Timeout) {
+		actions = append(actions, RestoreAction{
+			Description: fmt.Sprintf("Reset Timeout to %d seconds", *backup.Timeout),
+			Name:        "Timeout",
+			Attributes:  bootVariableAttributes,
+			Data:        encodeUint16Array([]uint16{*backup.Timeout}),
+		})
+	}
+
+	return actions, nil
+}
+
+// Applies the changes required to restore a backup to the supplied variable store
+func ApplyRestore(store VariableStore, actions []RestoreAction) error {
+	for _, action := range actions {
+		if err := store.WriteVariable(action.Name, EFI_GLOBAL_VARIABLE, action.Attributes, action.Data); err != nil {
+			return fmt.Errorf("failed to write UEFI variable %s: %v", action.Name, err)
+		}
+	}
+	return nil
+}
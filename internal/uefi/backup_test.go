@@ -0,0 +1,56 @@
+package uefi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestPlanRestoreIgnoresRecordedAttributes(t *testing.T) {
+	ubuntu, windows := loadOptionTestCases[0], loadOptionTestCases[1]
+
+	// Create a backup of a store containing two boot entries and tamper with the recorded variable attributes, as a
+	// user could by editing the backup file
+	backup, err := CreateBackup(newTestStore(t, ubuntu, windows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := range backup.Entries {
+		backup.Entries[index].VariableAttributes = EFI_VARIABLE_NON_VOLATILE | 0x20
+	}
+	contents, err := json.Marshal(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseBackup(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore the backup to a store in which one boot entry is missing and the other has changed
+	store := newTestStore(t, renamed(loadOptionTestCases[2], ubuntu.name))
+	actions, err := PlanRestore(store, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("got %d actions, expected 2", len(actions))
+	}
+	if err := ApplyRestore(store, actions); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both boot entries are written with the standard attributes rather than the recorded ones
+	for _, testCase := range []loadOptionTestCase{ubuntu, windows} {
+		attributes, data, err := store.ReadVariable(testCase.name, EFI_GLOBAL_VARIABLE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attributes != bootVariableAttributes {
+			t.Errorf("%s: got attributes 0x%x, expected 0x%x", testCase.name, attributes, bootVariableAttributes)
+		}
+		if !bytes.Equal(data, mustDecodeHex(t, testCase.data)) {
+			t.Errorf("%s: restored load option does not match the backup", testCase.name)
+		}
+	}
+}
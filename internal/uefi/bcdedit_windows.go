@@ -0,0 +1,255 @@
+package uefi
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/process"
+)
+
+// Interacts with UEFI boot entries by running the `bcdedit` command
+type BcdeditBackend struct{}
+
+// Returns the name of the backend
+func (b *BcdeditBackend) Name() string {
+	return "bcdedit"
+}
+
+// The `bcdedit` command always operates on the host system's firmware
+func (b *BcdeditBackend) IsVirtual() bool {
+	return false
+}
+
+// Determines whether the operating system has been booted in UEFI mode
+func (b *BcdeditBackend) IsUEFIEnabled() (bool, error) {
+	return isUEFIEnabled()
+}
+
+// Returns the list of system tools that we require in order to interact with UEFI NVRAM variables
+func (b *BcdeditBackend) RequiredTools() []string {
+	return []string{"bcdedit"}
+}
+
+// Returns the store that provides raw access to the UEFI variables underlying the backend's boot entries
+// (`bcdedit` does not expose raw variable data, so we access the variables directly through the firmware environment API)
+func (b *BcdeditBackend) RawVariables() VariableStore {
+	return &FirmwareStore{}
+}
+
+// Lists the UEFI boot entries for the host machine
+func (b *BcdeditBackend) ListBootEntries() ([]BootEntry, error) {
+
+	// Run `bcdedit` to print the list of boot entries
+	output, err := process.CaptureOutput([]string{"bcdedit", "/enum", "firmware"})
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile our regular expressions for parsing the output
+	separatorRegex, err := regexp.Compile(`^-+$`)
+	if err != nil {
+		return nil, err
+	}
+	identifierRegex, err := regexp.Compile(`identifier +(.+)`)
+	if err != nil {
+		return nil, err
+	}
+	descriptionRegex, err := regexp.Compile(`description +(.+)`)
+	if err != nil {
+		return nil, err
+	}
+
+	// Examine each line of the output and parse the boot entries
+	entries := []BootEntry{}
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for _, line := range lines {
+
+		// Determine whether the line is a separator that marks the start of a new boot entry
+		if separatorRegex.FindStringIndex(line) != nil {
+			entries = append(entries, BootEntry{})
+
+		} else if len(entries) > 0 {
+
+			// Determine whether the line provides the GUID or the description for the boot entry
+			if match := identifierRegex.FindStringSubmatch(line); match != nil {
+				entries[len(entries)-1].ID = match[1]
+
+			} else if match := descriptionRegex.FindStringSubmatch(line); match != nil {
+				entries[len(entries)-1].Description = match[1]
+
+			}
+		}
+	}
+
+	// Filter out any malformed boot entries, classifying the remaining entries
+	// (`bcdedit` does not report device paths, so only the Windows Boot Manager can be identified)
+	filtered := []BootEntry{}
+	for _, entry := range entries {
+		if entry.ID != "" && entry.Description != "" {
+			entry.Kind = ClassifyBootEntry(&entry)
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered, nil
+}
+
+// Lists the load options of the specified kind
+// (`bcdedit` only lists boot entries, so other kinds of load options are read directly through the firmware environment API)
+func (b *BcdeditBackend) ListLoadOptions(kind *LoadOptionKind) ([]BootEntry, error) {
+	if kind == BootOptions {
+		return b.ListBootEntries()
+	}
+	return NewFirmwareBackend().ListLoadOptions(kind)
+}
+
+// Retrieves the identifiers of the load options of the specified kind in order of priority
+func (b *BcdeditBackend) LoadOptionOrder(kind *LoadOptionKind) ([]string, error) {
+	if kind == BootOptions {
+		status, err := b.GetBootStatus()
+		if err != nil {
+			return nil, err
+		}
+		return status.BootOrder, nil
+	}
+	return NewFirmwareBackend().LoadOptionOrder(kind)
+}
+
+// Retrieves the values of the BootCurrent, BootNext, BootOrder and Timeout variables
+// (Note that `bcdedit` does not report the BootCurrent variable, so it is always left empty)
+func (b *BcdeditBackend) GetBootStatus() (*BootStatus, error) {
+
+	// Run `bcdedit` to print the settings of the firmware boot manager
+	elements, err := b.enumFirmwareBootManager()
+	if err != nil {
+		return nil, err
+	}
+
+	// The display order corresponds to BootOrder and the boot sequence corresponds to BootNext
+	status := &BootStatus{BootOrder: []string{}}
+	if order, exists := elements["displayorder"]; exists {
+		status.BootOrder = order
+	}
+	if sequence, exists := elements["bootsequence"]; exists && len(sequence) > 0 {
+		status.BootNext = sequence[0]
+	}
+	if timeout, exists := elements["timeout"]; exists && len(timeout) > 0 {
+		seconds, err := strconv.ParseUint(timeout[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bcdedit timeout value \"%s\": %v", timeout[0], err)
+		}
+		value := uint16(seconds)
+		status.Timeout = &value
+	}
+
+	return status, nil
+}
+
+// Sets the value of the BootNext UEFI NVRAM variable
+func (b *BcdeditBackend) SetBootNext(entry BootEntry) error {
+	_, err := process.CaptureOutput([]string{"bcdedit", "/set", "{fwbootmgr}", "bootsequence", entry.ID})
+	return err
+}
+
+// Deletes the BootNext UEFI NVRAM variable, if it is set
+func (b *BcdeditBackend) ClearBootNext() error {
+
+	// `bcdedit /deletevalue` fails if the element is not set, so check for it first
+	elements, err := b.enumFirmwareBootManager()
+	if err != nil {
+		return err
+	} else if _, exists := elements["bootsequence"]; !exists {
+		return nil
+	}
+
+	_, err = process.CaptureOutput([]string{"bcdedit", "/deletevalue", "{fwbootmgr}", "bootsequence"})
+	return err
+}
+
+// Sets the value of the Timeout UEFI NVRAM variable, in seconds
+func (b *BcdeditBackend) SetTimeout(seconds uint16) error {
+	_, err := process.CaptureOutput([]string{"bcdedit", "/set", "{fwbootmgr}", "timeout", strconv.Itoa(int(seconds))})
+	return err
+}
+
+// Deletes the Timeout UEFI NVRAM variable, if it is set
+func (b *BcdeditBackend) ClearTimeout() error {
+
+	// `bcdedit /deletevalue` fails if the element is not set, so check for it first
+	elements, err := b.enumFirmwareBootManager()
+	if err != nil {
+		return err
+	} else if _, exists := elements["timeout"]; !exists {
+		return nil
+	}
+
+	_, err = process.CaptureOutput([]string{"bcdedit", "/deletevalue", "{fwbootmgr}", "timeout"})
+	return err
+}
+
+// Replaces the value of the BootOrder UEFI NVRAM variable with the specified list of boot entry identifiers
+// (The display order of the firmware boot manager corresponds to the BootOrder variable)
+func (b *BcdeditBackend) SetBootOrder(ids []string) error {
+	if len(ids) == 0 {
+		_, err := process.CaptureOutput([]string{"bcdedit", "/deletevalue", "{fwbootmgr}", "displayorder"})
+		return err
+	}
+
+	_, err := process.CaptureOutput(append([]string{"bcdedit", "/set", "{fwbootmgr}", "displayorder"}, ids...))
+	return err
+}
+
+// Creating boot entries from device paths is not supported by `bcdedit`
+// (Use the `firmware` backend to create boot entries under Windows)
+func (b *BcdeditBackend) CreateBootEntry(option *LoadOption) (BootEntry, error) {
+	return BootEntry{}, ErrUnsupported
+}
+
+// Deletes the specified boot entry
+func (b *BcdeditBackend) DeleteBootEntry(entry BootEntry) error {
+	_, err := process.CaptureOutput([]string{"bcdedit", "/delete", entry.ID})
+	return err
+}
+
+// Determines whether the firmware supports booting into its setup user interface when requested via OsIndications
+// (`bcdedit` does not expose OsIndications, so we access the variables directly through the firmware environment API)
+func (b *BcdeditBackend) IsFirmwareSetupSupported() (bool, error) {
+	return NewFirmwareBackend().IsFirmwareSetupSupported()
+}
+
+// Sets or clears the request for the firmware to boot into its setup user interface on the next boot
+// (`bcdedit` does not expose OsIndications, so we access the variables directly through the firmware environment API)
+func (b *BcdeditBackend) SetFirmwareSetup(enabled bool) error {
+	return NewFirmwareBackend().SetFirmwareSetup(enabled)
+}
+
+// Runs `bcdedit` to print the settings of the firmware boot manager, and parses the elements into a map of lists
+// (Elements with multiple values, such as the display order, list each additional value on an indented line)
+func (b *BcdeditBackend) enumFirmwareBootManager() (map[string][]string, error) {
+	output, err := process.CaptureOutput([]string{"bcdedit", "/enum", "{fwbootmgr}"})
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile our regular expressions for parsing the output
+	elementRegex := regexp.MustCompile(`^([a-z]+) +(.+?)\s*$`)
+	continuationRegex := regexp.MustCompile(`^ +(\S.*?)\s*$`)
+
+	// Parse each element and its values
+	elements := map[string][]string{}
+	current := ""
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		if match := elementRegex.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			elements[current] = []string{match[2]}
+		} else if match := continuationRegex.FindStringSubmatch(line); match != nil && current != "" {
+			elements[current] = append(elements[current], match[1])
+		} else {
+			current = ""
+		}
+	}
+
+	return elements, nil
+}
@@ -0,0 +1,94 @@
+package uefi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// The identifier that `bcdedit` reports for the Windows Boot Manager, and the BCD object GUID that the Windows Boot
+// Manager's load option records in its optional data
+const (
+	BCDEDIT_BOOTMGR_ID  = "{bootmgr}"
+	_BOOTMGR_BCD_OBJECT = "{9dea862c-5cdd-4e70-acc1-f32b344d4795}"
+)
+
+// Matches the BCD object GUID that Windows records in the optional data of the load options it creates
+var bcdObjectPattern = regexp.MustCompile(`BCDOBJECT=(\{[0-9A-Fa-f-]{36}\})`)
+
+// Matches the four-digit hexadecimal identifiers of `Boot####` load options
+var loadOptionIDPattern = regexp.MustCompile(`^[0-9A-Fa-f]{4}$`)
+
+// Finds the `Boot####` load option in a variable store that corresponds to a boot entry reported by a backend
+//
+// Boot entries with four-digit hexadecimal identifiers are read directly. The identifiers that `bcdedit` reports are
+// BCD object GUIDs rather than load option numbers, so these boot entries are matched against the load options
+// instead: the Windows Boot Manager is identified by the BCD object GUID recorded in its optional data (or by its
+// loader path if there is none), and any other boot entry must have a unique description.
+func FindBootOption(store VariableStore, entry *BootEntry) (*BootEntry, error) {
+
+	// Read load options with hexadecimal identifiers directly
+	if loadOptionIDPattern.MatchString(entry.ID) {
+		_, data, err := store.ReadVariable(BootOptions.VariableName(strings.ToUpper(entry.ID)), EFI_GLOBAL_VARIABLE)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read UEFI variable %s: %v", BootOptions.VariableName(entry.ID), err)
+		}
+		option, err := ParseLoadOption(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse UEFI variable %s: %v", BootOptions.VariableName(entry.ID), err)
+		}
+		resolved := newBootEntry(strings.ToUpper(entry.ID), option)
+		return &resolved, nil
+	}
+
+	// List the load options so that they can be matched against the boot entry
+	options, err := (&NativeBackend{Store: store}).ListBootEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer load options whose BCD object GUID matches the identifier
+	object := entry.ID
+	if strings.EqualFold(object, BCDEDIT_BOOTMGR_ID) {
+		object = _BOOTMGR_BCD_OBJECT
+	}
+	matches := []*BootEntry{}
+	for index := range options {
+		if strings.EqualFold(BCDObject(options[index].OptionalData), object) {
+			matches = append(matches, &options[index])
+		}
+	}
+
+	// Fall back to the loader path for the Windows Boot Manager, or the description for any other boot entry
+	if len(matches) == 0 {
+		for index := range options {
+			if strings.EqualFold(entry.ID, BCDEDIT_BOOTMGR_ID) && options[index].Kind == KindWindows {
+				matches = append(matches, &options[index])
+			} else if !strings.EqualFold(entry.ID, BCDEDIT_BOOTMGR_ID) && options[index].Description == entry.Description {
+				matches = append(matches, &options[index])
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no Boot#### load option corresponds to boot entry \"%s\" (%s)", entry.Description, entry.ID)
+	} else if len(matches) > 1 {
+		return nil, fmt.Errorf("%d Boot#### load options correspond to boot entry \"%s\" (%s)", len(matches), entry.Description, entry.ID)
+	}
+	return matches[0], nil
+}
+
+// Returns the BCD object GUID recorded in the optional data of a load option created by Windows, or an empty string if
+// there is none
+// (The optional data consists of a `WINDOWS` header followed by a UTF-16 `BCDOBJECT={GUID}` string)
+func BCDObject(optionalData []byte) string {
+	units := make([]uint16, len(optionalData)/2)
+	for index := range units {
+		units[index] = uint16(optionalData[index*2]) | uint16(optionalData[index*2+1])<<8
+	}
+	if match := bcdObjectPattern.FindStringSubmatch(string(utf16.Decode(units))); match != nil {
+		return match[1]
+	}
+	return ""
+}
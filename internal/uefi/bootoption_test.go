@@ -0,0 +1,133 @@
+package uefi
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Creates a variable store containing the load options from the specified test cases, using their names as the
+// variable names
+func newTestStore(t *testing.T, testCases ...loadOptionTestCase) *MemoryStore {
+	t.Helper()
+	store := NewMemoryStore()
+	for _, testCase := range testCases {
+		if err := store.WriteVariable(testCase.name, EFI_GLOBAL_VARIABLE, 7, mustDecodeHex(t, testCase.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// Returns a copy of a load option test case with a different variable name
+func renamed(testCase loadOptionTestCase, name string) loadOptionTestCase {
+	testCase.name = name
+	return testCase
+}
+
+func TestFindBootOption(t *testing.T) {
+	ubuntu, windows, fedora := loadOptionTestCases[0], loadOptionTestCases[1], loadOptionTestCases[2]
+
+	// Strip the optional data from the Windows Boot Manager so that it can only be identified by its loader path
+	option, err := ParseLoadOption(mustDecodeHex(t, windows.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	option.OptionalData = nil
+	windowsWithoutData := renamed(windows, "Boot0005")
+	windowsWithoutData.data = hex.EncodeToString(option.Bytes())
+
+	testCases := []struct {
+		name     string
+		store    []loadOptionTestCase
+		entry    BootEntry
+		expected string
+	}{
+		{
+			name:     "hexadecimal identifier",
+			store:    []loadOptionTestCase{ubuntu, windows},
+			entry:    BootEntry{ID: "0001", Description: "Windows Boot Manager"},
+			expected: "0001",
+		},
+		{
+			name:     "Windows Boot Manager by BCD object",
+			store:    []loadOptionTestCase{ubuntu, windows, renamed(windowsWithoutData, "Boot0002")},
+			entry:    BootEntry{ID: "{bootmgr}", Description: "Windows Boot Manager"},
+			expected: "0001",
+		},
+		{
+			name:     "Windows Boot Manager by loader path",
+			store:    []loadOptionTestCase{ubuntu, windowsWithoutData},
+			entry:    BootEntry{ID: "{bootmgr}", Description: "Windows Boot Manager"},
+			expected: "0005",
+		},
+		{
+			name:     "other boot entry by description",
+			store:    []loadOptionTestCase{ubuntu, windows, fedora},
+			entry:    BootEntry{ID: "{c2d6b8a4-1e3f-11ee-9c4a-806e6f6e6963}", Description: "Fedora"},
+			expected: "0002",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			found, err := FindBootOption(newTestStore(t, testCase.store...), &testCase.entry)
+			if err != nil {
+				t.Fatalf("failed to find the load option: %v", err)
+			}
+			if found.ID != testCase.expected || found.DevicePath == nil {
+				t.Errorf("got %s (%s), expected %s", found.ID, found.DevicePathText, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestFindBootOptionErrors(t *testing.T) {
+	ubuntu := loadOptionTestCases[0]
+	testCases := []struct {
+		name  string
+		store []loadOptionTestCase
+		entry BootEntry
+	}{
+		{
+			name:  "missing variable",
+			store: []loadOptionTestCase{ubuntu},
+			entry: BootEntry{ID: "0007", Description: "ubuntu"},
+		},
+		{
+			name:  "no matching description",
+			store: []loadOptionTestCase{ubuntu},
+			entry: BootEntry{ID: "{c2d6b8a4-1e3f-11ee-9c4a-806e6f6e6963}", Description: "Fedora"},
+		},
+		{
+			name:  "duplicate descriptions",
+			store: []loadOptionTestCase{ubuntu, renamed(ubuntu, "Boot0004")},
+			entry: BootEntry{ID: "{c2d6b8a4-1e3f-11ee-9c4a-806e6f6e6963}", Description: "ubuntu"},
+		},
+		{
+			name:  "no Windows Boot Manager",
+			store: []loadOptionTestCase{ubuntu},
+			entry: BootEntry{ID: "{bootmgr}", Description: "Windows Boot Manager"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if found, err := FindBootOption(newTestStore(t, testCase.store...), &testCase.entry); err == nil {
+				t.Errorf("expected an error, got %s", found.ID)
+			}
+		})
+	}
+}
+
+func TestBCDObject(t *testing.T) {
+	option, err := ParseLoadOption(mustDecodeHex(t, loadOptionTestCases[1].data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object := BCDObject(option.OptionalData); object != "{9dea862c-5cdd-4e70-acc1-f32b344d4795}" {
+		t.Errorf("got %q", object)
+	}
+	if object := BCDObject([]byte{0x00, 0x00, 0x42, 0x4f}); object != "" {
+		t.Errorf("expected no BCD object, got %q", object)
+	}
+}
@@ -0,0 +1,96 @@
+package uefi
+
+import "fmt"
+
+// Returns the index of the first occurrence of a boot entry identifier in a boot order, or -1 if it is not present
+func IndexInOrder(order []string, id string) int {
+	for index, current := range order {
+		if current == id {
+			return index
+		}
+	}
+	return -1
+}
+
+// Returns a copy of a boot order with the specified boot entry moved to the front, inserting it if it is not present
+// (Any other occurrences of the boot entry are removed)
+func MoveToFront(order []string, id string) []string {
+	moved := []string{id}
+	for _, current := range order {
+		if current != id {
+			moved = append(moved, current)
+		}
+	}
+	return moved
+}
+
+// Returns a copy of a boot order with the specified boot entry swapped with the entry before it
+// (Boot entries that are already at the front of the order are left in place)
+func MoveUp(order []string, id string) ([]string, error) {
+	index := IndexInOrder(order, id)
+	if index == -1 {
+		return nil, fmt.Errorf("boot entry \"%s\" is not present in BootOrder", id)
+	}
+
+	moved := append([]string{}, order...)
+	if index > 0 {
+		moved[index-1], moved[index] = moved[index], moved[index-1]
+	}
+	return moved, nil
+}
+
+// Returns a copy of a boot order with the specified boot entry swapped with the entry after it
+// (Boot entries that are already at the end of the order are left in place)
+func MoveDown(order []string, id string) ([]string, error) {
+	index := IndexInOrder(order, id)
+	if index == -1 {
+		return nil, fmt.Errorf("boot entry \"%s\" is not present in BootOrder", id)
+	}
+
+	moved := append([]string{}, order...)
+	if index < len(moved)-1 {
+		moved[index], moved[index+1] = moved[index+1], moved[index]
+	}
+	return moved, nil
+}
+
+// Returns a copy of a boot order with all occurrences of the specified boot entry removed
+func RemoveFromOrder(order []string, id string) ([]string, error) {
+	if IndexInOrder(order, id) == -1 {
+		return nil, fmt.Errorf("boot entry \"%s\" is not present in BootOrder", id)
+	}
+
+	removed := []string{}
+	for _, current := range order {
+		if current != id {
+			removed = append(removed, current)
+		}
+	}
+	return removed, nil
+}
+
+// Returns a copy of a boot order with duplicate boot entries removed, keeping the first occurrence of each entry
+func DedupeOrder(order []string) []string {
+	seen := map[string]bool{}
+	deduped := []string{}
+	for _, id := range order {
+		if !seen[id] {
+			seen[id] = true
+			deduped = append(deduped, id)
+		}
+	}
+	return deduped
+}
+
+// Determines whether two boot orders are identical
+func EqualOrders(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package uefi
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The kinds of boot entries identified by ClassifyBootEntry
+// (The kinds for shim and GRUB loaders are qualified with the name of the directory that contains the loader, which
+// identifies the distribution that installed it, e.g. `shim/ubuntu`)
+const (
+	KindWindows     = "windows"
+	KindShim        = "shim"
+	KindGRUB        = "grub"
+	KindSystemdBoot = "systemd-boot"
+	KindRemovable   = "removable"
+	KindUSB         = "usb"
+	KindPXE         = "pxe"
+	KindHTTP        = "http"
+	KindFirmware    = "firmware"
+	KindOther       = "other"
+	KindUnknown     = "unknown"
+)
+
+// Patterns for classifying boot entries from their loader paths
+var (
+	windowsLoaderPattern     = regexp.MustCompile(`(?i)^\\EFI\\Microsoft\\Boot\\bootmgfw\.efi$`)
+	removableLoaderPattern   = regexp.MustCompile(`(?i)^\\EFI\\BOOT\\BOOT[^\\]*\.EFI$`)
+	systemdBootLoaderPattern = regexp.MustCompile(`(?i)^\\EFI\\systemd\\systemd-boot[^\\]*\.efi$`)
+	distroLoaderPattern      = regexp.MustCompile(`(?i)^\\EFI\\([^\\]+)\\(shim|grub)[^\\]*\.efi$`)
+)
+
+// Classifies a boot entry from its device path and loader path (see the `Kind*` constants)
+// (Boot entries without a device path can only be classified by identifier, which identifies the Windows Boot Manager
+// when using the `bcdedit` backend)
+func ClassifyBootEntry(entry *BootEntry) string {
+
+	// Boot entries without a device path cannot be classified, unless they are the Windows Boot Manager
+	if entry.DevicePath == nil && entry.DevicePathText == "" {
+		if strings.EqualFold(entry.ID, BCDEDIT_BOOTMGR_ID) {
+			return KindWindows
+		}
+		return KindUnknown
+	}
+
+	// Classify network boot entries, applications built into the firmware and USB devices by their device path nodes
+	// (Firmware applications are only identified by firmware volume nodes when they do not also reference a disk)
+	seen := map[string]bool{}
+	for _, node := range entry.NodeNames() {
+		seen[node] = true
+	}
+	switch {
+	case seen["Uri"]:
+		return KindHTTP
+	case seen["MAC"] || seen["IPv4"] || seen["IPv6"]:
+		return KindPXE
+	case (seen["FvFile"] || seen["FvVol"] || seen["Fv"]) && !seen["HD"]:
+		return KindFirmware
+	case seen["USB"] || seen["UsbClass"] || seen["UsbWwid"]:
+		return KindUSB
+	}
+
+	// Classify disk boot entries by their loader path
+	loader := entry.LoaderPath()
+	if loader == "" {
+		return KindOther
+	}
+	loader = NormaliseLoaderPath(loader)
+	switch {
+	case windowsLoaderPattern.MatchString(loader):
+		return KindWindows
+	case removableLoaderPattern.MatchString(loader):
+		return KindRemovable
+	case systemdBootLoaderPattern.MatchString(loader):
+		return KindSystemdBoot
+	}
+	if groups := distroLoaderPattern.FindStringSubmatch(loader); groups != nil {
+		return strings.ToLower(groups[2]) + "/" + strings.ToLower(groups[1])
+	}
+	return KindOther
+}
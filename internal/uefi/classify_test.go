@@ -0,0 +1,191 @@
+package uefi
+
+import (
+	"strings"
+	"testing"
+)
+
+// Decodes the load option for a test case into a boot entry, failing the test if it cannot be parsed
+func mustDecodeTestBootEntry(t *testing.T, testCase loadOptionTestCase) BootEntry {
+	t.Helper()
+	option, err := ParseLoadOption(mustDecodeHex(t, testCase.data))
+	if err != nil {
+		t.Fatalf("failed to parse load option: %v", err)
+	}
+	entry := newBootEntry(strings.TrimPrefix(testCase.name, "Boot"), option)
+	if entry.DevicePath == nil {
+		t.Fatalf("failed to decode the device path of %s", testCase.name)
+	}
+	return entry
+}
+
+func TestClassifyDecodedBootEntries(t *testing.T) {
+	expected := map[string]string{
+		"Boot0000": "shim/ubuntu",
+		"Boot0001": KindWindows,
+		"Boot0002": "shim/fedora",
+		"Boot0003": KindUSB,
+		"Boot0004": KindOther,
+		"Boot0005": KindPXE,
+		"Boot0006": KindPXE,
+		"Boot0007": KindHTTP,
+		"Boot0008": KindOther,
+		"Boot0009": KindFirmware,
+		"Boot000A": KindFirmware,
+		"Boot000B": KindOther,
+		"Boot000C": KindPXE,
+	}
+
+	// Each boot entry must be classified identically from its decoded device path and from its text representation, as
+	// reported by backends that do not decode device paths
+	for _, testCase := range loadOptionTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decoded := mustDecodeTestBootEntry(t, testCase)
+			if decoded.Kind != expected[testCase.name] {
+				t.Errorf("decoded: got %s, expected %s", decoded.Kind, expected[testCase.name])
+			}
+			text := &BootEntry{ID: decoded.ID, DevicePathText: testCase.devicePath}
+			if kind := ClassifyBootEntry(text); kind != expected[testCase.name] {
+				t.Errorf("text: got %s, expected %s", kind, expected[testCase.name])
+			}
+		})
+	}
+}
+
+func TestClassifyBootEntry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		entry    BootEntry
+		expected string
+	}{
+		{
+			name:     "removable media loader",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\BOOT\BOOTX64.EFI)`},
+			expected: KindRemovable,
+		},
+		{
+			name:     "removable media loader for arm64",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\Boot\bootaa64.efi)`},
+			expected: KindRemovable,
+		},
+		{
+			name:     "systemd-boot",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\systemd\systemd-bootx64.efi)`},
+			expected: KindSystemdBoot,
+		},
+		{
+			name:     "grub",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\Arch\grubx64.efi)`},
+			expected: "grub/arch",
+		},
+		{
+			name:     "forward slashes",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(EFI/Microsoft/Boot/bootmgfw.efi)`},
+			expected: KindWindows,
+		},
+		{
+			name:     "other loader",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\tools\shellx64.efi)`},
+			expected: KindOther,
+		},
+		{
+			name:     "loader outside a directory",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\shimx64.efi)`},
+			expected: KindOther,
+		},
+		{
+			name:     "USB class",
+			entry:    BootEntry{DevicePathText: `UsbClass(0xffff,0xffff,0x08,0x06,0x50)`},
+			expected: KindUSB,
+		},
+		{
+			name:     "USB with removable media loader",
+			entry:    BootEntry{DevicePathText: `PciRoot(0x0)/Pci(0x14,0x0)/USB(3,0)/HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\BOOT\BOOTX64.EFI)`},
+			expected: KindUSB,
+		},
+		{
+			name:     "firmware volume",
+			entry:    BootEntry{DevicePathText: `Fv(7cb8bdc9-f8eb-4f34-aaea-3ee4af6516a1)/FvFile(462caa21-7614-4503-836e-8ab6f4662331)`},
+			expected: KindFirmware,
+		},
+		{
+			name:     "firmware file on a disk",
+			entry:    BootEntry{DevicePathText: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/FvFile(462caa21-7614-4503-836e-8ab6f4662331)`},
+			expected: KindOther,
+		},
+		{
+			name:     "HTTP boot by URI",
+			entry:    BootEntry{DevicePathText: `PciRoot(0x0)/Pci(0x1,0x0)/MAC(525400123456,1)/IPv6(::,0x0,Static,::,::,64)/Uri(http://example.com/boot.efi)`},
+			expected: KindHTTP,
+		},
+		{
+			name:     "Windows Boot Manager from bcdedit",
+			entry:    BootEntry{ID: BCDEDIT_BOOTMGR_ID, Description: "Windows Boot Manager"},
+			expected: KindWindows,
+		},
+		{
+			name:     "Windows Boot Manager from bcdedit in upper case",
+			entry:    BootEntry{ID: "{BOOTMGR}", Description: "Windows Boot Manager"},
+			expected: KindWindows,
+		},
+		{
+			name:     "firmware application from bcdedit",
+			entry:    BootEntry{ID: "{9dea862c-5cdd-4e70-acc1-f32b344d4796}", Description: "UEFI: USB"},
+			expected: KindUnknown,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if kind := ClassifyBootEntry(&testCase.entry); kind != testCase.expected {
+				t.Errorf("got %s, expected %s", kind, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestLoaderPathAndNodeNames(t *testing.T) {
+	testCases := []struct {
+		name    string
+		loader  string
+		decoded []string
+		text    []string
+	}{
+		{name: "Boot0000", loader: `\EFI\ubuntu\shimx64.efi`, decoded: []string{"HD"}, text: []string{"HD", "File"}},
+		{name: "Boot0002", loader: `\EFI\fedora\shimx64.efi`, decoded: []string{"NVMe", "HD"}, text: []string{"PciRoot", "Pci", "Pci", "NVMe", "HD", "File"}},
+		{name: "Boot0003", loader: "", decoded: []string{"USB", "HD"}, text: []string{"PciRoot", "Pci", "USB", "HD"}},
+		{name: "Boot0004", loader: "", decoded: []string{"Sata"}, text: []string{"PciRoot", "Pci", "Sata"}},
+		{name: "Boot0007", loader: "", decoded: []string{"MAC", "IPv4", "Uri"}, text: []string{"PciRoot", "Pci", "MAC", "IPv4", "Uri"}},
+		{name: "Boot0009", loader: "", decoded: []string{"FvVol", "FvFile"}, text: []string{"FvVol", "FvFile"}},
+		{name: "Boot000B", loader: "", decoded: []string{"BBS"}, text: []string{"Path"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var decoded BootEntry
+			for _, loadOption := range loadOptionTestCases {
+				if loadOption.name == testCase.name {
+					decoded = mustDecodeTestBootEntry(t, loadOption)
+				}
+			}
+			text := &BootEntry{DevicePathText: decoded.DevicePathText}
+
+			// Both representations yield the same loader path
+			if loader := decoded.LoaderPath(); loader != testCase.loader {
+				t.Errorf("decoded loader path: got %q, expected %q", loader, testCase.loader)
+			}
+			if loader := text.LoaderPath(); loader != testCase.loader {
+				t.Errorf("text loader path: got %q, expected %q", loader, testCase.loader)
+			}
+
+			// Decoded device paths only name the nodes used for classification and selection, while the text
+			// representation names every node
+			if names := decoded.NodeNames(); strings.Join(names, "/") != strings.Join(testCase.decoded, "/") {
+				t.Errorf("decoded node names: got %q, expected %q", names, testCase.decoded)
+			}
+			if names := text.NodeNames(); strings.Join(names, "/") != strings.Join(testCase.text, "/") {
+				t.Errorf("text node names: got %q, expected %q", names, testCase.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package uefi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalises a loader path to the form used by file path device nodes (e.g. `/EFI/tools/shellx64.efi` becomes
+// `\EFI\tools\shellx64.efi`)
+func NormaliseLoaderPath(path string) string {
+	path = strings.ReplaceAll(path, "/", "\\")
+	if !strings.HasPrefix(path, "\\") {
+		path = "\\" + path
+	}
+	return path
+}
+
+// Creates an active load option that boots the specified loader file from the partition identified by a hard drive node
+func NewLoaderLoadOption(description string, partition *HardDriveNode, loader string) *LoadOption {
+	path := DevicePath{
+		partition,
+		&FilePathNode{Path: NormaliseLoaderPath(loader)},
+		&EndNode{SubType: END_ENTIRE_DEVICE_PATH},
+	}
+
+	return &LoadOption{
+		Attributes:   LOAD_OPTION_ACTIVE,
+		Description:  description,
+		FilePathList: path.Bytes(),
+		OptionalData: []byte{},
+	}
+}
+
+// Writes a load option to the lowest-numbered unused `Boot####` variable, without adding it to BootOrder
+func (b *NativeBackend) CreateBootEntry(option *LoadOption) (BootEntry, error) {
+
+	// Identify the load option numbers that are already in use
+	entries, err := b.ListBootEntries()
+	if err != nil {
+		return BootEntry{}, err
+	}
+	used := map[string]bool{}
+	for _, entry := range entries {
+		used[entry.ID] = true
+	}
+
+	// Write the load option to the first free slot
+	for number := 0; number <= 0xFFFF; number++ {
+		id := formatOptionNumber(uint16(number))
+		if !used[id] {
+			if err := b.Store.WriteVariable("Boot"+id, EFI_GLOBAL_VARIABLE, bootVariableAttributes, option.Bytes()); err != nil {
+				return BootEntry{}, fmt.Errorf("failed to write UEFI variable Boot%s: %v", id, err)
+			}
+			return newBootEntry(id, option), nil
+		}
+	}
+
+	return BootEntry{}, fmt.Errorf("all 65536 Boot#### variables are in use")
+}
+
+// Deletes the `Boot####` variable for the specified boot entry
+// (Note that this does not remove the boot entry from BootOrder)
+func (b *NativeBackend) DeleteBootEntry(entry BootEntry) error {
+	if _, err := parseOptionNumber(entry.ID); err != nil {
+		return err
+	}
+	return b.Store.DeleteVariable("Boot"+entry.ID, EFI_GLOBAL_VARIABLE)
+}
@@ -0,0 +1,660 @@
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Device path node types, from: <https://uefi.org/specs/UEFI/2.10/10_Protocols_Device_Path_Protocol.html#generic-device-path-structures>
+const (
+	HARDWARE_DEVICE_PATH  uint8 = 0x01
+	ACPI_DEVICE_PATH      uint8 = 0x02
+	MESSAGING_DEVICE_PATH uint8 = 0x03
+	MEDIA_DEVICE_PATH     uint8 = 0x04
+	BBS_DEVICE_PATH       uint8 = 0x05
+	END_DEVICE_PATH_TYPE  uint8 = 0x7f
+)
+
+// Device path node sub-types for the node types that we decode
+const (
+	HW_PCI_DP                uint8 = 0x01
+	HW_VENDOR_DP             uint8 = 0x04
+	ACPI_DP                  uint8 = 0x01
+	MSG_USB_DP               uint8 = 0x05
+	MSG_VENDOR_DP            uint8 = 0x0a
+	MSG_MAC_ADDR_DP          uint8 = 0x0b
+	MSG_IPv4_DP              uint8 = 0x0c
+	MSG_IPv6_DP              uint8 = 0x0d
+	MSG_SATA_DP              uint8 = 0x12
+	MSG_NVME_NAMESPACE_DP    uint8 = 0x17
+	MSG_URI_DP               uint8 = 0x18
+	MEDIA_HARDDRIVE_DP       uint8 = 0x01
+	MEDIA_CDROM_DP           uint8 = 0x02
+	MEDIA_VENDOR_DP          uint8 = 0x03
+	MEDIA_FILEPATH_DP        uint8 = 0x04
+	MEDIA_PIWG_FW_FILE_DP    uint8 = 0x06
+	MEDIA_PIWG_FW_VOL_DP     uint8 = 0x07
+	END_INSTANCE_DEVICE_PATH uint8 = 0x01
+	END_ENTIRE_DEVICE_PATH   uint8 = 0xff
+)
+
+// Field values and data lengths (excluding the four-byte node header) for the device path nodes that we decode
+const (
+	_ACPI_PNP0A03_PCI_ROOT   uint32 = 0x0a0341d0
+	_ACPI_PNP0A08_PCIE_ROOT  uint32 = 0x0a0841d0
+	_MBR_TYPE_PCAT           uint8  = 0x01
+	_MBR_TYPE_EFI_PARTITION  uint8  = 0x02
+	_SIGNATURE_TYPE_MBR      uint8  = 0x01
+	_SIGNATURE_TYPE_GUID     uint8  = 0x02
+	_IP_PROTOCOL_TCP         uint16 = 6
+	_IP_PROTOCOL_UDP         uint16 = 17
+	_IPV4_NODE_LENGTH_LEGACY int    = 15
+	_IPV4_NODE_LENGTH        int    = 23
+	_IPV6_NODE_LENGTH_LEGACY int    = 39
+	_IPV6_NODE_LENGTH        int    = 56
+	_HARDDRIVE_NODE_LENGTH   int    = 38
+	_ETHERNET_ADDRESS_LENGTH int    = 6
+)
+
+// Represents an individual node in a UEFI device path
+type DevicePathNode interface {
+
+	// Returns the type of the node (see the `*_DEVICE_PATH` constants)
+	NodeType() uint8
+
+	// Returns the sub-type of the node
+	NodeSubType() uint8
+
+	// Returns the text representation of the node, following the conventions used by `efibootmgr`
+	String() string
+
+	// Returns the encoded data for the node, excluding the four-byte node header
+	encode() []byte
+}
+
+// Represents a UEFI device path as a list of nodes, including any end nodes that separate device path instances
+type DevicePath []DevicePathNode
+
+// Parses a device path list (such as the FilePathList field of a load option) into its individual nodes
+//
+// Nodes whose type is not recognised, or whose data does not match the expected layout for their type, are
+// represented as `RawNode` values so that the device path can always be re-encoded without any loss of information.
+func ParseDevicePath(data []byte) (DevicePath, error) {
+	path := DevicePath{}
+	for offset := 0; offset < len(data); {
+
+		// Parse the node header and verify that the length is valid
+		if offset+4 > len(data) {
+			return nil, fmt.Errorf("device path node at offset %d is truncated", offset)
+		}
+		nodeType, nodeSubType := data[offset], data[offset+1]
+		length := int(binary.LittleEndian.Uint16(data[offset+2 : offset+4]))
+		if length < 4 || offset+length > len(data) {
+			return nil, fmt.Errorf("device path node at offset %d has invalid length %d", offset, length)
+		}
+
+		// Decode the node's data
+		path = append(path, decodeDevicePathNode(nodeType, nodeSubType, data[offset+4:offset+length]))
+		offset += length
+	}
+
+	return path, nil
+}
+
+// Encodes the device path back to its binary representation
+func (p DevicePath) Bytes() []byte {
+	buffer := &bytes.Buffer{}
+	for _, node := range p {
+		data := node.encode()
+		buffer.WriteByte(node.NodeType())
+		buffer.WriteByte(node.NodeSubType())
+		binary.Write(buffer, binary.LittleEndian, uint16(4+len(data)))
+		buffer.Write(data)
+	}
+	return buffer.Bytes()
+}
+
+// Returns the text representation of the device path, following the conventions used by `efibootmgr`
+// (e.g. `HD(1,GPT,...)/File(\EFI\ubuntu\shimx64.efi)`)
+func (p DevicePath) String() string {
+	builder := strings.Builder{}
+	separator := ""
+	for _, node := range p {
+		if end, isEnd := node.(*EndNode); isEnd {
+			separator = ""
+			if end.SubType == END_INSTANCE_DEVICE_PATH {
+				builder.WriteString(",")
+			} else if end.SubType == END_ENTIRE_DEVICE_PATH {
+				separator = ";"
+			}
+			continue
+		}
+
+		builder.WriteString(separator)
+		builder.WriteString(node.String())
+		separator = "/"
+	}
+
+	return strings.TrimSuffix(builder.String(), ";")
+}
+
+// Decodes the data for an individual device path node, falling back to a raw node if the data cannot be decoded
+func decodeDevicePathNode(nodeType uint8, nodeSubType uint8, data []byte) DevicePathNode {
+	raw := &RawNode{Type: nodeType, SubType: nodeSubType, Data: append([]byte{}, data...)}
+	le := binary.LittleEndian
+
+	switch {
+	case nodeType == END_DEVICE_PATH_TYPE && len(data) == 0:
+		return &EndNode{SubType: nodeSubType}
+
+	case nodeType == HARDWARE_DEVICE_PATH && nodeSubType == HW_PCI_DP && len(data) == 2:
+		return &PCINode{Function: data[0], Device: data[1]}
+
+	case nodeType == ACPI_DEVICE_PATH && nodeSubType == ACPI_DP && len(data) == 8:
+		return &ACPINode{HID: le.Uint32(data[0:4]), UID: le.Uint32(data[4:8])}
+
+	case nodeSubType == HW_VENDOR_DP && nodeType == HARDWARE_DEVICE_PATH && len(data) >= 16,
+		nodeSubType == MSG_VENDOR_DP && nodeType == MESSAGING_DEVICE_PATH && len(data) >= 16,
+		nodeSubType == MEDIA_VENDOR_DP && nodeType == MEDIA_DEVICE_PATH && len(data) >= 16:
+		node := &VendorNode{Type: nodeType, Data: append([]byte{}, data[16:]...)}
+		copy(node.GUID[:], data[0:16])
+		return node
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_USB_DP && len(data) == 2:
+		return &USBNode{ParentPort: data[0], Interface: data[1]}
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_SATA_DP && len(data) == 6:
+		return &SATANode{
+			HBAPort:            le.Uint16(data[0:2]),
+			PortMultiplierPort: le.Uint16(data[2:4]),
+			LUN:                le.Uint16(data[4:6]),
+		}
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_NVME_NAMESPACE_DP && len(data) == 12:
+		node := &NVMeNode{NamespaceID: le.Uint32(data[0:4])}
+		copy(node.EUI64[:], data[4:12])
+		return node
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_MAC_ADDR_DP && len(data) == 33:
+		node := &MACNode{InterfaceType: data[32]}
+		copy(node.Address[:], data[0:32])
+		return node
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_IPv4_DP && (len(data) == _IPV4_NODE_LENGTH || len(data) == _IPV4_NODE_LENGTH_LEGACY):
+		node := &IPv4Node{
+			LocalAddress:   net.IP(append([]byte{}, data[0:4]...)),
+			RemoteAddress:  net.IP(append([]byte{}, data[4:8]...)),
+			LocalPort:      le.Uint16(data[8:10]),
+			RemotePort:     le.Uint16(data[10:12]),
+			Protocol:       le.Uint16(data[12:14]),
+			StaticAddress:  data[14] != 0,
+			legacyEncoding: len(data) == _IPV4_NODE_LENGTH_LEGACY,
+		}
+		if !node.legacyEncoding {
+			node.GatewayAddress = net.IP(append([]byte{}, data[15:19]...))
+			node.SubnetMask = net.IP(append([]byte{}, data[19:23]...))
+		}
+		return node
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_IPv6_DP && (len(data) == _IPV6_NODE_LENGTH || len(data) == _IPV6_NODE_LENGTH_LEGACY):
+		node := &IPv6Node{
+			LocalAddress:   net.IP(append([]byte{}, data[0:16]...)),
+			RemoteAddress:  net.IP(append([]byte{}, data[16:32]...)),
+			LocalPort:      le.Uint16(data[32:34]),
+			RemotePort:     le.Uint16(data[34:36]),
+			Protocol:       le.Uint16(data[36:38]),
+			AddressOrigin:  data[38],
+			legacyEncoding: len(data) == _IPV6_NODE_LENGTH_LEGACY,
+		}
+		if !node.legacyEncoding {
+			node.PrefixLength = data[39]
+			node.GatewayAddress = net.IP(append([]byte{}, data[40:56]...))
+		}
+		return node
+
+	case nodeType == MESSAGING_DEVICE_PATH && nodeSubType == MSG_URI_DP:
+		return &URINode{URI: string(data)}
+
+	case nodeType == MEDIA_DEVICE_PATH && nodeSubType == MEDIA_HARDDRIVE_DP && len(data) == _HARDDRIVE_NODE_LENGTH:
+		node := &HardDriveNode{
+			PartitionNumber: le.Uint32(data[0:4]),
+			PartitionStart:  le.Uint64(data[4:12]),
+			PartitionSize:   le.Uint64(data[12:20]),
+			PartitionFormat: data[36],
+			SignatureType:   data[37],
+		}
+		copy(node.Signature[:], data[20:36])
+		return node
+
+	case nodeType == MEDIA_DEVICE_PATH && nodeSubType == MEDIA_FILEPATH_DP:
+		path, consumed, err := decodeUCS2String(data)
+		if err != nil {
+			return raw
+		}
+		node := &FilePathNode{Path: path}
+		if consumed != len(data) {
+			node.padding = append([]byte{}, data[consumed:]...)
+		}
+		return node
+
+	case nodeType == MEDIA_DEVICE_PATH && (nodeSubType == MEDIA_PIWG_FW_FILE_DP || nodeSubType == MEDIA_PIWG_FW_VOL_DP) && len(data) == 16:
+		node := &FirmwareNode{SubType: nodeSubType}
+		copy(node.GUID[:], data)
+		return node
+	}
+
+	return raw
+}
+
+// Represents a PCI device node (e.g. `Pci(0x1d,0x0)`)
+type PCINode struct {
+	Function uint8
+	Device   uint8
+}
+
+func (n *PCINode) NodeType() uint8    { return HARDWARE_DEVICE_PATH }
+func (n *PCINode) NodeSubType() uint8 { return HW_PCI_DP }
+func (n *PCINode) encode() []byte     { return []byte{n.Function, n.Device} }
+func (n *PCINode) String() string     { return fmt.Sprintf("Pci(0x%x,0x%x)", n.Device, n.Function) }
+
+// Represents an ACPI device node (e.g. `PciRoot(0x0)`)
+type ACPINode struct {
+	HID uint32
+	UID uint32
+}
+
+func (n *ACPINode) NodeType() uint8    { return ACPI_DEVICE_PATH }
+func (n *ACPINode) NodeSubType() uint8 { return ACPI_DP }
+
+func (n *ACPINode) encode() []byte {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[0:4], n.HID)
+	binary.LittleEndian.PutUint32(data[4:8], n.UID)
+	return data
+}
+
+func (n *ACPINode) String() string {
+	switch n.HID {
+	case _ACPI_PNP0A03_PCI_ROOT:
+		return fmt.Sprintf("PciRoot(0x%x)", n.UID)
+	case _ACPI_PNP0A08_PCIE_ROOT:
+		return fmt.Sprintf("PcieRoot(0x%x)", n.UID)
+	}
+
+	// Decode the compressed EISA identifier, which packs three letters into the lower 16 bits and a product number into the upper 16 bits
+	vendor := string([]byte{
+		byte((n.HID>>10)&0x1f) + 'A' - 1,
+		byte((n.HID>>5)&0x1f) + 'A' - 1,
+		byte(n.HID&0x1f) + 'A' - 1,
+	})
+	return fmt.Sprintf("Acpi(%s%04X,0x%x)", vendor, n.HID>>16, n.UID)
+}
+
+// Represents a vendor-defined hardware, messaging or media device node (e.g. `VenHw(GUID,data)`)
+type VendorNode struct {
+
+	// The node type (HARDWARE_DEVICE_PATH, MESSAGING_DEVICE_PATH or MEDIA_DEVICE_PATH)
+	Type uint8
+
+	// The vendor GUID that determines how the data is interpreted
+	GUID GUID
+
+	// Any vendor-defined data
+	Data []byte
+}
+
+func (n *VendorNode) NodeType() uint8 { return n.Type }
+func (n *VendorNode) encode() []byte  { return append(append([]byte{}, n.GUID[:]...), n.Data...) }
+
+func (n *VendorNode) NodeSubType() uint8 {
+	switch n.Type {
+	case HARDWARE_DEVICE_PATH:
+		return HW_VENDOR_DP
+	case MESSAGING_DEVICE_PATH:
+		return MSG_VENDOR_DP
+	default:
+		return MEDIA_VENDOR_DP
+	}
+}
+
+func (n *VendorNode) String() string {
+	prefix := map[uint8]string{HARDWARE_DEVICE_PATH: "VenHw", MESSAGING_DEVICE_PATH: "VenMsg"}[n.Type]
+	if prefix == "" {
+		prefix = "VenMedia"
+	}
+	if len(n.Data) == 0 {
+		return fmt.Sprintf("%s(%s)", prefix, n.GUID)
+	}
+	return fmt.Sprintf("%s(%s,%s)", prefix, n.GUID, hex.EncodeToString(n.Data))
+}
+
+// Represents a USB device node (e.g. `USB(0,0)`)
+type USBNode struct {
+	ParentPort uint8
+	Interface  uint8
+}
+
+func (n *USBNode) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *USBNode) NodeSubType() uint8 { return MSG_USB_DP }
+func (n *USBNode) encode() []byte     { return []byte{n.ParentPort, n.Interface} }
+func (n *USBNode) String() string     { return fmt.Sprintf("USB(%d,%d)", n.ParentPort, n.Interface) }
+
+// Represents a SATA device node (e.g. `Sata(0,65535,0)`)
+type SATANode struct {
+	HBAPort            uint16
+	PortMultiplierPort uint16
+	LUN                uint16
+}
+
+func (n *SATANode) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *SATANode) NodeSubType() uint8 { return MSG_SATA_DP }
+
+func (n *SATANode) encode() []byte {
+	data := make([]byte, 6)
+	binary.LittleEndian.PutUint16(data[0:2], n.HBAPort)
+	binary.LittleEndian.PutUint16(data[2:4], n.PortMultiplierPort)
+	binary.LittleEndian.PutUint16(data[4:6], n.LUN)
+	return data
+}
+
+func (n *SATANode) String() string {
+	return fmt.Sprintf("Sata(%d,%d,%d)", n.HBAPort, n.PortMultiplierPort, n.LUN)
+}
+
+// Represents an NVMe namespace device node (e.g. `NVMe(0x1,00-25-38-5B-71-B0-25-4C)`)
+type NVMeNode struct {
+	NamespaceID uint32
+	EUI64       [8]byte
+}
+
+func (n *NVMeNode) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *NVMeNode) NodeSubType() uint8 { return MSG_NVME_NAMESPACE_DP }
+
+func (n *NVMeNode) encode() []byte {
+	data := make([]byte, 12)
+	binary.LittleEndian.PutUint32(data[0:4], n.NamespaceID)
+	copy(data[4:], n.EUI64[:])
+	return data
+}
+
+func (n *NVMeNode) String() string {
+	octets := []string{}
+	for _, octet := range n.EUI64 {
+		octets = append(octets, fmt.Sprintf("%02X", octet))
+	}
+	return fmt.Sprintf("NVMe(0x%x,%s)", n.NamespaceID, strings.Join(octets, "-"))
+}
+
+// Represents a network interface MAC address device node (e.g. `MAC(001122334455,0x1)`)
+type MACNode struct {
+
+	// The MAC address, padded with zeroes to 32 bytes
+	Address [32]byte
+
+	// The network interface type, as defined by RFC 3232 (e.g. 0x1 for Ethernet)
+	InterfaceType uint8
+}
+
+func (n *MACNode) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *MACNode) NodeSubType() uint8 { return MSG_MAC_ADDR_DP }
+func (n *MACNode) encode() []byte     { return append(append([]byte{}, n.Address[:]...), n.InterfaceType) }
+
+// Returns the significant bytes of the MAC address
+// (Ethernet addresses are 6 bytes long, and we trim trailing zero padding for any other interface types)
+func (n *MACNode) HardwareAddress() net.HardwareAddr {
+	if n.InterfaceType <= 1 {
+		return net.HardwareAddr(append([]byte{}, n.Address[:_ETHERNET_ADDRESS_LENGTH]...))
+	}
+	return net.HardwareAddr(bytes.TrimRight(append([]byte{}, n.Address[:]...), "\x00"))
+}
+
+func (n *MACNode) String() string {
+	return fmt.Sprintf("MAC(%s,0x%x)", hex.EncodeToString(n.HardwareAddress()), n.InterfaceType)
+}
+
+// Encodes an IP address using the specified number of bytes, substituting zeroes for a missing address
+func encodeIP(ip net.IP, length int) []byte {
+	if length == net.IPv4len && ip.To4() != nil {
+		return ip.To4()
+	} else if length == net.IPv6len && ip.To16() != nil {
+		return ip.To16()
+	}
+	return make([]byte, length)
+}
+
+// Returns the text representation of an IP protocol number
+func ipProtocolString(protocol uint16) string {
+	switch protocol {
+	case _IP_PROTOCOL_TCP:
+		return "TCP"
+	case _IP_PROTOCOL_UDP:
+		return "UDP"
+	default:
+		return fmt.Sprintf("0x%x", protocol)
+	}
+}
+
+// Represents an IPv4 device node (e.g. `IPv4(0.0.0.0,0x0,DHCP,0.0.0.0,0.0.0.0,0.0.0.0)`)
+type IPv4Node struct {
+	LocalAddress   net.IP
+	RemoteAddress  net.IP
+	LocalPort      uint16
+	RemotePort     uint16
+	Protocol       uint16
+	StaticAddress  bool
+	GatewayAddress net.IP
+	SubnetMask     net.IP
+
+	// Specifies whether the node used the shorter encoding from older versions of the UEFI specification,
+	// which lacks the gateway address and subnet mask fields
+	legacyEncoding bool
+}
+
+func (n *IPv4Node) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *IPv4Node) NodeSubType() uint8 { return MSG_IPv4_DP }
+
+func (n *IPv4Node) encode() []byte {
+	buffer := &bytes.Buffer{}
+	buffer.Write(encodeIP(n.LocalAddress, net.IPv4len))
+	buffer.Write(encodeIP(n.RemoteAddress, net.IPv4len))
+	binary.Write(buffer, binary.LittleEndian, []uint16{n.LocalPort, n.RemotePort, n.Protocol})
+	buffer.WriteByte(map[bool]byte{false: 0, true: 1}[n.StaticAddress])
+	if !n.legacyEncoding {
+		buffer.Write(encodeIP(n.GatewayAddress, net.IPv4len))
+		buffer.Write(encodeIP(n.SubnetMask, net.IPv4len))
+	}
+	return buffer.Bytes()
+}
+
+func (n *IPv4Node) String() string {
+	origin := map[bool]string{false: "DHCP", true: "Static"}[n.StaticAddress]
+	text := fmt.Sprintf("IPv4(%s,%s,%s,%s", n.RemoteAddress, ipProtocolString(n.Protocol), origin, n.LocalAddress)
+	if !n.legacyEncoding {
+		text += fmt.Sprintf(",%s,%s", n.GatewayAddress, n.SubnetMask)
+	}
+	return text + ")"
+}
+
+// Represents an IPv6 device node (e.g. `IPv6(::,0x0,Static,::,::,0)`)
+type IPv6Node struct {
+	LocalAddress   net.IP
+	RemoteAddress  net.IP
+	LocalPort      uint16
+	RemotePort     uint16
+	Protocol       uint16
+	AddressOrigin  uint8
+	PrefixLength   uint8
+	GatewayAddress net.IP
+
+	// Specifies whether the node used the shorter encoding from older versions of the UEFI specification,
+	// which lacks the prefix length and gateway address fields
+	legacyEncoding bool
+}
+
+func (n *IPv6Node) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *IPv6Node) NodeSubType() uint8 { return MSG_IPv6_DP }
+
+func (n *IPv6Node) encode() []byte {
+	buffer := &bytes.Buffer{}
+	buffer.Write(encodeIP(n.LocalAddress, net.IPv6len))
+	buffer.Write(encodeIP(n.RemoteAddress, net.IPv6len))
+	binary.Write(buffer, binary.LittleEndian, []uint16{n.LocalPort, n.RemotePort, n.Protocol})
+	buffer.WriteByte(n.AddressOrigin)
+	if !n.legacyEncoding {
+		buffer.WriteByte(n.PrefixLength)
+		buffer.Write(encodeIP(n.GatewayAddress, net.IPv6len))
+	}
+	return buffer.Bytes()
+}
+
+func (n *IPv6Node) String() string {
+	origin := []string{"Static", "StatelessAutoConfigure", "StatefulAutoConfigure"}
+	originText := fmt.Sprintf("0x%x", n.AddressOrigin)
+	if int(n.AddressOrigin) < len(origin) {
+		originText = origin[n.AddressOrigin]
+	}
+	text := fmt.Sprintf("IPv6(%s,%s,%s,%s", n.RemoteAddress, ipProtocolString(n.Protocol), originText, n.LocalAddress)
+	if !n.legacyEncoding {
+		text += fmt.Sprintf(",%s,%d", n.GatewayAddress, n.PrefixLength)
+	}
+	return text + ")"
+}
+
+// Represents a URI device node, as used for HTTP boot (e.g. `Uri(http://example.com/boot.efi)`)
+type URINode struct {
+	URI string
+}
+
+func (n *URINode) NodeType() uint8    { return MESSAGING_DEVICE_PATH }
+func (n *URINode) NodeSubType() uint8 { return MSG_URI_DP }
+func (n *URINode) encode() []byte     { return []byte(n.URI) }
+func (n *URINode) String() string     { return fmt.Sprintf("Uri(%s)", n.URI) }
+
+// Represents a hard drive partition device node (e.g. `HD(1,GPT,GUID,0x800,0x100000)`)
+type HardDriveNode struct {
+
+	// The one-based partition number
+	PartitionNumber uint32
+
+	// The starting LBA of the partition
+	PartitionStart uint64
+
+	// The size of the partition, in logical blocks
+	PartitionSize uint64
+
+	// The partition signature, which is a GUID for GPT partitions or a 32-bit disk signature for MBR partitions
+	Signature [16]byte
+
+	// The partition table format (0x01 for MBR or 0x02 for GPT)
+	PartitionFormat uint8
+
+	// The signature type (0x00 for no signature, 0x01 for an MBR signature or 0x02 for a GUID signature)
+	SignatureType uint8
+}
+
+func (n *HardDriveNode) NodeType() uint8    { return MEDIA_DEVICE_PATH }
+func (n *HardDriveNode) NodeSubType() uint8 { return MEDIA_HARDDRIVE_DP }
+
+func (n *HardDriveNode) encode() []byte {
+	data := make([]byte, _HARDDRIVE_NODE_LENGTH)
+	binary.LittleEndian.PutUint32(data[0:4], n.PartitionNumber)
+	binary.LittleEndian.PutUint64(data[4:12], n.PartitionStart)
+	binary.LittleEndian.PutUint64(data[12:20], n.PartitionSize)
+	copy(data[20:36], n.Signature[:])
+	data[36] = n.PartitionFormat
+	data[37] = n.SignatureType
+	return data
+}
+
+// Returns the partition GUID, if the node has a GUID signature
+func (n *HardDriveNode) PartitionGUID() (GUID, bool) {
+	return GUID(n.Signature), n.SignatureType == _SIGNATURE_TYPE_GUID
+}
+
+func (n *HardDriveNode) String() string {
+	format := fmt.Sprintf("%d", n.PartitionFormat)
+	switch n.PartitionFormat {
+	case _MBR_TYPE_PCAT:
+		format = "MBR"
+	case _MBR_TYPE_EFI_PARTITION:
+		format = "GPT"
+	}
+
+	signature := "0"
+	switch n.SignatureType {
+	case _SIGNATURE_TYPE_MBR:
+		signature = fmt.Sprintf("0x%08x", binary.LittleEndian.Uint32(n.Signature[0:4]))
+	case _SIGNATURE_TYPE_GUID:
+		signature = GUID(n.Signature).String()
+	}
+
+	return fmt.Sprintf("HD(%d,%s,%s,0x%x,0x%x)", n.PartitionNumber, format, signature, n.PartitionStart, n.PartitionSize)
+}
+
+// Represents a file path device node (e.g. `File(\EFI\ubuntu\shimx64.efi)`)
+type FilePathNode struct {
+	Path string
+
+	// Any bytes that follow the null terminator of the path, which some firmware implementations include when they pad
+	// the node to a fixed length (preserved so that the node is re-encoded exactly as it was read)
+	padding []byte
+}
+
+func (n *FilePathNode) NodeType() uint8    { return MEDIA_DEVICE_PATH }
+func (n *FilePathNode) NodeSubType() uint8 { return MEDIA_FILEPATH_DP }
+func (n *FilePathNode) encode() []byte     { return append(encodeUCS2String(n.Path), n.padding...) }
+func (n *FilePathNode) String() string     { return fmt.Sprintf("File(%s)", n.Path) }
+
+// Represents a firmware volume or firmware file device node, as used for applications built into the firmware
+// (e.g. `FvVol(GUID)/FvFile(GUID)`)
+type FirmwareNode struct {
+
+	// The node sub-type (MEDIA_PIWG_FW_FILE_DP or MEDIA_PIWG_FW_VOL_DP)
+	SubType uint8
+
+	// The GUID of the firmware volume or file
+	GUID GUID
+}
+
+func (n *FirmwareNode) NodeType() uint8    { return MEDIA_DEVICE_PATH }
+func (n *FirmwareNode) NodeSubType() uint8 { return n.SubType }
+func (n *FirmwareNode) encode() []byte     { return append([]byte{}, n.GUID[:]...) }
+
+func (n *FirmwareNode) String() string {
+	if n.SubType == MEDIA_PIWG_FW_VOL_DP {
+		return fmt.Sprintf("FvVol(%s)", n.GUID)
+	}
+	return fmt.Sprintf("FvFile(%s)", n.GUID)
+}
+
+// Represents an end node, which terminates either a device path instance or the entire device path
+type EndNode struct {
+	SubType uint8
+}
+
+func (n *EndNode) NodeType() uint8    { return END_DEVICE_PATH_TYPE }
+func (n *EndNode) NodeSubType() uint8 { return n.SubType }
+func (n *EndNode) encode() []byte     { return []byte{} }
+func (n *EndNode) String() string     { return "" }
+
+// Represents a device node whose type is not decoded, preserving its raw data (e.g. `Path(5,1,data)`)
+type RawNode struct {
+	Type    uint8
+	SubType uint8
+	Data    []byte
+}
+
+func (n *RawNode) NodeType() uint8    { return n.Type }
+func (n *RawNode) NodeSubType() uint8 { return n.SubType }
+func (n *RawNode) encode() []byte     { return n.Data }
+
+func (n *RawNode) String() string {
+	if len(n.Data) == 0 {
+		return fmt.Sprintf("Path(%d,%d)", n.Type, n.SubType)
+	}
+	return fmt.Sprintf("Path(%d,%d,%s)", n.Type, n.SubType, hex.EncodeToString(n.Data))
+}
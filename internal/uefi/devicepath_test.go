@@ -0,0 +1,192 @@
+package uefi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Parses the device path list of a load option test case, failing the test if it cannot be parsed
+func mustParseTestDevicePath(t *testing.T, testCase loadOptionTestCase) (DevicePath, []byte) {
+	t.Helper()
+	option, err := ParseLoadOption(mustDecodeHex(t, testCase.data))
+	if err != nil {
+		t.Fatalf("failed to parse load option: %v", err)
+	}
+	path, err := ParseDevicePath(option.FilePathList)
+	if err != nil {
+		t.Fatalf("failed to parse device path: %v", err)
+	}
+	return path, option.FilePathList
+}
+
+func TestParseDevicePath(t *testing.T) {
+	for _, testCase := range loadOptionTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path, data := mustParseTestDevicePath(t, testCase)
+			if text := path.String(); text != testCase.devicePath {
+				t.Errorf("text mismatch:\n got: %s\nwant: %s", text, testCase.devicePath)
+			}
+			if encoded := path.Bytes(); !bytes.Equal(encoded, data) {
+				t.Errorf("round trip mismatch:\n got: %x\nwant: %x", encoded, data)
+			}
+		})
+	}
+}
+
+func TestParseDevicePathPreservesUnknownNodes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		index    int
+		nodeType uint8
+		subType  uint8
+		data     string
+	}{
+		{name: "Boot000B", index: 0, nodeType: BBS_DEVICE_PATH, subType: 0x01, data: "02000000535431303030444d30303300"},
+		{name: "Boot000C", index: 3, nodeType: MESSAGING_DEVICE_PATH, subType: 0x1f, data: "0008080808000000000000000000000000"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var path DevicePath
+			for _, loadOption := range loadOptionTestCases {
+				if loadOption.name == testCase.name {
+					path, _ = mustParseTestDevicePath(t, loadOption)
+				}
+			}
+			if testCase.index >= len(path) {
+				t.Fatalf("expected at least %d nodes, got %d", testCase.index+1, len(path))
+			}
+
+			raw, ok := path[testCase.index].(*RawNode)
+			if !ok {
+				t.Fatalf("expected a raw node, got %T", path[testCase.index])
+			}
+			if raw.Type != testCase.nodeType || raw.SubType != testCase.subType {
+				t.Errorf("node type: got (%d,%d), expected (%d,%d)", raw.Type, raw.SubType, testCase.nodeType, testCase.subType)
+			}
+			if expected := mustDecodeHex(t, testCase.data); !bytes.Equal(raw.Data, expected) {
+				t.Errorf("node data: got %x, expected %x", raw.Data, expected)
+			}
+		})
+	}
+}
+
+func TestParseDevicePathFallsBackToRawNodes(t *testing.T) {
+
+	// Known node types whose data does not match the expected layout are preserved as raw nodes
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "short hard drive node", data: "04010800010000007fff0400"},
+		{name: "long PCI node", data: "0101070000001d7fff0400"},
+		{name: "unterminated file path", data: "040406005c007fff0400"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data := mustDecodeHex(t, testCase.data)
+			path, err := ParseDevicePath(data)
+			if err != nil {
+				t.Fatalf("failed to parse device path: %v", err)
+			}
+			if _, ok := path[0].(*RawNode); !ok {
+				t.Errorf("expected a raw node, got %T", path[0])
+			}
+			if encoded := path.Bytes(); !bytes.Equal(encoded, data) {
+				t.Errorf("round trip mismatch:\n got: %x\nwant: %x", encoded, data)
+			}
+		})
+	}
+}
+
+func TestParseDevicePathTrimsFilePathPadding(t *testing.T) {
+
+	// `\EFI\a.efi` followed by its null terminator and eight bytes of padding
+	data := mustDecodeHex(t, "04042200"+"5c004500460049005c0061002e006500660069000000"+"0000000000000000"+"7fff0400")
+	path, err := ParseDevicePath(data)
+	if err != nil {
+		t.Fatalf("failed to parse device path: %v", err)
+	}
+	file, ok := path[0].(*FilePathNode)
+	if !ok {
+		t.Fatalf("expected a file path node, got %T", path[0])
+	}
+	if file.Path != `\EFI\a.efi` {
+		t.Errorf("path: got %q, expected %q", file.Path, `\EFI\a.efi`)
+	}
+	if loader := (&BootEntry{DevicePath: path}).LoaderPath(); loader != `\EFI\a.efi` {
+		t.Errorf("loader path: got %q", loader)
+	}
+	if encoded := path.Bytes(); !bytes.Equal(encoded, data) {
+		t.Errorf("round trip mismatch:\n got: %x\nwant: %x", encoded, data)
+	}
+}
+
+func TestParseDevicePathErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "truncated header", data: "0401"},
+		{name: "length shorter than header", data: "04010200"},
+		{name: "length beyond data", data: "04012a000100"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if path, err := ParseDevicePath(mustDecodeHex(t, testCase.data)); err == nil {
+				t.Errorf("expected an error, got %s", path)
+			}
+		})
+	}
+}
+
+func TestDevicePathInstances(t *testing.T) {
+
+	// Device path instances are separated by commas, and the end of the device path is not rendered
+	path := DevicePath{
+		&FilePathNode{Path: `\EFI\a.efi`},
+		&EndNode{SubType: END_INSTANCE_DEVICE_PATH},
+		&FilePathNode{Path: `\EFI\b.efi`},
+		&EndNode{SubType: END_ENTIRE_DEVICE_PATH},
+	}
+	if text, expected := path.String(), `File(\EFI\a.efi),File(\EFI\b.efi)`; text != expected {
+		t.Errorf("text mismatch: got %s, expected %s", text, expected)
+	}
+
+	parsed, err := ParseDevicePath(path.Bytes())
+	if err != nil {
+		t.Fatalf("failed to parse encoded device path: %v", err)
+	}
+	if parsed.String() != path.String() {
+		t.Errorf("round trip mismatch: got %s, expected %s", parsed, path)
+	}
+}
+
+func TestDecodedNodeFields(t *testing.T) {
+	ubuntu, _ := mustParseTestDevicePath(t, loadOptionTestCases[0])
+	entry := &BootEntry{DevicePath: ubuntu}
+	hardDrive := entry.HardDrive()
+	if hardDrive == nil {
+		t.Fatalf("expected a hard drive node in %s", ubuntu)
+	}
+	if guid, ok := hardDrive.PartitionGUID(); !ok || guid.String() != "5d8f5a3e-5f3c-4c6b-9d2e-1f0e6b4a7c21" {
+		t.Errorf("partition GUID: got %s (%v)", guid, ok)
+	}
+	if loader := entry.LoaderPath(); loader != `\EFI\ubuntu\shimx64.efi` {
+		t.Errorf("loader path: got %s", loader)
+	}
+
+	pxe, _ := mustParseTestDevicePath(t, loadOptionTestCases[5])
+	mac, ok := pxe[2].(*MACNode)
+	if !ok {
+		t.Fatalf("expected a MAC node, got %T", pxe[2])
+	}
+	if address := mac.HardwareAddress().String(); address != "52:54:00:12:34:56" {
+		t.Errorf("MAC address: got %s", address)
+	}
+	if _, ok := pxe[3].(*IPv4Node); !ok {
+		t.Errorf("expected an IPv4 node, got %T", pxe[3])
+	}
+}
@@ -0,0 +1,210 @@
+package uefi
+
+import "bytes"
+
+// Describes the differences between two snapshots of the UEFI boot configuration
+// (BootNext is deliberately ignored, since the firmware clears it during every boot that follows it being set)
+type BackupDiff struct {
+
+	// The boot entries that are present in the new snapshot but not the old snapshot
+	Added []DiffEntry `json:"added"`
+
+	// The boot entries that are present in the old snapshot but not the new snapshot
+	Removed []DiffEntry `json:"removed"`
+
+	// The boot entries that are present in both snapshots with identical load options but different identifiers
+	Moved []MovedEntry `json:"moved"`
+
+	// The boot entries whose descriptions have changed
+	Renamed []RenamedEntry `json:"renamed"`
+
+	// The boot entries whose device paths have changed
+	DevicePathChanged []DevicePathChange `json:"devicePathChanged"`
+
+	// The boot entries whose positions in BootOrder have changed
+	Reordered []ReorderedEntry `json:"reordered"`
+
+	// The old and new values of BootOrder, or nil if it has not changed
+	BootOrder *BootOrderChange `json:"bootOrder"`
+
+	// The old and new values of Timeout, or nil if it has not changed
+	Timeout *TimeoutChange `json:"timeout"`
+}
+
+// Identifies a boot entry that has been added or removed
+type DiffEntry struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	DevicePath  string `json:"devicePath,omitempty"`
+}
+
+// Describes a boot entry whose load option has moved to a different identifier
+type MovedEntry struct {
+	OldID       string `json:"oldId"`
+	NewID       string `json:"newId"`
+	Description string `json:"description"`
+}
+
+// Describes a boot entry whose description has changed
+type RenamedEntry struct {
+	ID             string `json:"id"`
+	OldDescription string `json:"oldDescription"`
+	NewDescription string `json:"newDescription"`
+}
+
+// Describes a boot entry whose device path has changed
+type DevicePathChange struct {
+	ID            string `json:"id"`
+	Description   string `json:"description"`
+	OldDevicePath string `json:"oldDevicePath"`
+	NewDevicePath string `json:"newDevicePath"`
+}
+
+// Describes a boot entry whose position in BootOrder has changed
+// (Positions are one-based, and zero indicates that the boot entry is not present in BootOrder)
+type ReorderedEntry struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	OldPosition int    `json:"oldPosition"`
+	NewPosition int    `json:"newPosition"`
+}
+
+// Describes a change to the BootOrder variable
+type BootOrderChange struct {
+	Old []string `json:"old"`
+	New []string `json:"new"`
+}
+
+// Describes a change to the Timeout variable
+type TimeoutChange struct {
+	Old *uint16 `json:"old"`
+	New *uint16 `json:"new"`
+}
+
+// Compares two snapshots of the UEFI boot configuration, matching boot entries by their identifiers
+// (Boot entries that are only present in one snapshot are then matched by their raw load options, so that a boot entry
+// that has been renumbered is reported as moved rather than as being removed and added)
+func DiffBackups(before *Backup, after *Backup) *BackupDiff {
+	diff := &BackupDiff{
+		Added:             []DiffEntry{},
+		Removed:           []DiffEntry{},
+		Moved:             []MovedEntry{},
+		Renamed:           []RenamedEntry{},
+		DevicePathChanged: []DevicePathChange{},
+		Reordered:         []ReorderedEntry{},
+	}
+
+	// Index the boot entries in each snapshot by their identifiers
+	oldEntries := map[string]BackupEntry{}
+	for _, entry := range before.Entries {
+		oldEntries[entry.ID] = entry
+	}
+	newEntries := map[string]BackupEntry{}
+	for _, entry := range after.Entries {
+		newEntries[entry.ID] = entry
+	}
+
+	// Identify the boot entries that are only present in the old snapshot
+	removed := []BackupEntry{}
+	for _, entry := range before.Entries {
+		if _, exists := newEntries[entry.ID]; !exists {
+			removed = append(removed, entry)
+		}
+	}
+
+	// Identify the boot entries that are only present in the new snapshot, along with boot entries whose descriptions or
+	// device paths have changed
+	added := []BackupEntry{}
+	for _, entry := range after.Entries {
+		previous, exists := oldEntries[entry.ID]
+		if !exists {
+			added = append(added, entry)
+			continue
+		}
+
+		if previous.Description != entry.Description {
+			diff.Renamed = append(diff.Renamed, RenamedEntry{
+				ID:             entry.ID,
+				OldDescription: previous.Description,
+				NewDescription: entry.Description,
+			})
+		}
+
+		// Compare the raw device path lists, since the text representations may be identical for distinct device paths
+		previousOption, previousErr := ParseLoadOption(previous.Raw)
+		option, err := ParseLoadOption(entry.Raw)
+		if previousErr != nil || err != nil || !bytes.Equal(previousOption.FilePathList, option.FilePathList) {
+			diff.DevicePathChanged = append(diff.DevicePathChanged, DevicePathChange{
+				ID:            entry.ID,
+				Description:   entry.Description,
+				OldDevicePath: previous.DevicePathText,
+				NewDevicePath: entry.DevicePathText,
+			})
+		}
+	}
+
+	// Pair each boot entry that is only present in the old snapshot with the first unpaired boot entry in the new
+	// snapshot that has an identical load option, treating any that remain unpaired as removed or added
+	paired := map[int]bool{}
+	for _, entry := range removed {
+		match := -1
+		for index, candidate := range added {
+			if !paired[index] && bytes.Equal(entry.Raw, candidate.Raw) {
+				match = index
+				break
+			}
+		}
+		if match == -1 {
+			diff.Removed = append(diff.Removed, DiffEntry{ID: entry.ID, Description: entry.Description, DevicePath: entry.DevicePathText})
+			continue
+		}
+		paired[match] = true
+		diff.Moved = append(diff.Moved, MovedEntry{OldID: entry.ID, NewID: added[match].ID, Description: added[match].Description})
+	}
+	for index, entry := range added {
+		if !paired[index] {
+			diff.Added = append(diff.Added, DiffEntry{ID: entry.ID, Description: entry.Description, DevicePath: entry.DevicePathText})
+		}
+	}
+
+	// Identify changes to the boot order, along with the individual boot entries whose positions have changed
+	if !EqualOrders(before.BootOrder, after.BootOrder) {
+		diff.BootOrder = &BootOrderChange{Old: before.BootOrder, New: after.BootOrder}
+		for _, id := range DedupeOrder(append(append([]string{}, after.BootOrder...), before.BootOrder...)) {
+			oldPosition, newPosition := IndexInOrder(before.BootOrder, id)+1, IndexInOrder(after.BootOrder, id)+1
+			if oldPosition != newPosition {
+				description := ""
+				if entry, exists := newEntries[id]; exists {
+					description = entry.Description
+				} else if entry, exists := oldEntries[id]; exists {
+					description = entry.Description
+				}
+				diff.Reordered = append(diff.Reordered, ReorderedEntry{
+					ID:          id,
+					Description: description,
+					OldPosition: oldPosition,
+					NewPosition: newPosition,
+				})
+			}
+		}
+	}
+
+	// Identify changes to the timeout
+	if (before.Timeout == nil) != (after.Timeout == nil) || (before.Timeout != nil && *before.Timeout != *after.Timeout) {
+		diff.Timeout = &TimeoutChange{Old: before.Timeout, New: after.Timeout}
+	}
+
+	return diff
+}
+
+// Determines whether the snapshots differ
+func (d *BackupDiff) HasChanges() bool {
+	return len(d.Added) > 0 ||
+		len(d.Removed) > 0 ||
+		len(d.Moved) > 0 ||
+		len(d.Renamed) > 0 ||
+		len(d.DevicePathChanged) > 0 ||
+		len(d.Reordered) > 0 ||
+		d.BootOrder != nil ||
+		d.Timeout != nil
+}
@@ -0,0 +1,101 @@
+package uefi
+
+import (
+	"strings"
+	"testing"
+)
+
+// Creates a backup entry from the load option of a test case, using the specified identifier
+func testBackupEntry(t *testing.T, testCase loadOptionTestCase, id string) BackupEntry {
+	t.Helper()
+	raw := mustDecodeHex(t, testCase.data)
+	option, err := ParseLoadOption(raw)
+	if err != nil {
+		t.Fatalf("failed to parse load option: %v", err)
+	}
+	return BackupEntry{ID: id, Raw: raw, Description: option.Description, DevicePathText: testCase.devicePath}
+}
+
+func TestDiffBackupsMatchesMovedEntries(t *testing.T) {
+	ubuntu, windows, fedora := loadOptionTestCases[0], loadOptionTestCases[1], loadOptionTestCases[2]
+
+	testCases := []struct {
+		name    string
+		before  []BackupEntry
+		after   []BackupEntry
+		added   []string
+		removed []string
+		moved   []string
+	}{
+		{
+			name:   "unchanged",
+			before: []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, windows, "0001")},
+			after:  []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, windows, "0001")},
+		},
+		{
+			name:    "added and removed",
+			before:  []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, windows, "0001")},
+			after:   []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, fedora, "0002")},
+			added:   []string{"0002"},
+			removed: []string{"0001"},
+		},
+		{
+			name:   "renumbered",
+			before: []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, windows, "0001")},
+			after:  []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, windows, "0004")},
+			moved:  []string{"0001->0004"},
+		},
+		{
+			name:   "swapped",
+			before: []BackupEntry{testBackupEntry(t, ubuntu, "0000"), testBackupEntry(t, windows, "0001")},
+			after:  []BackupEntry{testBackupEntry(t, windows, "0002"), testBackupEntry(t, ubuntu, "0003")},
+			moved:  []string{"0000->0003", "0001->0002"},
+		},
+		{
+			name:   "renumbered alongside an unrelated addition",
+			before: []BackupEntry{testBackupEntry(t, windows, "0001")},
+			after:  []BackupEntry{testBackupEntry(t, fedora, "0002"), testBackupEntry(t, windows, "0003")},
+			added:  []string{"0002"},
+			moved:  []string{"0001->0003"},
+		},
+		{
+			name:   "duplicates are only paired once",
+			before: []BackupEntry{testBackupEntry(t, windows, "0001")},
+			after:  []BackupEntry{testBackupEntry(t, windows, "0002"), testBackupEntry(t, windows, "0003")},
+			added:  []string{"0003"},
+			moved:  []string{"0001->0002"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			diff := DiffBackups(&Backup{Entries: testCase.before}, &Backup{Entries: testCase.after})
+
+			added := []string{}
+			for _, entry := range diff.Added {
+				added = append(added, entry.ID)
+			}
+			removed := []string{}
+			for _, entry := range diff.Removed {
+				removed = append(removed, entry.ID)
+			}
+			moved := []string{}
+			for _, entry := range diff.Moved {
+				moved = append(moved, entry.OldID+"->"+entry.NewID)
+			}
+
+			if strings.Join(added, ",") != strings.Join(testCase.added, ",") {
+				t.Errorf("added: got %v, expected %v", added, testCase.added)
+			}
+			if strings.Join(removed, ",") != strings.Join(testCase.removed, ",") {
+				t.Errorf("removed: got %v, expected %v", removed, testCase.removed)
+			}
+			if strings.Join(moved, ",") != strings.Join(testCase.moved, ",") {
+				t.Errorf("moved: got %v, expected %v", moved, testCase.moved)
+			}
+			if diff.HasChanges() != (len(testCase.added)+len(testCase.removed)+len(testCase.moved) > 0) {
+				t.Errorf("got HasChanges() %v for %d added, %d removed and %d moved", diff.HasChanges(), len(added), len(removed), len(moved))
+			}
+		})
+	}
+}
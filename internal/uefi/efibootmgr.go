@@ -0,0 +1,198 @@
+package uefi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Represents the parsed output of the `efibootmgr` command (with or without the `-v` flag)
+type EfibootmgrOutput struct {
+
+	// The value of the BootCurrent variable, or an empty string if it was not reported
+	BootCurrent string
+
+	// The value of the BootNext variable, or an empty string if it is not set
+	BootNext string
+
+	// The list of boot entry identifiers from the BootOrder variable
+	BootOrder []string
+
+	// The boot manager timeout in seconds, or nil if it was not reported
+	Timeout *uint16
+
+	// The boot entries, in the order they were listed
+	Entries []BootEntry
+}
+
+// Compile the regular expressions for parsing the output of `efibootmgr`
+var (
+	efibootmgrHeaderRegex   = regexp.MustCompile(`^(BootCurrent|BootNext|BootOrder|Timeout):\s*(.*?)\s*$`)
+	efibootmgrEntryRegex    = regexp.MustCompile(`^Boot([0-9A-Fa-f]{4})(\*?) *(.*)$`)
+	efibootmgrDumpRegex     = regexp.MustCompile(`^\s+(dp|data):\s*((?:[0-9A-Fa-f]{2}|/|\s)*)$`)
+	efibootmgrTimeout       = regexp.MustCompile(`^([0-9]+)\s+seconds?$`)
+	efibootmgrNodeRegex     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*\(`)
+	efibootmgrBarePathRegex = regexp.MustCompile(`(?i)^\\[^\t]*?\.efi`)
+)
+
+// Parses the output of the `efibootmgr` command
+//
+// This supports the output formats of efibootmgr versions 15 through 18, both with and without the `-v` flag:
+//
+//   - Entries are listed as `Boot####* Description`, where the asterisk indicates that the entry is active
+//   - Verbose output appends the device path to the description, separated by a tab character, and both older and
+//     newer versions append any optional data directly after the device path
+//   - Device paths printed by efivar 38 and newer use bare file paths in place of `File(...)` nodes
+//   - Newer versions of efibootmgr print hexadecimal dumps of the device path and optional data on indented
+//     `dp:` and `data:` lines following each entry when the `-v` flag is specified, with the nodes of the device
+//     path separated by slashes
+func ParseEfibootmgrOutput(output string) (*EfibootmgrOutput, error) {
+	parsed := &EfibootmgrOutput{BootOrder: []string{}, Entries: []BootEntry{}}
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+
+		// Determine whether the line is a header line
+		if groups := efibootmgrHeaderRegex.FindStringSubmatch(line); groups != nil {
+			if err := parsed.parseHeader(groups[1], groups[2]); err != nil {
+				return nil, err
+			}
+
+		} else if groups := efibootmgrEntryRegex.FindStringSubmatch(line); groups != nil {
+
+			// Split the description from the device path column, if present
+			entry := BootEntry{ID: strings.ToUpper(groups[1]), Active: groups[2] == "*"}
+			description, devicePath, hasDevicePath := strings.Cut(groups[3], "\t")
+			entry.Description = strings.TrimRight(description, " ")
+			if hasDevicePath {
+				entry.DevicePathText = parseEfibootmgrDevicePath(strings.TrimSpace(devicePath))
+			}
+			parsed.Entries = append(parsed.Entries, entry)
+
+		} else if groups := efibootmgrDumpRegex.FindStringSubmatch(line); groups != nil && len(parsed.Entries) > 0 {
+
+			// Decode the hexadecimal dump and append it to the data for the most recent entry
+			// (Device path dumps separate the bytes of each node with slashes, which we discard)
+			data, err := hex.DecodeString(strings.Join(strings.Fields(strings.ReplaceAll(groups[2], "/", " ")), ""))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse efibootmgr output line \"%s\": %v", line, err)
+			}
+			entry := &parsed.Entries[len(parsed.Entries)-1]
+			if groups[1] == "dp" {
+				entry.FilePathList = append(entry.FilePathList, data...)
+				if path, err := ParseDevicePath(entry.FilePathList); err == nil {
+					entry.DevicePath = path
+					if entry.DevicePathText == "" {
+						entry.DevicePathText = path.String()
+					}
+				}
+			} else {
+				entry.OptionalData = append(entry.OptionalData, data...)
+			}
+		}
+	}
+
+	// Classify each entry once its device path has been fully parsed
+	for index := range parsed.Entries {
+		parsed.Entries[index].Kind = ClassifyBootEntry(&parsed.Entries[index])
+	}
+
+	return parsed, nil
+}
+
+// Parses the value of an individual header line
+func (o *EfibootmgrOutput) parseHeader(name string, value string) error {
+	switch name {
+	case "BootCurrent":
+		o.BootCurrent = strings.ToUpper(value)
+	case "BootNext":
+		o.BootNext = strings.ToUpper(value)
+	case "BootOrder":
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				o.BootOrder = append(o.BootOrder, strings.ToUpper(id))
+			}
+		}
+	case "Timeout":
+		groups := efibootmgrTimeout.FindStringSubmatch(value)
+		if groups == nil {
+			return fmt.Errorf("failed to parse efibootmgr timeout value \"%s\"", value)
+		}
+		seconds, err := strconv.ParseUint(groups[1], 10, 16)
+		if err != nil {
+			return fmt.Errorf("failed to parse efibootmgr timeout value \"%s\": %v", value, err)
+		}
+		timeout := uint16(seconds)
+		o.Timeout = &timeout
+	}
+
+	return nil
+}
+
+// Extracts the device path text from the device path column of verbose `efibootmgr` output, discarding any trailing
+// optional data, which efibootmgr appends without a separator
+//
+// Versions 38 and newer of the efivar library print file path nodes as bare paths rather than `File(...)` nodes (e.g.
+// `HD(1,GPT,...)/\EFI\fedora\shimx64.efi`), and these are normalised to the `File(...)` form so that the text
+// representation is the same regardless of which version of efivar produced it.
+func parseEfibootmgrDevicePath(column string) string {
+
+	// Consume device path nodes of the form `Name(arguments)` or bare file paths, separated by slashes or commas
+	builder := strings.Builder{}
+	offset := 0
+	for offset < len(column) {
+
+		// Consume a bare file path, which is always the last node in a device path instance
+		// (Any optional data is appended directly after the file name, so we treat the first `.efi` extension as the
+		// end of the path, and treat the remainder of the column as the path if there is no such extension)
+		if column[offset] == '\\' {
+			path := column[offset:]
+			if match := efibootmgrBarePathRegex.FindStringIndex(path); match != nil {
+				path = path[:match[1]]
+			}
+			builder.WriteString(fmt.Sprintf("File(%s)", path))
+			break
+		}
+
+		// Find the start of the next node
+		match := efibootmgrNodeRegex.FindStringIndex(column[offset:])
+		if match == nil {
+			break
+		}
+
+		// Find the closing parenthesis for the node, accounting for any nested parentheses in its arguments
+		depth := 0
+		end := -1
+		for index := offset + match[1] - 1; index < len(column); index++ {
+			if column[index] == '(' {
+				depth++
+			} else if column[index] == ')' {
+				depth--
+				if depth == 0 {
+					end = index + 1
+					break
+				}
+			}
+		}
+		if end == -1 {
+			break
+		}
+		builder.WriteString(column[offset:end])
+
+		// Continue to the next node if a separator follows
+		offset = end
+		if offset < len(column) && (column[offset] == '/' || column[offset] == ',') && startsEfibootmgrNode(column[offset+1:]) {
+			builder.WriteByte(column[offset])
+			offset++
+		} else {
+			break
+		}
+	}
+
+	return builder.String()
+}
+
+// Determines whether the remainder of a device path column starts with a device path node or a bare file path
+func startsEfibootmgrNode(remainder string) bool {
+	return efibootmgrNodeRegex.MatchString(remainder) || strings.HasPrefix(remainder, "\\")
+}
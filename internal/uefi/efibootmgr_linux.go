@@ -0,0 +1,166 @@
+package uefi
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/process"
+)
+
+// Interacts with UEFI boot entries by running the `efibootmgr` command
+type EfibootmgrBackend struct{}
+
+// Returns the name of the backend
+func (b *EfibootmgrBackend) Name() string {
+	return "efibootmgr"
+}
+
+// The `efibootmgr` command always operates on the host system's firmware
+func (b *EfibootmgrBackend) IsVirtual() bool {
+	return false
+}
+
+// Determines whether the operating system has been booted in UEFI mode
+func (b *EfibootmgrBackend) IsUEFIEnabled() (bool, error) {
+	return isUEFIEnabled()
+}
+
+// Returns the list of system tools that we require in order to interact with UEFI NVRAM variables
+func (b *EfibootmgrBackend) RequiredTools() []string {
+	return []string{"efibootmgr"}
+}
+
+// Returns the store that provides raw access to the UEFI variables underlying the backend's boot entries
+// (`efibootmgr` does not expose raw variable data, so we access the variables directly through efivarfs)
+func (b *EfibootmgrBackend) RawVariables() VariableStore {
+	return &Efivarfs{Root: DefaultEfivarfsRoot}
+}
+
+// Lists the UEFI boot entries for the host machine
+func (b *EfibootmgrBackend) ListBootEntries() ([]BootEntry, error) {
+	output, err := b.query()
+	if err != nil {
+		return nil, err
+	}
+	return output.Entries, nil
+}
+
+// Lists the load options of the specified kind
+// (`efibootmgr` only lists boot entries, so other kinds of load options are read directly through efivarfs)
+func (b *EfibootmgrBackend) ListLoadOptions(kind *LoadOptionKind) ([]BootEntry, error) {
+	if kind == BootOptions {
+		return b.ListBootEntries()
+	}
+	return NewEfivarfsBackend(DefaultEfivarfsRoot).ListLoadOptions(kind)
+}
+
+// Retrieves the identifiers of the load options of the specified kind in order of priority
+func (b *EfibootmgrBackend) LoadOptionOrder(kind *LoadOptionKind) ([]string, error) {
+	if kind == BootOptions {
+		status, err := b.GetBootStatus()
+		if err != nil {
+			return nil, err
+		}
+		return status.BootOrder, nil
+	}
+	return NewEfivarfsBackend(DefaultEfivarfsRoot).LoadOptionOrder(kind)
+}
+
+// Retrieves the values of the BootCurrent, BootNext, BootOrder and Timeout variables
+func (b *EfibootmgrBackend) GetBootStatus() (*BootStatus, error) {
+	output, err := b.query()
+	if err != nil {
+		return nil, err
+	}
+
+	return &BootStatus{
+		BootCurrent: output.BootCurrent,
+		BootNext:    output.BootNext,
+		BootOrder:   output.BootOrder,
+		Timeout:     output.Timeout,
+	}, nil
+}
+
+// Sets the value of the BootNext UEFI NVRAM variable
+func (b *EfibootmgrBackend) SetBootNext(entry BootEntry) error {
+	_, err := process.CaptureOutput([]string{"efibootmgr", "--bootnext", entry.ID})
+	return err
+}
+
+// Deletes the BootNext UEFI NVRAM variable, if it is set
+func (b *EfibootmgrBackend) ClearBootNext() error {
+
+	// `efibootmgr --delete-bootnext` fails if the variable is not set, so check its value first
+	output, err := b.query()
+	if err != nil || output.BootNext == "" {
+		return err
+	}
+
+	_, err = process.CaptureOutput([]string{"efibootmgr", "--delete-bootnext"})
+	return err
+}
+
+// Sets the value of the Timeout UEFI NVRAM variable, in seconds
+func (b *EfibootmgrBackend) SetTimeout(seconds uint16) error {
+	_, err := process.CaptureOutput([]string{"efibootmgr", "--timeout", strconv.Itoa(int(seconds))})
+	return err
+}
+
+// Deletes the Timeout UEFI NVRAM variable, if it is set
+func (b *EfibootmgrBackend) ClearTimeout() error {
+
+	// `efibootmgr --delete-timeout` fails if the variable is not set, so check its value first
+	output, err := b.query()
+	if err != nil || output.Timeout == nil {
+		return err
+	}
+
+	_, err = process.CaptureOutput([]string{"efibootmgr", "--delete-timeout"})
+	return err
+}
+
+// Replaces the value of the BootOrder UEFI NVRAM variable with the specified list of boot entry identifiers
+func (b *EfibootmgrBackend) SetBootOrder(ids []string) error {
+
+	// `efibootmgr --bootorder` requires at least one entry, so an empty order deletes the variable instead
+	if len(ids) == 0 {
+		_, err := process.CaptureOutput([]string{"efibootmgr", "--delete-bootorder"})
+		return err
+	}
+
+	_, err := process.CaptureOutput([]string{"efibootmgr", "--bootorder", strings.Join(ids, ",")})
+	return err
+}
+
+// Writes a load option to an unused boot entry without adding it to BootOrder, returning the new boot entry
+// (`efibootmgr --create` requires a block device rather than a device path, so we write the variable directly through efivarfs)
+func (b *EfibootmgrBackend) CreateBootEntry(option *LoadOption) (BootEntry, error) {
+	return NewEfivarfsBackend(DefaultEfivarfsRoot).CreateBootEntry(option)
+}
+
+// Deletes the specified boot entry
+func (b *EfibootmgrBackend) DeleteBootEntry(entry BootEntry) error {
+	_, err := process.CaptureOutput([]string{"efibootmgr", "--bootnum", entry.ID, "--delete-bootnum"})
+	return err
+}
+
+// Determines whether the firmware supports booting into its setup user interface when requested via OsIndications
+// (`efibootmgr` does not expose OsIndications, so we access the variables directly through efivarfs)
+func (b *EfibootmgrBackend) IsFirmwareSetupSupported() (bool, error) {
+	return NewEfivarfsBackend(DefaultEfivarfsRoot).IsFirmwareSetupSupported()
+}
+
+// Sets or clears the request for the firmware to boot into its setup user interface on the next boot
+// (`efibootmgr` does not expose OsIndications, so we access the variables directly through efivarfs)
+func (b *EfibootmgrBackend) SetFirmwareSetup(enabled bool) error {
+	return NewEfivarfsBackend(DefaultEfivarfsRoot).SetFirmwareSetup(enabled)
+}
+
+// Runs `efibootmgr` in verbose mode and parses its output
+func (b *EfibootmgrBackend) query() (*EfibootmgrOutput, error) {
+	output, err := process.CaptureOutput([]string{"efibootmgr", "-v"})
+	if err != nil {
+		return nil, err
+	}
+	return ParseEfibootmgrOutput(output)
+}
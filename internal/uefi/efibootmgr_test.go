@@ -0,0 +1,149 @@
+package uefi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Specifies whether the expected results for the efibootmgr corpus should be regenerated from the parser's output
+var updateGolden = flag.Bool("update", false, "regenerate the expected results for golden-file tests")
+
+// The results that we expect to parse from a file in the efibootmgr corpus
+type efibootmgrGolden struct {
+	BootCurrent string                  `json:"bootCurrent"`
+	BootNext    string                  `json:"bootNext"`
+	BootOrder   []string                `json:"bootOrder"`
+	Timeout     *uint16                 `json:"timeout"`
+	Entries     []efibootmgrGoldenEntry `json:"entries"`
+}
+
+// The values that we expect to parse for an individual boot entry
+type efibootmgrGoldenEntry struct {
+	ID           string `json:"id"`
+	Description  string `json:"description"`
+	Active       bool   `json:"active"`
+	DevicePath   string `json:"devicePath"`
+	Decoded      bool   `json:"decoded"`
+	OptionalData string `json:"optionalData"`
+	Kind         string `json:"kind"`
+}
+
+// Runs the parser over each captured `efibootmgr` output in testdata/efibootmgr and compares the results to the
+// expected results stored alongside it (run `go test -update` to regenerate the expected results)
+func TestParseEfibootmgrOutput(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "efibootmgr", "*.txt"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("failed to find the efibootmgr corpus: %v", err)
+	}
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			contents, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, err := ParseEfibootmgrOutput(string(contents))
+			if err != nil {
+				t.Fatalf("failed to parse efibootmgr output: %v", err)
+			}
+
+			// Convert the parsed output to the golden representation
+			actual := efibootmgrGolden{
+				BootCurrent: output.BootCurrent,
+				BootNext:    output.BootNext,
+				BootOrder:   output.BootOrder,
+				Timeout:     output.Timeout,
+				Entries:     []efibootmgrGoldenEntry{},
+			}
+			for _, entry := range output.Entries {
+				actual.Entries = append(actual.Entries, efibootmgrGoldenEntry{
+					ID:           entry.ID,
+					Description:  entry.Description,
+					Active:       entry.Active,
+					DevicePath:   entry.DevicePathText,
+					Decoded:      entry.DevicePath != nil,
+					OptionalData: hex.EncodeToString(entry.OptionalData),
+					Kind:         entry.Kind,
+				})
+			}
+			encoded, err := json.MarshalIndent(actual, "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded = append(encoded, '\n')
+
+			// Compare the results to the expected results, or regenerate them if requested
+			golden := strings.TrimSuffix(input, ".txt") + ".json"
+			if *updateGolden {
+				if err := os.WriteFile(golden, encoded, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read the expected results: %v", err)
+			}
+			if string(encoded) != string(expected) {
+				t.Errorf("parsed output does not match %s:\n got: %s\nwant: %s", golden, encoded, expected)
+			}
+		})
+	}
+}
+
+func TestParseEfibootmgrDevicePath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		column   string
+		expected string
+	}{
+		{
+			name:     "nodes",
+			column:   `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\fedora\shimx64.efi)`,
+			expected: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\fedora\shimx64.efi)`,
+		},
+		{
+			name:     "trailing text data",
+			column:   `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\BOOT\BOOTX64.EFI)..BO`,
+			expected: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\BOOT\BOOTX64.EFI)`,
+		},
+		{
+			name:     "bare path",
+			column:   `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/\EFI\fedora\shimx64.efi`,
+			expected: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\fedora\shimx64.efi)`,
+		},
+		{
+			name:     "bare path with trailing hex data",
+			column:   `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/\EFI\BOOT\BOOTX64.EFI0000424f`,
+			expected: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\BOOT\BOOTX64.EFI)`,
+		},
+		{
+			name:     "bare path without extension",
+			column:   `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/\vmlinuz`,
+			expected: `HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\vmlinuz)`,
+		},
+		{
+			name:     "bare path only",
+			column:   `\EFI\tools\shellx64.efi`,
+			expected: `File(\EFI\tools\shellx64.efi)`,
+		},
+		{
+			name:     "nested parentheses",
+			column:   `PciRoot(0x0)/Pci(0x1,0x0)/MAC(525400123456,1)/Uri(http://example.com/boot(1).efi)`,
+			expected: `PciRoot(0x0)/Pci(0x1,0x0)/MAC(525400123456,1)/Uri(http://example.com/boot(1).efi)`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if actual := parseEfibootmgrDevicePath(testCase.column); actual != testCase.expected {
+				t.Errorf("got %s, expected %s", actual, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package uefi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// The default mount point for the efivarfs filesystem under Linux
+const DefaultEfivarfsRoot = "/sys/firmware/efi/efivars"
+
+// The filesystem magic number reported by statfs() for efivarfs mounts, from: <linux/magic.h>
+const _EFIVARFS_MAGIC = 0xde5e81e4
+
+// Provides access to UEFI variables through the Linux efivarfs filesystem
+//
+// Each variable is represented by a file named `Name-GUID`, whose contents consist of the variable's 4-byte attribute
+// flags followed by its data. See <https://docs.kernel.org/filesystems/efivarfs.html> for details.
+type Efivarfs struct {
+
+	// The directory containing the variable files
+	// (This is typically the efivarfs mount point, but can also be a fixture directory populated with regular files)
+	Root string
+
+	// Provides access to the inode flags of the variable files, or nil to use the FS_IOC_GETFLAGS and FS_IOC_SETFLAGS ioctls
+	// (Recent kernels mark most variable files as immutable, so the immutable flag must be cleared before writing)
+	Flags InodeFlags
+}
+
+// Creates a native backend that accesses UEFI variables through efivarfs at the specified root directory
+// (Any root other than the default mount point is treated as a fixture directory containing simulated variables)
+func NewEfivarfsBackend(root string) *NativeBackend {
+	return &NativeBackend{
+		name:    "efivarfs",
+		Store:   &Efivarfs{Root: root},
+		Virtual: filepath.Clean(root) != DefaultEfivarfsRoot,
+	}
+}
+
+// Determines whether the efivarfs root directory exists
+func (e *Efivarfs) IsAvailable() (bool, error) {
+	info, err := os.Stat(e.Root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return info.IsDir(), nil
+}
+
+// Lists the names of all variables in the efivarfs root directory
+func (e *Efivarfs) ListVariables() ([]VariableName, error) {
+	files, err := os.ReadDir(e.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the variable name and vendor GUID from each filename, ignoring any files that don't follow the convention
+	names := []VariableName{}
+	for _, file := range files {
+		if name, ok := parseEfivarfsFilename(file.Name()); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// Reads the attributes and data of the specified variable
+func (e *Efivarfs) ReadVariable(name string, guid GUID) (uint32, []byte, error) {
+	contents, err := os.ReadFile(e.variablePath(name, guid))
+	if err != nil {
+		return 0, nil, err
+	} else if len(contents) < 4 {
+		return 0, nil, fmt.Errorf("efivarfs file for UEFI variable %s is truncated", name)
+	}
+
+	return binary.LittleEndian.Uint32(contents[0:4]), contents[4:], nil
+}
+
+// Creates or replaces the specified variable
+func (e *Efivarfs) WriteVariable(name string, guid GUID, attributes uint32, data []byte) error {
+
+	// efivarfs requires that the attribute flags and the data are supplied together in a single write() call
+	contents := make([]byte, 4+len(data))
+	binary.LittleEndian.PutUint32(contents[0:4], attributes)
+	copy(contents[4:], data)
+
+	// efivarfs replaces the variable as a whole with each write, but regular files in a fixture directory need truncating
+	flags := os.O_WRONLY | os.O_CREATE
+	if !e.isEfivarfsMount() {
+		flags |= os.O_TRUNC
+	}
+
+	// Clear the immutable flag for the duration of the write, if it is set
+	path := e.variablePath(name, guid)
+	restore, err := clearImmutable(e.inodeFlags(), path)
+	if err != nil {
+		return err
+	}
+
+	// Write the contents to the variable's file
+	file, err := os.OpenFile(path, flags, 0644)
+	if err == nil {
+		_, err = file.Write(contents)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	// Restore the immutable flag, reporting the failure of the write in preference to the failure to restore the flag
+	if restoreErr := restore(); err == nil {
+		err = restoreErr
+	}
+	return err
+}
+
+// Deletes the specified variable
+func (e *Efivarfs) DeleteVariable(name string, guid GUID) error {
+
+	// Clear the immutable flag, if it is set
+	path := e.variablePath(name, guid)
+	restore, err := clearImmutable(e.inodeFlags(), path)
+	if err != nil {
+		return err
+	}
+
+	// Delete the variable's file, restoring the immutable flag if the file could not be deleted
+	if err := os.Remove(path); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
+// Returns the accessor for the inode flags of the variable files
+func (e *Efivarfs) inodeFlags() InodeFlags {
+	if e.Flags != nil {
+		return e.Flags
+	}
+	return &ioctlInodeFlags{}
+}
+
+// Determines whether the root directory is an efivarfs mount rather than a regular directory
+func (e *Efivarfs) isEfivarfsMount() bool {
+	stat := unix.Statfs_t{}
+	if err := unix.Statfs(e.Root, &stat); err != nil {
+		return false
+	}
+	return uint32(stat.Type) == _EFIVARFS_MAGIC
+}
+
+// Returns the path to the efivarfs file for the specified variable
+func (e *Efivarfs) variablePath(name string, guid GUID) string {
+	return filepath.Join(e.Root, fmt.Sprintf("%s-%s", name, guid.String()))
+}
+
+// Parses a variable name and vendor GUID from an efivarfs filename
+func parseEfivarfsFilename(filename string) (VariableName, bool) {
+
+	// The GUID is always the final 36 characters of the filename, preceded by a hyphen
+	if len(filename) < 38 || filename[len(filename)-37] != '-' {
+		return VariableName{}, false
+	}
+	guid, err := ParseGUID(filename[len(filename)-36:])
+	if err != nil {
+		return VariableName{}, false
+	}
+
+	return VariableName{Name: filename[:len(filename)-37], GUID: guid}, true
+}
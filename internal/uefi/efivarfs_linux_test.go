@@ -0,0 +1,201 @@
+package uefi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// Simulates the FS_IOC_GETFLAGS and FS_IOC_SETFLAGS ioctls, recording each change to the inode flags along with the
+// contents of the file at the time of the change
+type fakeInodeFlags struct {
+	flags   map[string]uint32
+	changes []fakeFlagChange
+	getErr  error
+	setErr  func(path string, flags uint32) error
+}
+
+// Records a single call to SetFlags
+type fakeFlagChange struct {
+	flags    uint32
+	contents string
+}
+
+func (f *fakeInodeFlags) GetFlags(path string) (uint32, error) {
+	if f.getErr != nil {
+		return 0, f.getErr
+	}
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	return f.flags[path], nil
+}
+
+func (f *fakeInodeFlags) SetFlags(path string, flags uint32) error {
+	if f.setErr != nil {
+		if err := f.setErr(path, flags); err != nil {
+			return err
+		}
+	}
+	contents, _ := os.ReadFile(path)
+	f.changes = append(f.changes, fakeFlagChange{flags: flags, contents: string(contents)})
+	f.flags[path] = flags
+	return nil
+}
+
+// The flags of a typical immutable efivarfs file (FS_IMMUTABLE_FL combined with an unrelated flag that must survive)
+const testImmutableFlags = _FS_IMMUTABLE_FL | 0x00080000
+
+// Creates an efivarfs store in a temporary directory containing a single variable, with the specified inode flags
+func newTestEfivarfs(t *testing.T, flags uint32) (*Efivarfs, *fakeInodeFlags, string) {
+	t.Helper()
+	root := t.TempDir()
+	store := &Efivarfs{Root: root, Flags: &fakeInodeFlags{flags: map[string]uint32{}}}
+	path := store.variablePath("Boot0000", EFI_GLOBAL_VARIABLE)
+	if err := os.WriteFile(path, []byte("\x07\x00\x00\x00old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := store.Flags.(*fakeInodeFlags)
+	fake.flags[path] = flags
+	return store, fake, path
+}
+
+func TestWriteVariableClearsAndRestoresImmutableFlag(t *testing.T) {
+	store, fake, path := newTestEfivarfs(t, testImmutableFlags)
+	if err := store.WriteVariable("Boot0000", EFI_GLOBAL_VARIABLE, 7, []byte("new")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	// The flag must be cleared while the file still holds the old data, and restored once it holds the new data
+	expected := []fakeFlagChange{
+		{flags: testImmutableFlags &^ _FS_IMMUTABLE_FL, contents: "\x07\x00\x00\x00old"},
+		{flags: testImmutableFlags, contents: "\x07\x00\x00\x00new"},
+	}
+	if fmt.Sprint(fake.changes) != fmt.Sprint(expected) {
+		t.Errorf("flag changes: got %q, expected %q", fake.changes, expected)
+	}
+	if fake.flags[path] != testImmutableFlags {
+		t.Errorf("final flags: got 0x%x, expected 0x%x", fake.flags[path], testImmutableFlags)
+	}
+}
+
+func TestWriteVariableLeavesMutableFilesAlone(t *testing.T) {
+	store, fake, _ := newTestEfivarfs(t, 0x00080000)
+	if err := store.WriteVariable("Boot0000", EFI_GLOBAL_VARIABLE, 7, []byte("new")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if len(fake.changes) != 0 {
+		t.Errorf("expected no flag changes, got %q", fake.changes)
+	}
+}
+
+func TestWriteVariableCreatesMissingFiles(t *testing.T) {
+	store, fake, _ := newTestEfivarfs(t, testImmutableFlags)
+	if err := store.WriteVariable("Boot0001", EFI_GLOBAL_VARIABLE, 7, []byte("new")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if len(fake.changes) != 0 {
+		t.Errorf("expected no flag changes for a new variable, got %q", fake.changes)
+	}
+}
+
+func TestWriteVariableRestoresImmutableFlagWhenWriteFails(t *testing.T) {
+
+	// Replace the variable's file with a directory so that opening it for writing fails
+	store, fake, path := newTestEfivarfs(t, testImmutableFlags)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.WriteVariable("Boot0000", EFI_GLOBAL_VARIABLE, 7, []byte("new"))
+	if err == nil || strings.Contains(err.Error(), "immutable") {
+		t.Fatalf("expected the write error to be reported, got %v", err)
+	}
+	if len(fake.changes) != 2 || fake.flags[path] != testImmutableFlags {
+		t.Errorf("expected the flag to be cleared and restored, got changes %q and final flags 0x%x", fake.changes, fake.flags[path])
+	}
+}
+
+func TestWriteVariableFailsWhenImmutableFlagCannotBeCleared(t *testing.T) {
+	store, fake, path := newTestEfivarfs(t, testImmutableFlags)
+	fake.setErr = func(string, uint32) error { return unix.EPERM }
+
+	err := store.WriteVariable("Boot0000", EFI_GLOBAL_VARIABLE, 7, []byte("new"))
+	if err == nil || !strings.Contains(err.Error(), "chattr -i") {
+		t.Fatalf("expected an error suggesting chattr, got %v", err)
+	}
+	if contents, _ := os.ReadFile(path); string(contents) != "\x07\x00\x00\x00old" {
+		t.Errorf("expected the variable to be unchanged, got %q", contents)
+	}
+}
+
+func TestWriteVariableReportsFailureToRestoreImmutableFlag(t *testing.T) {
+	store, fake, _ := newTestEfivarfs(t, testImmutableFlags)
+	fake.setErr = func(path string, flags uint32) error {
+		if flags&_FS_IMMUTABLE_FL != 0 {
+			return unix.EPERM
+		}
+		return nil
+	}
+
+	err := store.WriteVariable("Boot0000", EFI_GLOBAL_VARIABLE, 7, []byte("new"))
+	if err == nil || !strings.Contains(err.Error(), "chattr +i") {
+		t.Fatalf("expected an error suggesting chattr, got %v", err)
+	}
+}
+
+func TestWriteVariableIgnoresUnsupportedInodeFlags(t *testing.T) {
+	for _, unsupported := range []error{unix.ENOTTY, unix.EOPNOTSUPP, unix.EINVAL} {
+		t.Run(unsupported.Error(), func(t *testing.T) {
+			store, fake, path := newTestEfivarfs(t, testImmutableFlags)
+			fake.getErr = &fs.PathError{Op: "ioctl", Path: path, Err: unsupported}
+			if err := store.WriteVariable("Boot0000", EFI_GLOBAL_VARIABLE, 7, []byte("new")); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			if len(fake.changes) != 0 {
+				t.Errorf("expected no flag changes, got %q", fake.changes)
+			}
+		})
+	}
+}
+
+func TestDeleteVariableClearsImmutableFlag(t *testing.T) {
+	store, fake, path := newTestEfivarfs(t, testImmutableFlags)
+	if err := store.DeleteVariable("Boot0000", EFI_GLOBAL_VARIABLE); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the variable to be deleted, got %v", err)
+	}
+	if len(fake.changes) != 1 || fake.changes[0].flags != testImmutableFlags&^_FS_IMMUTABLE_FL {
+		t.Errorf("expected the flag to be cleared, got %q", fake.changes)
+	}
+}
+
+func TestDeleteVariableRestoresImmutableFlagWhenDeleteFails(t *testing.T) {
+
+	// Replace the variable's file with a non-empty directory so that removing it fails
+	store, fake, path := newTestEfivarfs(t, testImmutableFlags)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.DeleteVariable("Boot0000", EFI_GLOBAL_VARIABLE); err == nil {
+		t.Fatalf("expected the delete to fail")
+	}
+	if len(fake.changes) != 2 || fake.flags[path] != testImmutableFlags {
+		t.Errorf("expected the flag to be cleared and restored, got changes %q and final flags 0x%x", fake.changes, fake.flags[path])
+	}
+}
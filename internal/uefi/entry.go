@@ -1,5 +1,14 @@
 package uefi
 
+import "regexp"
+
+// Patterns for extracting details from the text representation of device paths, for backends that do not provide the
+// decoded device path (e.g. `efibootmgr`)
+var (
+	textNodeNamePattern   = regexp.MustCompile(`(?:^|/)([A-Za-z0-9]+)\(`)
+	textLoaderPathPattern = regexp.MustCompile(`(?:^|/)File\(([^)]*)\)`)
+)
+
 // Represents an individual UEFI boot entry
 type BootEntry struct {
 
@@ -9,4 +18,99 @@ type BootEntry struct {
 
 	// The human-readable description for the boot entry
 	Description string
+
+	// Specifies whether the boot entry has the LOAD_OPTION_ACTIVE attribute set
+	Active bool
+
+	// The raw bytes of the device path list for the boot entry
+	// (This is only populated when the load option has been read directly from NVRAM)
+	FilePathList []byte
+
+	// The decoded device path list for the boot entry
+	// (This is only populated when the load option has been read directly from NVRAM)
+	DevicePath DevicePath
+
+	// The text representation of the device path list for the boot entry, following the conventions used by
+	// `efibootmgr` (e.g. `HD(1,GPT,...)/File(\EFI\ubuntu\shimx64.efi)`)
+	DevicePathText string
+
+	// Any optional data that the boot entry passes to the loaded image
+	// (This is only populated when the load option has been read directly from NVRAM)
+	OptionalData []byte
+
+	// The kind of boot entry, as identified from its device path and loader path (see `ClassifyBootEntry`)
+	Kind string
+}
+
+// Returns the hard drive node that identifies the GPT partition in the boot entry's device path, or nil if there is none
+func (e *BootEntry) HardDrive() *HardDriveNode {
+	for _, node := range e.DevicePath {
+		if hardDrive, ok := node.(*HardDriveNode); ok && hardDrive.SignatureType == _SIGNATURE_TYPE_GUID {
+			return hardDrive
+		}
+	}
+	return nil
+}
+
+// Returns the path of the file in the boot entry's device path (e.g. `\EFI\ubuntu\shimx64.efi`), or an empty string if
+// the device path does not contain a file path node
+// (The path is extracted from the text representation of the device path if the device path was not decoded)
+func (e *BootEntry) LoaderPath() string {
+	if e.DevicePath == nil {
+		if match := textLoaderPathPattern.FindStringSubmatch(e.DevicePathText); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+
+	for _, node := range e.DevicePath {
+		if file, ok := node.(*FilePathNode); ok {
+			return file.Path
+		}
+	}
+	return ""
+}
+
+// Returns the names of the nodes in the boot entry's device path, using the names from their text representation
+// (e.g. `HD` or `MAC`), or parsing them from the text representation if the device path was not decoded
+func (e *BootEntry) NodeNames() []string {
+	names := []string{}
+	if e.DevicePath == nil {
+		for _, match := range textNodeNamePattern.FindAllStringSubmatch(e.DevicePathText, -1) {
+			names = append(names, match[1])
+		}
+		return names
+	}
+
+	// Identify decoded nodes by their type, since nodes that we do not decode use a generic text representation
+	// (Only the nodes that are used to classify and select boot entries are named)
+	for _, node := range e.DevicePath {
+		switch {
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_USB_DP:
+			names = append(names, "USB")
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_SATA_DP:
+			names = append(names, "Sata")
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_NVME_NAMESPACE_DP:
+			names = append(names, "NVMe")
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_MAC_ADDR_DP:
+			names = append(names, "MAC")
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_IPv4_DP:
+			names = append(names, "IPv4")
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_IPv6_DP:
+			names = append(names, "IPv6")
+		case node.NodeType() == MESSAGING_DEVICE_PATH && node.NodeSubType() == MSG_URI_DP:
+			names = append(names, "Uri")
+		case node.NodeType() == MEDIA_DEVICE_PATH && node.NodeSubType() == MEDIA_HARDDRIVE_DP:
+			names = append(names, "HD")
+		case node.NodeType() == MEDIA_DEVICE_PATH && node.NodeSubType() == MEDIA_CDROM_DP:
+			names = append(names, "CDROM")
+		case node.NodeType() == MEDIA_DEVICE_PATH && node.NodeSubType() == MEDIA_PIWG_FW_FILE_DP:
+			names = append(names, "FvFile")
+		case node.NodeType() == MEDIA_DEVICE_PATH && node.NodeSubType() == MEDIA_PIWG_FW_VOL_DP:
+			names = append(names, "FvVol")
+		case node.NodeType() == BBS_DEVICE_PATH:
+			names = append(names, "BBS")
+		}
+	}
+	return names
 }
@@ -0,0 +1,229 @@
+package uefi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32                             = windows.NewLazySystemDLL("kernel32.dll")
+	ntdll                                = windows.NewLazySystemDLL("ntdll.dll")
+	getFirmwareType                      = kernel32.NewProc("GetFirmwareType")
+	getFirmwareEnvironmentVariableExW    = kernel32.NewProc("GetFirmwareEnvironmentVariableExW")
+	setFirmwareEnvironmentVariableExW    = kernel32.NewProc("SetFirmwareEnvironmentVariableExW")
+	ntEnumerateSystemEnvironmentValuesEx = ntdll.NewProc("NtEnumerateSystemEnvironmentValuesEx")
+)
+
+// Constants from: <https://learn.microsoft.com/en-us/windows/win32/api/winnt/ne-winnt-firmware_type>
+const _FirmwareTypeUefi uint32 = 2
+
+// Information class for `NtEnumerateSystemEnvironmentValuesEx()` that requests variable names only, from:
+// <https://learn.microsoft.com/en-us/windows/win32/api/winternl/nf-winternl-ntenumeratesystemenvironmentvaluesex>
+const _SystemEnvironmentNameInformation uint32 = 1
+
+// The largest variable size that we will attempt to read, which comfortably exceeds the NVRAM capacity of real firmware
+const maxVariableSize = 1024 * 1024
+
+// Provides access to UEFI variables through the Windows firmware environment variable API
+//
+// Note that this requires the SeSystemEnvironmentPrivilege privilege, which is only available to elevated processes.
+type FirmwareStore struct {
+
+	// Ensures we only attempt to enable the SeSystemEnvironmentPrivilege privilege once
+	privilegeOnce sync.Once
+
+	// The error (if any) that occurred when enabling the SeSystemEnvironmentPrivilege privilege
+	privilegeError error
+}
+
+// Creates a native backend that accesses UEFI variables through the Windows firmware environment variable API
+func NewFirmwareBackend() *NativeBackend {
+	return &NativeBackend{name: "firmware", Store: &FirmwareStore{}}
+}
+
+// Determines whether the system firmware is UEFI firmware
+func (s *FirmwareStore) IsAvailable() (bool, error) {
+	var firmwareType uint32
+	if result, _, err := getFirmwareType.Call(uintptr(unsafe.Pointer(&firmwareType))); result == 0 {
+		return false, err
+	}
+
+	return firmwareType == _FirmwareTypeUefi, nil
+}
+
+// Lists the names of all variables in NVRAM
+func (s *FirmwareStore) ListVariables() ([]VariableName, error) {
+	if err := s.enablePrivilege(); err != nil {
+		return nil, err
+	}
+
+	// Query the required buffer size and then retrieve the list of variable names
+	bufferLength := uint32(0)
+	buffer := []byte{}
+	for {
+		var bufferPointer uintptr
+		if len(buffer) > 0 {
+			bufferPointer = uintptr(unsafe.Pointer(&buffer[0]))
+		}
+		status, _, _ := ntEnumerateSystemEnvironmentValuesEx.Call(
+			uintptr(_SystemEnvironmentNameInformation),
+			bufferPointer,
+			uintptr(unsafe.Pointer(&bufferLength)),
+		)
+		if windows.NTStatus(status) == windows.STATUS_BUFFER_TOO_SMALL {
+			buffer = make([]byte, bufferLength)
+			continue
+		} else if status != 0 {
+			return nil, fmt.Errorf("failed to enumerate UEFI variables: %v", windows.NTStatus(status))
+		}
+		break
+	}
+
+	// Parse the list of VARIABLE_NAME structures, each of which consists of an offset to the next entry,
+	// the vendor GUID and the null-terminated UTF-16 variable name
+	names := []VariableName{}
+	for offset := 0; offset+20 <= len(buffer); {
+		next := int(binary.LittleEndian.Uint32(buffer[offset:]))
+		guid := GUID{}
+		copy(guid[:], buffer[offset+4:offset+20])
+		name, _, err := decodeUCS2String(buffer[offset+20:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse UEFI variable name: %v", err)
+		}
+		names = append(names, VariableName{Name: name, GUID: guid})
+
+		if next == 0 {
+			break
+		}
+		offset += next
+	}
+
+	return names, nil
+}
+
+// Reads the attributes and data of the specified variable
+func (s *FirmwareStore) ReadVariable(name string, guid GUID) (uint32, []byte, error) {
+	if err := s.enablePrivilege(); err != nil {
+		return 0, nil, err
+	}
+
+	// Convert the variable name and GUID to UTF-16 strings
+	namePointer, guidPointer, err := firmwareVariableStrings(name, guid)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Retrieve the variable's data, growing the buffer until it is large enough
+	for size := 1024; ; size *= 2 {
+		buffer := make([]byte, size)
+		var attributes uint32
+		result, _, err := getFirmwareEnvironmentVariableExW.Call(
+			uintptr(unsafe.Pointer(namePointer)),
+			uintptr(unsafe.Pointer(guidPointer)),
+			uintptr(unsafe.Pointer(&buffer[0])),
+			uintptr(size),
+			uintptr(unsafe.Pointer(&attributes)),
+		)
+		if result != 0 {
+			return attributes, buffer[:result], nil
+		} else if errors.Is(err, windows.ERROR_ENVVAR_NOT_FOUND) {
+			return 0, nil, fmt.Errorf("UEFI variable %s-%s: %w", name, guid, fs.ErrNotExist)
+		} else if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) || size >= maxVariableSize {
+			return 0, nil, fmt.Errorf("failed to read UEFI variable %s: %v", name, err)
+		}
+	}
+}
+
+// Creates or replaces the specified variable
+func (s *FirmwareStore) WriteVariable(name string, guid GUID, attributes uint32, data []byte) error {
+	if err := s.enablePrivilege(); err != nil {
+		return err
+	}
+
+	// Convert the variable name and GUID to UTF-16 strings
+	namePointer, guidPointer, err := firmwareVariableStrings(name, guid)
+	if err != nil {
+		return err
+	}
+
+	// Write the variable's data
+	var dataPointer uintptr
+	if len(data) > 0 {
+		dataPointer = uintptr(unsafe.Pointer(&data[0]))
+	}
+	result, _, err := setFirmwareEnvironmentVariableExW.Call(
+		uintptr(unsafe.Pointer(namePointer)),
+		uintptr(unsafe.Pointer(guidPointer)),
+		dataPointer,
+		uintptr(len(data)),
+		uintptr(attributes),
+	)
+	if result == 0 {
+		return fmt.Errorf("failed to write UEFI variable %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// Deletes the specified variable
+func (s *FirmwareStore) DeleteVariable(name string, guid GUID) error {
+
+	// Verify that the variable exists, since deleting a non-existent variable does not report a distinct error
+	if _, _, err := s.ReadVariable(name, guid); err != nil {
+		return err
+	}
+
+	// Writing a variable with a size of zero deletes it
+	return s.WriteVariable(name, guid, 0, nil)
+}
+
+// Enables the SeSystemEnvironmentPrivilege privilege for the current process, which is required for accessing UEFI variables
+func (s *FirmwareStore) enablePrivilege() error {
+	s.privilegeOnce.Do(func() {
+
+		// Open the access token for the current process
+		var token windows.Token
+		if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token); err != nil {
+			s.privilegeError = fmt.Errorf("failed to open process token: %v", err)
+			return
+		}
+		defer token.Close()
+
+		// Look up the locally unique identifier for the privilege
+		privileges := windows.Tokenprivileges{PrivilegeCount: 1}
+		privileges.Privileges[0].Attributes = windows.SE_PRIVILEGE_ENABLED
+		name, _ := windows.UTF16PtrFromString("SeSystemEnvironmentPrivilege")
+		if err := windows.LookupPrivilegeValue(nil, name, &privileges.Privileges[0].Luid); err != nil {
+			s.privilegeError = fmt.Errorf("failed to look up SeSystemEnvironmentPrivilege: %v", err)
+			return
+		}
+
+		// Enable the privilege
+		// (Note that this succeeds without enabling anything if the token does not hold the privilege, in which case
+		// subsequent variable accesses will fail with an access denied error)
+		if err := windows.AdjustTokenPrivileges(token, false, &privileges, 0, nil, nil); err != nil {
+			s.privilegeError = fmt.Errorf("failed to enable SeSystemEnvironmentPrivilege: %v", err)
+		}
+	})
+
+	return s.privilegeError
+}
+
+// Converts a variable name and vendor GUID to the UTF-16 strings expected by the firmware environment variable API
+func firmwareVariableStrings(name string, guid GUID) (*uint16, *uint16, error) {
+	namePointer, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	guidPointer, err := windows.UTF16PtrFromString(fmt.Sprintf("{%s}", guid.String()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return namePointer, guidPointer, nil
+}
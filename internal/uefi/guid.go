@@ -0,0 +1,62 @@
+package uefi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Represents a UEFI GUID in its binary (mixed-endian) representation
+type GUID [16]byte
+
+// The vendor GUID for globally-defined UEFI variables such as `BootOrder` and `Boot####`, from:
+// <https://uefi.org/specs/UEFI/2.10/03_Boot_Manager.html#globally-defined-variables>
+var EFI_GLOBAL_VARIABLE = MustParseGUID("8be4df61-93ca-11d2-aa0d-00e098032b8c")
+
+// Parses a GUID from its canonical string representation (e.g. "8be4df61-93ca-11d2-aa0d-00e098032b8c")
+func ParseGUID(s string) (GUID, error) {
+
+	// Verify that the string has the expected layout of hexadecimal groups
+	guid := GUID{}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	groups := strings.Split(s, "-")
+	if len(s) != 36 || len(groups) != 5 || len(groups[0]) != 8 || len(groups[1]) != 4 || len(groups[2]) != 4 || len(groups[3]) != 4 {
+		return guid, fmt.Errorf("invalid GUID \"%s\"", s)
+	}
+
+	// Decode the hexadecimal digits
+	raw, err := hex.DecodeString(strings.Join(groups, ""))
+	if err != nil {
+		return guid, fmt.Errorf("invalid GUID \"%s\": %v", s, err)
+	}
+
+	// The first three groups are stored in little-endian byte order, and the remaining bytes are stored as-is
+	binary.LittleEndian.PutUint32(guid[0:4], binary.BigEndian.Uint32(raw[0:4]))
+	binary.LittleEndian.PutUint16(guid[4:6], binary.BigEndian.Uint16(raw[4:6]))
+	binary.LittleEndian.PutUint16(guid[6:8], binary.BigEndian.Uint16(raw[6:8]))
+	copy(guid[8:], raw[8:])
+	return guid, nil
+}
+
+// Parses a GUID from its canonical string representation and panics if it is invalid
+// (This should only be used for GUID constants that are known to be valid)
+func MustParseGUID(s string) GUID {
+	guid, err := ParseGUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return guid
+}
+
+// Returns the canonical lowercase string representation of the GUID
+func (g GUID) String() string {
+	return fmt.Sprintf(
+		"%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8:10],
+		g[10:16],
+	)
+}
@@ -0,0 +1,83 @@
+package uefi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// The inode flag that prevents a file from being modified or deleted, from: <linux/fs.h>
+const _FS_IMMUTABLE_FL uint32 = 0x00000010
+
+// Reads and modifies the inode flags of files, as exposed by the FS_IOC_GETFLAGS and FS_IOC_SETFLAGS ioctls
+// (This is abstracted so that the handling of immutable variables can be exercised without real firmware)
+type InodeFlags interface {
+
+	// Retrieves the inode flags of the specified file
+	// (Files that do not exist produce an error that matches `fs.ErrNotExist`)
+	GetFlags(path string) (uint32, error)
+
+	// Replaces the inode flags of the specified file
+	SetFlags(path string, flags uint32) error
+}
+
+// Reads and modifies inode flags using the FS_IOC_GETFLAGS and FS_IOC_SETFLAGS ioctls
+type ioctlInodeFlags struct{}
+
+// Retrieves the inode flags of the specified file
+func (i *ioctlInodeFlags) GetFlags(path string) (uint32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return unix.IoctlGetUint32(int(file.Fd()), unix.FS_IOC_GETFLAGS)
+}
+
+// Replaces the inode flags of the specified file
+func (i *ioctlInodeFlags) SetFlags(path string, flags uint32) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return unix.IoctlSetPointerInt(int(file.Fd()), unix.FS_IOC_SETFLAGS, int(flags))
+}
+
+// Temporarily clears the immutable flag of the specified file, if it is set, returning a function that restores it
+// (Files that do not exist and filesystems that do not support inode flags are treated as mutable)
+func clearImmutable(flags InodeFlags, path string) (func() error, error) {
+	noop := func() error { return nil }
+
+	// Determine whether the file is immutable
+	current, err := flags.GetFlags(path)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL) {
+		return noop, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the inode flags of \"%s\": %v", path, err)
+	} else if current&_FS_IMMUTABLE_FL == 0 {
+		return noop, nil
+	}
+
+	// Clear the immutable flag
+	if err := flags.SetFlags(path, current&^_FS_IMMUTABLE_FL); err != nil {
+		return nil, fmt.Errorf(
+			"\"%s\" is marked immutable and the immutable flag could not be cleared (try running `chattr -i %s`): %v",
+			path, path, err,
+		)
+	}
+
+	// Return a function that restores the original flags
+	return func() error {
+		if err := flags.SetFlags(path, current); err != nil {
+			return fmt.Errorf(
+				"failed to restore the immutable flag of \"%s\" (run `chattr +i %s` to restore it manually): %v",
+				path, path, err,
+			)
+		}
+		return nil
+	}, nil
+}
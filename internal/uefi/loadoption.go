@@ -0,0 +1,103 @@
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Load option attribute flags, from: <https://uefi.org/specs/UEFI/2.10/03_Boot_Manager.html#load-options>
+const (
+	LOAD_OPTION_ACTIVE          uint32 = 0x00000001
+	LOAD_OPTION_FORCE_RECONNECT uint32 = 0x00000002
+	LOAD_OPTION_HIDDEN          uint32 = 0x00000008
+	LOAD_OPTION_CATEGORY        uint32 = 0x00001F00
+	LOAD_OPTION_CATEGORY_BOOT   uint32 = 0x00000000
+	LOAD_OPTION_CATEGORY_APP    uint32 = 0x00000100
+)
+
+// Represents a decoded EFI_LOAD_OPTION structure, as stored in `Boot####` variables
+type LoadOption struct {
+
+	// The attribute flags for the load option (see the `LOAD_OPTION_*` constants)
+	Attributes uint32
+
+	// The human-readable description for the load option
+	Description string
+
+	// The raw bytes of the device path list that identifies the load option's target
+	FilePathList []byte
+
+	// Any additional data that is passed to the loaded image
+	OptionalData []byte
+}
+
+// Parses an EFI_LOAD_OPTION structure from the raw data of a load option variable
+func ParseLoadOption(data []byte) (*LoadOption, error) {
+
+	// Verify that the data is large enough to hold the fixed-size header fields
+	if len(data) < 6 {
+		return nil, fmt.Errorf("load option is truncated (%d bytes)", len(data))
+	}
+
+	// Parse the fixed-size header fields
+	option := &LoadOption{Attributes: binary.LittleEndian.Uint32(data[0:4])}
+	filePathListLength := int(binary.LittleEndian.Uint16(data[4:6]))
+
+	// Parse the null-terminated UCS-2 description
+	description, consumed, err := decodeUCS2String(data[6:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse load option description: %v", err)
+	}
+	option.Description = description
+
+	// Extract the device path list and any optional data that follows it
+	offset := 6 + consumed
+	if offset+filePathListLength > len(data) {
+		return nil, fmt.Errorf("load option device path list length %d exceeds the available data", filePathListLength)
+	}
+	option.FilePathList = append([]byte{}, data[offset:offset+filePathListLength]...)
+	option.OptionalData = append([]byte{}, data[offset+filePathListLength:]...)
+	return option, nil
+}
+
+// Determines whether the load option has the LOAD_OPTION_ACTIVE attribute set
+func (o *LoadOption) Active() bool {
+	return o.Attributes&LOAD_OPTION_ACTIVE != 0
+}
+
+// Serialises the load option to an EFI_LOAD_OPTION structure suitable for writing to a load option variable
+func (o *LoadOption) Bytes() []byte {
+	buffer := &bytes.Buffer{}
+	binary.Write(buffer, binary.LittleEndian, o.Attributes)
+	binary.Write(buffer, binary.LittleEndian, uint16(len(o.FilePathList)))
+	buffer.Write(encodeUCS2String(o.Description))
+	buffer.Write(o.FilePathList)
+	buffer.Write(o.OptionalData)
+	return buffer.Bytes()
+}
+
+// Decodes a null-terminated UCS-2 string, returning the string and the number of bytes consumed (including the terminator)
+func decodeUCS2String(data []byte) (string, int, error) {
+	units := []uint16{}
+	for offset := 0; offset+1 < len(data); offset += 2 {
+		unit := binary.LittleEndian.Uint16(data[offset : offset+2])
+		if unit == 0 {
+			return string(utf16.Decode(units)), offset + 2, nil
+		}
+		units = append(units, unit)
+	}
+
+	return "", 0, fmt.Errorf("string is not null-terminated")
+}
+
+// Encodes a string as a null-terminated UCS-2 string
+func encodeUCS2String(s string) []byte {
+	units := append(utf16.Encode([]rune(s)), 0)
+	encoded := make([]byte, len(units)*2)
+	for index, unit := range units {
+		binary.LittleEndian.PutUint16(encoded[index*2:], unit)
+	}
+	return encoded
+}
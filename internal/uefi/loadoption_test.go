@@ -0,0 +1,217 @@
+package uefi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Describes the data of a `Boot####` variable (excluding the attributes that precede it in efivarfs) and the values
+// that we expect to decode from it
+type loadOptionTestCase struct {
+	name         string
+	data         string
+	attributes   uint32
+	description  string
+	devicePath   string
+	optionalData int
+}
+
+// Load options covering each kind of device path node that we decode, along with nodes that we do not
+var loadOptionTestCases = []loadOptionTestCase{
+	{
+		// An Ubuntu entry created by shim on a GPT disk
+		name:         "Boot0000",
+		data:         "0100000062007500620075006e0074007500000004012a0001000000000800000000000000001000000000003e5a8f5d3c5f6b4c9d2e1f0e6b4a7c210202040434005c004500460049005c007500620075006e00740075005c007300680069006d007800360034002e0065006600690000007fff0400",
+		attributes:   0x1,
+		description:  "ubuntu",
+		devicePath:   `HD(1,GPT,5d8f5a3e-5f3c-4c6b-9d2e-1f0e6b4a7c21,0x800,0x100000)/File(\EFI\ubuntu\shimx64.efi)`,
+		optionalData: 0,
+	},
+	{
+		// The Windows Boot Manager, whose optional data holds the BCD object identifier
+		name:         "Boot0001",
+		data:         "010000007400570069006e0064006f0077007300200042006f006f00740020004d0061006e006100670065007200000004012a0002000000000810000000000000200300000000007a4f3e0b1d2c5f4e8a9b6c7d8e9f0a1b0202040446005c004500460049005c004d006900630072006f0073006f00660074005c0042006f006f0074005c0062006f006f0074006d006700660077002e0065006600690000007fff040057494e444f5753000100000088000000780000004200430044004f0042004a004500430054003d007b00390064006500610038003600320063002d0035006300640064002d0034006500370030002d0061006300630031002d006600330032006200330034003400640034003700390035007d000000",
+		attributes:   0x1,
+		description:  "Windows Boot Manager",
+		devicePath:   `HD(2,GPT,0b3e4f7a-2c1d-4e5f-8a9b-6c7d8e9f0a1b,0x100800,0x32000)/File(\EFI\Microsoft\Boot\bootmgfw.efi)`,
+		optionalData: 118,
+	},
+	{
+		// A Fedora entry created by the firmware with the full hardware path to an NVMe disk
+		name:         "Boot0002",
+		data:         "010000008a004600650064006f0072006100000002010c00d041030a0000000001010600001d01010600000003171000010000000025385b71b0254c04012a0001000000000800000000000000c01200000000002a0c1f7e3b8d6e4f9a5c2b4d6f8e0a130202040434005c004500460049005c006600650064006f00720061005c007300680069006d007800360034002e0065006600690000007fff0400",
+		attributes:   0x1,
+		description:  "Fedora",
+		devicePath:   `PciRoot(0x0)/Pci(0x1d,0x0)/Pci(0x0,0x0)/NVMe(0x1,00-25-38-5B-71-B0-25-4C)/HD(1,GPT,7e1f0c2a-8d3b-4f6e-9a5c-2b4d6f8e0a13,0x800,0x12c000)/File(\EFI\fedora\shimx64.efi)`,
+		optionalData: 0,
+	},
+	{
+		// A USB flash drive with an MBR partition table
+		name:         "Boot0003",
+		data:         "01000000460055004500460049003a002000530061006e004400690073006b0020004300720075007a0065007200200042006c00610064006500200031002e0030003000000002010c00d041030a0000000001010600001403050600030004012a0001000000000800000000000000f0d501000000003b2a1f4e00000000000000000000000001017fff04002e3d7ef5a1b9c04d9e8b1a2c3d4e5f60",
+		attributes:   0x1,
+		description:  "UEFI: SanDisk Cruzer Blade 1.00",
+		devicePath:   `PciRoot(0x0)/Pci(0x14,0x0)/USB(3,0)/HD(1,MBR,0x4e1f2a3b,0x800,0x1d5f000)`,
+		optionalData: 16,
+	},
+	{
+		// A SATA disk whose entry was created by the firmware without a partition
+		name:         "Boot0004",
+		data:         "01000000200055004500460049003a0020005700440043002000570044003100300045005a0045005800000002010c00d041030a0000000001010600001703120a000000ffff00007fff0400",
+		attributes:   0x1,
+		description:  "UEFI: WDC WD10EZEX",
+		devicePath:   `PciRoot(0x0)/Pci(0x17,0x0)/Sata(0,65535,0)`,
+		optionalData: 0,
+	},
+	{
+		// An IPv4 PXE boot entry created by OVMF
+		name:         "Boot0005",
+		data:         "0100000056005500450046004900200050005800450076003400200028004d00410043003a003500320035003400300030003100320033003400350036002900000002010c00d041030a00000000010106000003030b2500525400123456000000000000000000000000000000000000000000000000000001030c1b0000000000000000000000000000000000000000000000007fff04004eac0881119f594d850ee21a522c59b2",
+		attributes:   0x1,
+		description:  "UEFI PXEv4 (MAC:525400123456)",
+		devicePath:   `PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)/IPv4(0.0.0.0,0x0,DHCP,0.0.0.0,0.0.0.0,0.0.0.0)`,
+		optionalData: 16,
+	},
+	{
+		// An IPv6 PXE boot entry created by OVMF
+		name:         "Boot0006",
+		data:         "0100000077005500450046004900200050005800450076003600200028004d00410043003a003500320035003400300030003100320033003400350036002900000002010c00d041030a00000000010106000003030b2500525400123456000000000000000000000000000000000000000000000000000001030d3c0000000000000000000000000000000000000000000000000000000000000000000000000000000040000000000000000000000000000000007fff04004eac0881119f594d850ee21a522c59b2",
+		attributes:   0x1,
+		description:  "UEFI PXEv6 (MAC:525400123456)",
+		devicePath:   `PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)/IPv6(::,0x0,Static,::,::,64)`,
+		optionalData: 16,
+	},
+	{
+		// An HTTP boot entry created by OVMF, with an empty URI that is filled in by DHCP
+		name:         "Boot0007",
+		data:         "010000005a0055004500460049002000480054005400500076003400200028004d00410043003a003500320035003400300030003100320033003400350036002900000002010c00d041030a00000000010106000003030b2500525400123456000000000000000000000000000000000000000000000000000001030c1b000000000000000000000000000000000000000000000000031804007fff04004eac0881119f594d850ee21a522c59b2",
+		attributes:   0x1,
+		description:  "UEFI HTTPv4 (MAC:525400123456)",
+		devicePath:   `PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)/IPv4(0.0.0.0,0x0,DHCP,0.0.0.0,0.0.0.0,0.0.0.0)/Uri()`,
+		optionalData: 16,
+	},
+	{
+		// A vendor-defined hardware device on an ARM virtual machine
+		name:         "Boot0008",
+		data:         "010000001900550045004600490020004d0069007300630020004400650076006900630065000000010415007e4ce3930eb5df1192232443dfd72085007fff0400",
+		attributes:   0x1,
+		description:  "UEFI Misc Device",
+		devicePath:   `VenHw(93e34c7e-b50e-11df-9223-2443dfd72085,00)`,
+		optionalData: 0,
+	},
+	{
+		// The UEFI shell built into the firmware
+		name:         "Boot0009",
+		data:         "010000002c00450046004900200049006e007400650072006e0061006c0020005300680065006c006c00000004071400c9bdb87cebf8344faaea3ee4af6516a10406140083a5047c3e9e1c4fad65e05268d0b4d17fff0400",
+		attributes:   0x1,
+		description:  "EFI Internal Shell",
+		devicePath:   `FvVol(7cb8bdc9-f8eb-4f34-aaea-3ee4af6516a1)/FvFile(7c04a583-9e3e-4f1c-ad65-e05268d0b4d1)`,
+		optionalData: 0,
+	},
+	{
+		// The firmware setup application, which is hidden and categorised as an application
+		name:         "Boot000A",
+		data:         "090100002c0055006900410070007000000004071400c9bdb87cebf8344faaea3ee4af6516a10406140021aa2c4614760345836e8ab6f46623317fff0400",
+		attributes:   0x109,
+		description:  "UiApp",
+		devicePath:   `FvVol(7cb8bdc9-f8eb-4f34-aaea-3ee4af6516a1)/FvFile(462caa21-7614-4503-836e-8ab6f4662331)`,
+		optionalData: 0,
+	},
+	{
+		// A legacy BIOS boot entry, which uses a BBS node that we do not decode
+		name:         "Boot000B",
+		data:         "010000001800480061007200640020004400720069007600650000000501140002000000535431303030444d303033007fff0400",
+		attributes:   0x1,
+		description:  "Hard Drive",
+		devicePath:   `Path(5,1,02000000535431303030444d30303300)`,
+		optionalData: 0,
+	},
+	{
+		// A DNS device node, which we do not decode
+		name:         "Boot000C",
+		data:         "01000000500044004e005300200062006f006f007400000002010c00d041030a00000000010106000003030b2500525400123456000000000000000000000000000000000000000000000000000001031f150000080808080000000000000000000000007fff0400",
+		attributes:   0x1,
+		description:  "DNS boot",
+		devicePath:   `PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)/Path(3,31,0008080808000000000000000000000000)`,
+		optionalData: 0,
+	},
+}
+
+// Decodes the hexadecimal data for a test case, failing the test if it is invalid
+func mustDecodeHex(t *testing.T, data string) []byte {
+	t.Helper()
+	decoded, err := hex.DecodeString(data)
+	if err != nil {
+		t.Fatalf("invalid hexadecimal test data: %v", err)
+	}
+	return decoded
+}
+
+func TestParseLoadOption(t *testing.T) {
+	for _, testCase := range loadOptionTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data := mustDecodeHex(t, testCase.data)
+			option, err := ParseLoadOption(data)
+			if err != nil {
+				t.Fatalf("failed to parse load option: %v", err)
+			}
+
+			if option.Attributes != testCase.attributes {
+				t.Errorf("attributes: got 0x%x, expected 0x%x", option.Attributes, testCase.attributes)
+			}
+			if option.Active() != (testCase.attributes&LOAD_OPTION_ACTIVE != 0) {
+				t.Errorf("active: got %v for attributes 0x%x", option.Active(), testCase.attributes)
+			}
+			if option.Description != testCase.description {
+				t.Errorf("description: got %q, expected %q", option.Description, testCase.description)
+			}
+			if len(option.OptionalData) != testCase.optionalData {
+				t.Errorf("optional data: got %d bytes, expected %d", len(option.OptionalData), testCase.optionalData)
+			}
+			if encoded := option.Bytes(); !bytes.Equal(encoded, data) {
+				t.Errorf("round trip mismatch:\n got: %x\nwant: %x", encoded, data)
+			}
+		})
+	}
+}
+
+func TestParseLoadOptionErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "truncated header", data: "0100000004"},
+		{name: "unterminated description", data: "0100000000006100620063"},
+		{name: "device path list too long", data: "01000000ff00610000007fff0400"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if option, err := ParseLoadOption(mustDecodeHex(t, testCase.data)); err == nil {
+				t.Errorf("expected an error, got %+v", option)
+			}
+		})
+	}
+}
+
+func TestLoadOptionBytes(t *testing.T) {
+	option := &LoadOption{
+		Attributes:   LOAD_OPTION_ACTIVE,
+		Description:  "Linux Boot Manager",
+		FilePathList: DevicePath{&FilePathNode{Path: `\EFI\systemd\systemd-bootx64.efi`}, &EndNode{SubType: END_ENTIRE_DEVICE_PATH}}.Bytes(),
+		OptionalData: []byte{0xde, 0xad},
+	}
+
+	parsed, err := ParseLoadOption(option.Bytes())
+	if err != nil {
+		t.Fatalf("failed to parse encoded load option: %v", err)
+	}
+	if parsed.Attributes != option.Attributes || parsed.Description != option.Description {
+		t.Errorf("header mismatch: got %+v", parsed)
+	}
+	if !bytes.Equal(parsed.FilePathList, option.FilePathList) || !bytes.Equal(parsed.OptionalData, option.OptionalData) {
+		t.Errorf("payload mismatch: got %x / %x", parsed.FilePathList, parsed.OptionalData)
+	}
+}
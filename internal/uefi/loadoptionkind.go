@@ -0,0 +1,63 @@
+package uefi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Identifies one of the types of load option variables defined by the UEFI specification, from:
+// <https://uefi.org/specs/UEFI/2.10/03_Boot_Manager.html#globally-defined-variables>
+type LoadOptionKind struct {
+
+	// The name used to select the kind on the command line
+	Name string
+
+	// The prefix of the `####` load option variable names (e.g. `Boot` for `Boot####`)
+	Prefix string
+
+	// The name of the variable that lists the load options in order of priority, or an empty string if the load
+	// options are processed in numerical order (as is the case for `PlatformRecovery####`)
+	OrderVariable string
+
+	// Matches the names of the load option variables and captures their four-digit hexadecimal identifiers
+	regex *regexp.Regexp
+}
+
+// Creates a load option kind for variables with the specified prefix
+func newLoadOptionKind(name string, prefix string, orderVariable string) *LoadOptionKind {
+	return &LoadOptionKind{
+		Name:          name,
+		Prefix:        prefix,
+		OrderVariable: orderVariable,
+		regex:         regexp.MustCompile(fmt.Sprintf(`^%s([0-9A-F]{4})$`, prefix)),
+	}
+}
+
+// The supported load option kinds
+var (
+	BootOptions             = newLoadOptionKind("boot", "Boot", "BootOrder")
+	DriverOptions           = newLoadOptionKind("driver", "Driver", "DriverOrder")
+	SysPrepOptions          = newLoadOptionKind("sysprep", "SysPrep", "SysPrepOrder")
+	PlatformRecoveryOptions = newLoadOptionKind("recovery", "PlatformRecovery", "")
+)
+
+// Lists all of the supported load option kinds
+var LoadOptionKinds = []*LoadOptionKind{BootOptions, DriverOptions, SysPrepOptions, PlatformRecoveryOptions}
+
+// Returns the load option kind with the specified name
+func ParseLoadOptionKind(name string) (*LoadOptionKind, error) {
+	names := []string{}
+	for _, kind := range LoadOptionKinds {
+		if strings.EqualFold(kind.Name, name) {
+			return kind, nil
+		}
+		names = append(names, kind.Name)
+	}
+	return nil, fmt.Errorf("unknown load option kind \"%s\" (supported kinds: %s)", name, strings.Join(names, ", "))
+}
+
+// Returns the name of the variable for the load option with the specified identifier (e.g. `Boot0001`)
+func (k *LoadOptionKind) VariableName(id string) string {
+	return k.Prefix + id
+}
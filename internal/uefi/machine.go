@@ -0,0 +1,38 @@
+package uefi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tensorworks/bootnext/internal/pe"
+)
+
+// Returns the PE/COFF machine type of the images that the firmware is able to execute, given the CPU architecture
+// reported by the operating system and the bitness of the firmware
+// (64-bit CPUs can run 32-bit firmware, in which case the firmware only executes 32-bit images)
+func firmwareMachine(cpu string, platformSize int) (uint16, error) {
+	switch strings.ToLower(cpu) {
+	case "x86_64", "amd64":
+		if platformSize == 32 {
+			return pe.IMAGE_FILE_MACHINE_I386, nil
+		}
+		return pe.IMAGE_FILE_MACHINE_AMD64, nil
+	case "i386", "i486", "i586", "i686", "x86":
+		return pe.IMAGE_FILE_MACHINE_I386, nil
+	case "aarch64", "arm64":
+		if platformSize == 32 {
+			return pe.IMAGE_FILE_MACHINE_ARMTHUMB_MIXED, nil
+		}
+		return pe.IMAGE_FILE_MACHINE_ARM64, nil
+	case "arm", "armv7l", "armv7", "armv8l":
+		return pe.IMAGE_FILE_MACHINE_ARMTHUMB_MIXED, nil
+	case "ia64":
+		return pe.IMAGE_FILE_MACHINE_IA64, nil
+	case "riscv64":
+		return pe.IMAGE_FILE_MACHINE_RISCV64, nil
+	case "loongarch64":
+		return pe.IMAGE_FILE_MACHINE_LOONGARCH64, nil
+	default:
+		return 0, fmt.Errorf("unrecognised CPU architecture \"%s\"", cpu)
+	}
+}
@@ -0,0 +1,80 @@
+package uefi
+
+import (
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/pe"
+	"github.com/tensorworks/bootnext/internal/pe/petest"
+)
+
+func TestFirmwareMachine(t *testing.T) {
+	testCases := []struct {
+		cpu          string
+		platformSize int
+		expected     uint16
+		err          bool
+	}{
+		{cpu: "x86_64", platformSize: 64, expected: pe.IMAGE_FILE_MACHINE_AMD64},
+		{cpu: "x86_64", platformSize: 32, expected: pe.IMAGE_FILE_MACHINE_I386},
+		{cpu: "AMD64", platformSize: 0, expected: pe.IMAGE_FILE_MACHINE_AMD64},
+		{cpu: "i686", platformSize: 32, expected: pe.IMAGE_FILE_MACHINE_I386},
+		{cpu: "x86", platformSize: 0, expected: pe.IMAGE_FILE_MACHINE_I386},
+		{cpu: "aarch64", platformSize: 64, expected: pe.IMAGE_FILE_MACHINE_ARM64},
+		{cpu: "aarch64", platformSize: 32, expected: pe.IMAGE_FILE_MACHINE_ARMTHUMB_MIXED},
+		{cpu: "ARM64", platformSize: 0, expected: pe.IMAGE_FILE_MACHINE_ARM64},
+		{cpu: "armv7l", platformSize: 32, expected: pe.IMAGE_FILE_MACHINE_ARMTHUMB_MIXED},
+		{cpu: "riscv64", platformSize: 64, expected: pe.IMAGE_FILE_MACHINE_RISCV64},
+		{cpu: "loongarch64", platformSize: 64, expected: pe.IMAGE_FILE_MACHINE_LOONGARCH64},
+		{cpu: "s390x", platformSize: 0, err: true},
+		{cpu: "", platformSize: 0, err: true},
+	}
+
+	for _, testCase := range testCases {
+		machine, err := firmwareMachine(testCase.cpu, testCase.platformSize)
+		if testCase.err {
+			if err == nil {
+				t.Errorf("%s/%d: got machine 0x%04x, expected an error", testCase.cpu, testCase.platformSize, machine)
+			}
+		} else if err != nil {
+			t.Errorf("%s/%d: got error %v, expected machine 0x%04x", testCase.cpu, testCase.platformSize, err, testCase.expected)
+		} else if machine != testCase.expected {
+			t.Errorf("%s/%d: got machine 0x%04x, expected 0x%04x", testCase.cpu, testCase.platformSize, machine, testCase.expected)
+		}
+	}
+}
+
+func TestFirmwareMachineMatchesImages(t *testing.T) {
+
+	// An image built for each architecture is only accepted by firmware of the same architecture and bitness
+	images := map[string][]byte{
+		"x64":  petest.NewImage(pe.IMAGE_FILE_MACHINE_AMD64, pe.IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("x64 loader")),
+		"ia32": petest.NewImage(pe.IMAGE_FILE_MACHINE_I386, pe.IMAGE_NT_OPTIONAL_HDR32_MAGIC, []byte("ia32 loader")),
+		"aa64": petest.NewImage(pe.IMAGE_FILE_MACHINE_ARM64, pe.IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("aa64 loader")),
+	}
+	firmware := []struct {
+		cpu          string
+		platformSize int
+		accepts      string
+	}{
+		{cpu: "x86_64", platformSize: 64, accepts: "x64"},
+		{cpu: "x86_64", platformSize: 32, accepts: "ia32"},
+		{cpu: "i686", platformSize: 32, accepts: "ia32"},
+		{cpu: "aarch64", platformSize: 64, accepts: "aa64"},
+	}
+
+	for _, testCase := range firmware {
+		machine, err := firmwareMachine(testCase.cpu, testCase.platformSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range images {
+			image, err := pe.Parse(data)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if accepted := image.Machine == machine; accepted != (name == testCase.accepts) {
+				t.Errorf("%s/%d firmware: got accepted %v for %s image, expected %v", testCase.cpu, testCase.platformSize, accepted, name, name == testCase.accepts)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package uefi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// Represents an individual variable held by a `MemoryStore`
+type memoryVariable struct {
+	Attributes uint32
+	Data       []byte
+}
+
+// The JSON representation of an individual variable in a `MemoryStore` file
+type memoryVariableJSON struct {
+	Name       string `json:"name"`
+	GUID       string `json:"guid"`
+	Attributes uint32 `json:"attributes"`
+	Data       []byte `json:"data"`
+}
+
+// The JSON representation of a `MemoryStore` file
+type memoryStoreJSON struct {
+	Variables []memoryVariableJSON `json:"variables"`
+}
+
+// Simulates UEFI NVRAM by holding variables in memory, optionally persisting them to a JSON file
+type MemoryStore struct {
+
+	// The path to the JSON file that the variables are persisted to, or an empty string to keep them in memory only
+	Path string
+
+	// The variables held by the store
+	variables map[VariableName]memoryVariable
+}
+
+// Creates an empty in-memory variable store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{variables: map[VariableName]memoryVariable{}}
+}
+
+// Creates an in-memory variable store that is persisted to the specified JSON file
+// (If the file does not exist then the store starts out empty and the file is created when the first variable is written)
+func LoadMemoryStore(path string) (*MemoryStore, error) {
+
+	// Attempt to read the contents of the file, treating a missing file as an empty store
+	store := NewMemoryStore()
+	store.Path = path
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Parse the JSON and populate the store
+	parsed := memoryStoreJSON{}
+	if err := json.Unmarshal(contents, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse variable store file \"%s\": %v", path, err)
+	}
+	for _, variable := range parsed.Variables {
+		guid, err := ParseGUID(variable.GUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse variable store file \"%s\": %v", path, err)
+		}
+		store.variables[VariableName{Name: variable.Name, GUID: guid}] = memoryVariable{
+			Attributes: variable.Attributes,
+			Data:       variable.Data,
+		}
+	}
+
+	return store, nil
+}
+
+// Creates a native backend that simulates NVRAM in memory, optionally persisting the variables to the specified JSON file
+func NewMemoryBackend(path string) (*NativeBackend, error) {
+	store := NewMemoryStore()
+	if path != "" {
+		loaded, err := LoadMemoryStore(path)
+		if err != nil {
+			return nil, err
+		}
+		store = loaded
+	}
+
+	return &NativeBackend{name: "memory", Store: store, Virtual: true}, nil
+}
+
+// Simulated variables are always available
+func (s *MemoryStore) IsAvailable() (bool, error) {
+	return true, nil
+}
+
+// Lists the names of all variables in the store
+func (s *MemoryStore) ListVariables() ([]VariableName, error) {
+	names := []VariableName{}
+	for name := range s.variables {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Name != names[j].Name {
+			return names[i].Name < names[j].Name
+		}
+		return names[i].GUID.String() < names[j].GUID.String()
+	})
+	return names, nil
+}
+
+// Reads the attributes and data of the specified variable
+func (s *MemoryStore) ReadVariable(name string, guid GUID) (uint32, []byte, error) {
+	variable, exists := s.variables[VariableName{Name: name, GUID: guid}]
+	if !exists {
+		return 0, nil, fmt.Errorf("UEFI variable %s-%s: %w", name, guid, fs.ErrNotExist)
+	}
+
+	return variable.Attributes, append([]byte{}, variable.Data...), nil
+}
+
+// Creates or replaces the specified variable
+func (s *MemoryStore) WriteVariable(name string, guid GUID, attributes uint32, data []byte) error {
+	s.variables[VariableName{Name: name, GUID: guid}] = memoryVariable{
+		Attributes: attributes,
+		Data:       append([]byte{}, data...),
+	}
+	return s.save()
+}
+
+// Deletes the specified variable
+func (s *MemoryStore) DeleteVariable(name string, guid GUID) error {
+	key := VariableName{Name: name, GUID: guid}
+	if _, exists := s.variables[key]; !exists {
+		return fmt.Errorf("UEFI variable %s-%s: %w", name, guid, fs.ErrNotExist)
+	}
+
+	delete(s.variables, key)
+	return s.save()
+}
+
+// Persists the variables to the JSON file, if one was specified
+func (s *MemoryStore) save() error {
+	if s.Path == "" {
+		return nil
+	}
+
+	// Convert the variables to their JSON representation
+	names, _ := s.ListVariables()
+	serialised := memoryStoreJSON{Variables: []memoryVariableJSON{}}
+	for _, name := range names {
+		variable := s.variables[name]
+		serialised.Variables = append(serialised.Variables, memoryVariableJSON{
+			Name:       name.Name,
+			GUID:       name.GUID.String(),
+			Attributes: variable.Attributes,
+			Data:       variable.Data,
+		})
+	}
+
+	// Write the JSON to the file
+	contents, err := json.MarshalIndent(serialised, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, append(contents, '\n'), 0644)
+}
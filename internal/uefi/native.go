@@ -0,0 +1,236 @@
+package uefi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// Interacts with UEFI boot entries by reading and writing NVRAM variables directly, without any external tools
+type NativeBackend struct {
+
+	// The name of the backend, as accepted by `NewBackend()`
+	name string
+
+	// The store that provides access to the underlying UEFI variables
+	Store VariableStore
+
+	// Specifies whether the store holds simulated variables rather than the host system's NVRAM variables
+	Virtual bool
+}
+
+// Returns the name of the backend
+func (b *NativeBackend) Name() string {
+	return b.name
+}
+
+// Determines whether the backend operates on simulated variables rather than the host system's firmware
+func (b *NativeBackend) IsVirtual() bool {
+	return b.Virtual
+}
+
+// Determines whether the operating system has been booted in UEFI mode
+func (b *NativeBackend) IsUEFIEnabled() (bool, error) {
+	return b.Store.IsAvailable()
+}
+
+// Returns the list of system tools that we require in order to interact with UEFI NVRAM variables
+func (b *NativeBackend) RequiredTools() []string {
+	return nil
+}
+
+// Returns the store that provides raw access to the UEFI variables underlying the backend's boot entries
+func (b *NativeBackend) RawVariables() VariableStore {
+	return b.Store
+}
+
+// Lists the UEFI boot entries for the host machine
+func (b *NativeBackend) ListBootEntries() ([]BootEntry, error) {
+	return b.ListLoadOptions(BootOptions)
+}
+
+// Lists the load options of the specified kind (e.g. the `Driver####` variables for driver load options)
+func (b *NativeBackend) ListLoadOptions(kind *LoadOptionKind) ([]BootEntry, error) {
+
+	// Identify the load option variables that are present in the store
+	names, err := b.Store.ListVariables()
+	if err != nil {
+		return nil, err
+	}
+	numbers := []uint16{}
+	for _, name := range names {
+		if groups := kind.regex.FindStringSubmatch(name.Name); groups != nil && name.GUID == EFI_GLOBAL_VARIABLE {
+			number, err := parseOptionNumber(groups[1])
+			if err != nil {
+				return nil, err
+			}
+			numbers = append(numbers, number)
+		}
+	}
+
+	// List the load options in numerical order, which is consistent with the output of `efibootmgr`
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	// Read and decode each load option
+	entries := []BootEntry{}
+	for _, number := range numbers {
+		name := kind.VariableName(formatOptionNumber(number))
+		_, data, err := b.Store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read UEFI variable %s: %v", name, err)
+		}
+		option, err := ParseLoadOption(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse UEFI variable %s: %v", name, err)
+		}
+
+		entries = append(entries, newBootEntry(formatOptionNumber(number), option))
+	}
+
+	return entries, nil
+}
+
+// Creates a boot entry from a decoded load option
+func newBootEntry(id string, option *LoadOption) BootEntry {
+	entry := BootEntry{
+		ID:           id,
+		Description:  option.Description,
+		Active:       option.Active(),
+		FilePathList: option.FilePathList,
+		OptionalData: option.OptionalData,
+	}
+
+	// Decode the device path list, leaving the decoded fields empty if it is malformed
+	if path, err := ParseDevicePath(option.FilePathList); err == nil {
+		entry.DevicePath = path
+		entry.DevicePathText = path.String()
+	}
+
+	entry.Kind = ClassifyBootEntry(&entry)
+	return entry
+}
+
+// Retrieves the list of boot entry numbers from the BootOrder variable
+func (b *NativeBackend) BootOrder() ([]uint16, error) {
+	return readUint16ArrayVariable(b.Store, "BootOrder")
+}
+
+// Retrieves the identifiers of the load options of the specified kind in order of priority
+// (Load options without an order variable are processed in numerical order, so they are all listed in that order)
+func (b *NativeBackend) LoadOptionOrder(kind *LoadOptionKind) ([]string, error) {
+	if kind.OrderVariable == "" {
+		entries, err := b.ListLoadOptions(kind)
+		if err != nil {
+			return nil, err
+		}
+		ids := []string{}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return ids, nil
+	}
+
+	numbers, err := readUint16ArrayVariable(b.Store, kind.OrderVariable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable %s: %v", kind.OrderVariable, err)
+	}
+	ids := []string{}
+	for _, number := range numbers {
+		ids = append(ids, formatOptionNumber(number))
+	}
+	return ids, nil
+}
+
+// Retrieves the value of the BootCurrent variable, reporting whether the variable exists
+func (b *NativeBackend) BootCurrent() (uint16, bool, error) {
+	return readUint16Variable(b.Store, "BootCurrent")
+}
+
+// Retrieves the value of the BootNext variable, reporting whether the variable exists
+func (b *NativeBackend) BootNext() (uint16, bool, error) {
+	return readUint16Variable(b.Store, "BootNext")
+}
+
+// Retrieves the value of the Timeout variable, reporting whether the variable exists
+func (b *NativeBackend) Timeout() (uint16, bool, error) {
+	return readUint16Variable(b.Store, "Timeout")
+}
+
+// Retrieves the values of the BootCurrent, BootNext, BootOrder and Timeout variables
+func (b *NativeBackend) GetBootStatus() (*BootStatus, error) {
+	status := &BootStatus{BootOrder: []string{}}
+
+	// Read the BootCurrent and BootNext variables
+	if current, exists, err := b.BootCurrent(); err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable BootCurrent: %v", err)
+	} else if exists {
+		status.BootCurrent = formatOptionNumber(current)
+	}
+	if next, exists, err := b.BootNext(); err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable BootNext: %v", err)
+	} else if exists {
+		status.BootNext = formatOptionNumber(next)
+	}
+
+	// Read the BootOrder variable
+	order, err := b.BootOrder()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable BootOrder: %v", err)
+	}
+	for _, number := range order {
+		status.BootOrder = append(status.BootOrder, formatOptionNumber(number))
+	}
+
+	// Read the Timeout variable
+	if timeout, exists, err := b.Timeout(); err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable Timeout: %v", err)
+	} else if exists {
+		status.Timeout = &timeout
+	}
+
+	return status, nil
+}
+
+// Sets the value of the BootNext UEFI NVRAM variable
+func (b *NativeBackend) SetBootNext(entry BootEntry) error {
+	number, err := parseOptionNumber(entry.ID)
+	if err != nil {
+		return err
+	}
+	return writeUint16Variable(b.Store, "BootNext", number)
+}
+
+// Deletes the BootNext UEFI NVRAM variable, if it is set
+func (b *NativeBackend) ClearBootNext() error {
+	if err := b.Store.DeleteVariable("BootNext", EFI_GLOBAL_VARIABLE); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Sets the value of the Timeout UEFI NVRAM variable, in seconds
+func (b *NativeBackend) SetTimeout(seconds uint16) error {
+	return writeUint16Variable(b.Store, "Timeout", seconds)
+}
+
+// Deletes the Timeout UEFI NVRAM variable, if it is set
+func (b *NativeBackend) ClearTimeout() error {
+	if err := b.Store.DeleteVariable("Timeout", EFI_GLOBAL_VARIABLE); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Replaces the value of the BootOrder UEFI NVRAM variable with the specified list of boot entry identifiers
+func (b *NativeBackend) SetBootOrder(ids []string) error {
+	numbers := make([]uint16, len(ids))
+	for index, id := range ids {
+		number, err := parseOptionNumber(id)
+		if err != nil {
+			return err
+		}
+		numbers[index] = number
+	}
+	return writeUint16ArrayVariable(b.Store, "BootOrder", numbers)
+}
@@ -0,0 +1,71 @@
+package uefi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Bits of the OsIndications and OsIndicationsSupported variables, from: <https://uefi.org/specs/UEFI/2.10/08_Services_Runtime_Services.html#exchanging-information-between-the-os-and-firmware>
+const (
+	EFI_OS_INDICATIONS_BOOT_TO_FW_UI                   uint64 = 0x0000000000000001
+	EFI_OS_INDICATIONS_TIMESTAMP_REVOCATION            uint64 = 0x0000000000000002
+	EFI_OS_INDICATIONS_FILE_CAPSULE_DELIVERY_SUPPORTED uint64 = 0x0000000000000004
+	EFI_OS_INDICATIONS_FMP_CAPSULE_SUPPORTED           uint64 = 0x0000000000000008
+	EFI_OS_INDICATIONS_CAPSULE_RESULT_VAR_SUPPORTED    uint64 = 0x0000000000000010
+	EFI_OS_INDICATIONS_START_OS_RECOVERY               uint64 = 0x0000000000000020
+	EFI_OS_INDICATIONS_START_PLATFORM_RECOVERY         uint64 = 0x0000000000000040
+	EFI_OS_INDICATIONS_JSON_CONFIG_DATA_REFRESH        uint64 = 0x0000000000000080
+)
+
+// Reads a global variable containing a single UINT64 value, reporting whether the variable exists
+func readUint64Variable(store VariableStore, name string) (uint64, bool, error) {
+	_, data, err := store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	} else if len(data) != 8 {
+		return 0, false, fmt.Errorf("UEFI variable %s has unexpected size %d", name, len(data))
+	}
+
+	return binary.LittleEndian.Uint64(data), true, nil
+}
+
+// Writes a global variable containing a single UINT64 value
+func writeUint64Variable(store VariableStore, name string, value uint64) error {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, value)
+	return store.WriteVariable(name, EFI_GLOBAL_VARIABLE, bootVariableAttributes, data)
+}
+
+// Determines whether the firmware supports booting into its setup user interface when requested via OsIndications
+func (b *NativeBackend) IsFirmwareSetupSupported() (bool, error) {
+	supported, _, err := readUint64Variable(b.Store, "OsIndicationsSupported")
+	if err != nil {
+		return false, fmt.Errorf("failed to read UEFI variable OsIndicationsSupported: %v", err)
+	}
+	return supported&EFI_OS_INDICATIONS_BOOT_TO_FW_UI != 0, nil
+}
+
+// Sets or clears the request for the firmware to boot into its setup user interface on the next boot
+func (b *NativeBackend) SetFirmwareSetup(enabled bool) error {
+
+	// Read the existing indications so that we preserve any other bits that have been set
+	indications, exists, err := readUint64Variable(b.Store, "OsIndications")
+	if err != nil {
+		return fmt.Errorf("failed to read UEFI variable OsIndications: %v", err)
+	}
+
+	// Set or clear the bit, leaving the variable untouched if it already has the requested value
+	updated := indications &^ EFI_OS_INDICATIONS_BOOT_TO_FW_UI
+	if enabled {
+		updated |= EFI_OS_INDICATIONS_BOOT_TO_FW_UI
+	}
+	if updated == indications && (exists || !enabled) {
+		return nil
+	}
+
+	return writeUint64Variable(b.Store, "OsIndications", updated)
+}
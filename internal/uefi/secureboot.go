@@ -0,0 +1,290 @@
+package uefi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/tensorworks/bootnext/internal/pe"
+)
+
+// The vendor GUID for the `db` and `dbx` image security database variables, from:
+// <https://uefi.org/specs/UEFI/2.10/32_Secure_Boot_and_Driver_Signing.html#uefi-image-variable-guid-variable-name>
+var EFI_IMAGE_SECURITY_DATABASE_GUID = MustParseGUID("d719b2cb-3d3a-4596-a3bc-dad00e67656f")
+
+// Signature types for the entries in a signature database, from: <https://uefi.org/specs/UEFI/2.10/32_Secure_Boot_and_Driver_Signing.html#efi-cert-type-sha256-guid>
+var (
+	EFI_CERT_SHA256_GUID      = MustParseGUID("c1c41626-504c-4092-aca9-41f936934328")
+	EFI_CERT_X509_GUID        = MustParseGUID("a5c059a1-94e4-4aa7-87b5-ab155c2bf072")
+	EFI_CERT_X509_SHA256_GUID = MustParseGUID("3bd2a492-96c0-4079-b420-fcf98ef103ed")
+)
+
+// The size of the fixed fields of an EFI_SIGNATURE_LIST structure
+const signatureListHeaderSize = 28
+
+// Represents an individual entry in a signature database, such as a certificate or an image hash
+type SignatureData struct {
+
+	// The signature type of the list that the entry belongs to (e.g. `EFI_CERT_X509_GUID`)
+	Type GUID
+
+	// The GUID identifying the agent that added the entry
+	Owner GUID
+
+	// The certificate or hash held by the entry
+	Data []byte
+}
+
+// Represents the decoded contents of a signature database variable such as `db` or `dbx`
+type SignatureDatabase []SignatureData
+
+// Parses a list of EFI_SIGNATURE_LIST structures, from:
+// <https://uefi.org/specs/UEFI/2.10/32_Secure_Boot_and_Driver_Signing.html#efi-signature-data>
+func ParseSignatureDatabase(data []byte) (SignatureDatabase, error) {
+	database := SignatureDatabase{}
+	for len(data) > 0 {
+
+		// Parse the fixed fields of the signature list
+		if len(data) < signatureListHeaderSize {
+			return nil, errors.New("signature list is truncated")
+		}
+		signatureType := GUID{}
+		copy(signatureType[:], data[0:16])
+		listSize := binary.LittleEndian.Uint32(data[16:])
+		headerSize := binary.LittleEndian.Uint32(data[20:])
+		signatureSize := binary.LittleEndian.Uint32(data[24:])
+		if uint64(listSize) > uint64(len(data)) || uint64(listSize) < signatureListHeaderSize+uint64(headerSize) || signatureSize < 16 {
+			return nil, fmt.Errorf("signature list has invalid sizes (list %d, header %d, signature %d)", listSize, headerSize, signatureSize)
+		}
+
+		// Parse each of the signatures in the list, skipping the type-specific header
+		signatures := data[signatureListHeaderSize+headerSize : listSize]
+		if uint32(len(signatures))%signatureSize != 0 {
+			return nil, fmt.Errorf("signature list size %d is not a multiple of the signature size %d", len(signatures), signatureSize)
+		}
+		for offset := uint32(0); offset < uint32(len(signatures)); offset += signatureSize {
+			entry := SignatureData{Type: signatureType, Data: signatures[offset+16 : offset+signatureSize]}
+			copy(entry.Owner[:], signatures[offset:offset+16])
+			database = append(database, entry)
+		}
+
+		data = data[listSize:]
+	}
+
+	return database, nil
+}
+
+// Determines whether the database contains an entry of the specified type holding the specified data
+func (d SignatureDatabase) Contains(signatureType GUID, data []byte) bool {
+	for _, entry := range d {
+		if entry.Type == signatureType && bytes.Equal(entry.Data, data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the X.509 certificates in the database, ignoring any entries that cannot be parsed
+func (d SignatureDatabase) Certificates() []*x509.Certificate {
+	certificates := []*x509.Certificate{}
+	for _, entry := range d {
+		if entry.Type == EFI_CERT_X509_GUID {
+			if certificate, err := x509.ParseCertificate(entry.Data); err == nil {
+				certificates = append(certificates, certificate)
+			}
+		}
+	}
+	return certificates
+}
+
+// Represents the Secure Boot configuration of the firmware
+type SecureBootState struct {
+
+	// Specifies whether the firmware is enforcing Secure Boot (the value of the `SecureBoot` variable)
+	Enabled bool
+
+	// Specifies whether the platform is in setup mode, in which no platform key is enrolled (the value of the `SetupMode` variable)
+	SetupMode bool
+
+	// The authorised signature database
+	DB SignatureDatabase
+
+	// The forbidden signature database
+	DBX SignatureDatabase
+}
+
+// Reads the `SecureBoot`, `SetupMode`, `db` and `dbx` variables
+// (Missing variables are treated as disabled or empty, since firmware without Secure Boot support does not define them)
+func ReadSecureBootState(store VariableStore) (*SecureBootState, error) {
+	state := &SecureBootState{}
+
+	// Read the SecureBoot and SetupMode variables
+	secureBoot, err := readUint8Variable(store, "SecureBoot")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable SecureBoot: %v", err)
+	}
+	setupMode, err := readUint8Variable(store, "SetupMode")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable SetupMode: %v", err)
+	}
+	state.Enabled = secureBoot == 1
+	state.SetupMode = setupMode == 1
+
+	// Read the db and dbx variables
+	if state.DB, err = readSignatureDatabase(store, "db"); err != nil {
+		return nil, err
+	}
+	if state.DBX, err = readSignatureDatabase(store, "dbx"); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Reads and parses an image security database variable, treating a missing variable as an empty database
+func readSignatureDatabase(store VariableStore, name string) (SignatureDatabase, error) {
+	_, data, err := store.ReadVariable(name, EFI_IMAGE_SECURITY_DATABASE_GUID)
+	if errors.Is(err, fs.ErrNotExist) {
+		return SignatureDatabase{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read UEFI variable %s: %v", name, err)
+	}
+	database, err := ParseSignatureDatabase(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse UEFI variable %s: %v", name, err)
+	}
+	return database, nil
+}
+
+// Reads a global variable containing a single UINT8 value, treating a missing variable as zero
+func readUint8Variable(store VariableStore, name string) (uint8, error) {
+	_, data, err := store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	} else if len(data) != 1 {
+		return 0, fmt.Errorf("UEFI variable %s has unexpected size %d", name, len(data))
+	}
+	return data[0], nil
+}
+
+// Determines whether the firmware will verify the signatures of images before executing them
+// (Signatures are not verified in setup mode, even if the SecureBoot variable is set)
+func (s *SecureBootState) IsEnforcing() bool {
+	return s.Enabled && !s.SetupMode
+}
+
+// Describes whether the firmware would permit an image to be executed under Secure Boot
+type ImageVerdict struct {
+
+	// Specifies whether the image would be permitted
+	Allowed bool
+
+	// A human-readable explanation of the verdict
+	Reason string
+}
+
+// Checks an image against the `db` and `dbx` signature databases in the same order as the firmware, from:
+// <https://uefi.org/specs/UEFI/2.10/32_Secure_Boot_and_Driver_Signing.html#authorization-process>
+//
+// An image is rejected if its hash or any certificate in the chain of one of its signatures (including the certificate
+// in `db` that the chain ends at, which need not be embedded in the signature) appears in `dbx`, and is
+// otherwise permitted if its hash appears in `db` or one of its signatures chains to a certificate in `db`. Only the
+// SHA-256 and X.509 signature types are considered, and timestamp-based revocation is not evaluated.
+func (s *SecureBootState) CheckImage(image *pe.Image) ImageVerdict {
+
+	// Compute the SHA-256 Authenticode digest of the image, which is the form used by hash entries in db and dbx
+	imageHash, err := image.AuthenticodeHash(crypto.SHA256)
+	if err != nil {
+		return ImageVerdict{Reason: fmt.Sprintf("failed to hash the image: %v", err)}
+	}
+	if s.DBX.Contains(EFI_CERT_SHA256_GUID, imageHash) {
+		return ImageVerdict{Reason: "the image hash is revoked in dbx"}
+	}
+
+	// Parse the image's signatures
+	signatures, err := image.Signatures()
+	if err != nil {
+		return ImageVerdict{Reason: fmt.Sprintf("the image signature is invalid: %v", err)}
+	}
+
+	// Check each signature against dbx and then db
+	trustedBy := ""
+	covered := 0
+	revoked := s.DBX.Certificates()
+	trusted := s.DB.Certificates()
+	for _, signature := range signatures {
+
+		// Ignore signatures that do not cover the image's contents
+		digest, err := image.AuthenticodeHash(signature.DigestAlgorithm)
+		if err != nil || !bytes.Equal(digest, signature.Digest) {
+			continue
+		}
+		covered++
+
+		// Reject the image if any certificate in the chain has been revoked, or was issued by a revoked certificate
+		// (The root of the chain is rarely embedded in the signature, so it can only be identified as an issuer)
+		chain := signature.Chain()
+		for _, certificate := range chain {
+			if s.DBX.containsCertificateHash(certificate) {
+				return ImageVerdict{Reason: fmt.Sprintf("certificate \"%s\" is revoked in dbx", certificate.Subject.CommonName)}
+			} else if issuer := findTrustAnchor(certificate, revoked); issuer != nil {
+				return ImageVerdict{Reason: fmt.Sprintf("certificate \"%s\" is revoked in dbx", issuer.Subject.CommonName)}
+			}
+		}
+
+		// Determine whether the chain ends at (or is issued by) a certificate in db, rejecting the image if the hash of
+		// that certificate has been revoked
+		if trustedBy == "" {
+			for _, certificate := range chain {
+				if anchor := findTrustAnchor(certificate, trusted); anchor != nil {
+					if s.DBX.containsCertificateHash(anchor) {
+						return ImageVerdict{Reason: fmt.Sprintf("certificate \"%s\" is revoked in dbx", anchor.Subject.CommonName)}
+					}
+					trustedBy = anchor.Subject.CommonName
+					break
+				}
+			}
+		}
+	}
+
+	// Permit the image if one of its signatures is trusted or its hash is authorised
+	if trustedBy != "" {
+		return ImageVerdict{Allowed: true, Reason: fmt.Sprintf("signed by a certificate chaining to \"%s\" in db", trustedBy)}
+	} else if s.DB.Contains(EFI_CERT_SHA256_GUID, imageHash) {
+		return ImageVerdict{Allowed: true, Reason: "the image hash is authorised in db"}
+	} else if len(signatures) == 0 {
+		return ImageVerdict{Reason: "the image is not signed and its hash is not in db"}
+	} else if covered == 0 {
+		return ImageVerdict{Reason: "the image has been modified since it was signed"}
+	}
+	return ImageVerdict{Reason: "no valid signature chains to a certificate in db"}
+}
+
+// Determines whether the database contains the SHA-256 hash of the to-be-signed portion of a certificate
+// (Each EFI_CERT_X509_SHA256 entry holds the hash followed by an EFI_TIME revocation time, which is not evaluated)
+func (d SignatureDatabase) containsCertificateHash(certificate *x509.Certificate) bool {
+	tbsHash := sha256.Sum256(certificate.RawTBSCertificate)
+	for _, entry := range d {
+		if entry.Type == EFI_CERT_X509_SHA256_GUID && bytes.HasPrefix(entry.Data, tbsHash[:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the certificate from the specified list that is identical to or issued the certificate, or nil if there is none
+func findTrustAnchor(certificate *x509.Certificate, anchors []*x509.Certificate) *x509.Certificate {
+	for _, anchor := range anchors {
+		if certificate.Equal(anchor) || pe.IsIssuedBy(certificate, anchor) {
+			return anchor
+		}
+	}
+	return nil
+}
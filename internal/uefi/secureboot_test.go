@@ -0,0 +1,344 @@
+package uefi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tensorworks/bootnext/internal/pe"
+	"github.com/tensorworks/bootnext/internal/pe/petest"
+)
+
+// The owner GUID used for the entries in the test signature lists
+var testSignatureOwner = MustParseGUID("77fa9abd-0359-4d32-bd60-28f4e78f784b")
+
+// Encodes an EFI_SIGNATURE_LIST structure holding the specified entries, which must all have the same size
+func encodeSignatureList(signatureType GUID, entries ...[]byte) []byte {
+	signatureSize := 16
+	if len(entries) > 0 {
+		signatureSize += len(entries[0])
+	}
+	list := make([]byte, signatureListHeaderSize)
+	copy(list[0:], signatureType[:])
+	binary.LittleEndian.PutUint32(list[16:], uint32(signatureListHeaderSize+len(entries)*signatureSize))
+	binary.LittleEndian.PutUint32(list[24:], uint32(signatureSize))
+	for _, entry := range entries {
+		list = append(append(list, testSignatureOwner[:]...), entry...)
+	}
+	return list
+}
+
+// Builds a signature database from a list of certificate and hash entries
+func newTestDatabase(certificates []*x509.Certificate, hashes [][]byte) SignatureDatabase {
+	database := SignatureDatabase{}
+	for _, certificate := range certificates {
+		database = append(database, SignatureData{Type: EFI_CERT_X509_GUID, Owner: testSignatureOwner, Data: certificate.Raw})
+	}
+	for _, hash := range hashes {
+		database = append(database, SignatureData{Type: EFI_CERT_SHA256_GUID, Owner: testSignatureOwner, Data: hash})
+	}
+	return database
+}
+
+func TestParseSignatureDatabase(t *testing.T) {
+	first, second := bytes.Repeat([]byte{0x11}, sha256.Size), bytes.Repeat([]byte{0x22}, sha256.Size)
+	certificate := []byte("certificate data of a different size")
+	valid := append(encodeSignatureList(EFI_CERT_SHA256_GUID, first, second), encodeSignatureList(EFI_CERT_X509_GUID, certificate)...)
+
+	// Returns a copy of the valid database with a UINT32 field of the first signature list replaced
+	withField := func(offset int, value uint32) []byte {
+		data := append([]byte{}, valid...)
+		binary.LittleEndian.PutUint32(data[offset:], value)
+		return data
+	}
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected SignatureDatabase
+		err      string
+	}{
+		{
+			name:     "empty",
+			data:     []byte{},
+			expected: SignatureDatabase{},
+		},
+		{
+			name: "hashes and certificate",
+			data: valid,
+			expected: SignatureDatabase{
+				{Type: EFI_CERT_SHA256_GUID, Owner: testSignatureOwner, Data: first},
+				{Type: EFI_CERT_SHA256_GUID, Owner: testSignatureOwner, Data: second},
+				{Type: EFI_CERT_X509_GUID, Owner: testSignatureOwner, Data: certificate},
+			},
+		},
+		{
+			name: "signature header is skipped",
+			data: func() []byte {
+				list := encodeSignatureList(EFI_CERT_SHA256_GUID, first)
+				binary.LittleEndian.PutUint32(list[16:], uint32(len(list)+4))
+				binary.LittleEndian.PutUint32(list[20:], 4)
+				return append(append(list[:signatureListHeaderSize:signatureListHeaderSize], 0xde, 0xad, 0xbe, 0xef), list[signatureListHeaderSize:]...)
+			}(),
+			expected: SignatureDatabase{{Type: EFI_CERT_SHA256_GUID, Owner: testSignatureOwner, Data: first}},
+		},
+		{
+			name: "truncated header",
+			data: valid[:signatureListHeaderSize-1],
+			err:  "signature list is truncated",
+		},
+		{
+			name: "truncated list",
+			data: valid[:signatureListHeaderSize+16+sha256.Size],
+			err:  "invalid sizes",
+		},
+		{
+			name: "truncated second list",
+			data: valid[:len(valid)-1],
+			err:  "invalid sizes",
+		},
+		{
+			name: "list smaller than its header",
+			data: withField(16, signatureListHeaderSize-1),
+			err:  "invalid sizes",
+		},
+		{
+			name: "header larger than the list",
+			data: withField(20, 0xffffffff),
+			err:  "invalid sizes",
+		},
+		{
+			name: "signature smaller than the owner",
+			data: withField(24, 15),
+			err:  "invalid sizes",
+		},
+		{
+			name: "zero signature size",
+			data: withField(24, 0),
+			err:  "invalid sizes",
+		},
+		{
+			name: "list not a multiple of the signature size",
+			data: withField(24, 16+sha256.Size+1),
+			err:  "is not a multiple of the signature size",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			database, err := ParseSignatureDatabase(testCase.data)
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("got error %v, expected %q", err, testCase.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(database) != len(testCase.expected) {
+				t.Fatalf("got %d entries, expected %d", len(database), len(testCase.expected))
+			}
+			for index, entry := range database {
+				expected := testCase.expected[index]
+				if entry.Type != expected.Type || entry.Owner != expected.Owner || !bytes.Equal(entry.Data, expected.Data) {
+					t.Errorf("entry %d: got %s %s %x, expected %s %s %x", index, entry.Type, entry.Owner, entry.Data, expected.Type, expected.Owner, expected.Data)
+				}
+			}
+		})
+	}
+
+	// Every truncation of a valid database is either rejected or parsed without panicking
+	for length := 0; length < len(valid); length++ {
+		ParseSignatureDatabase(valid[:length])
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+
+	// Build a chain of certificates from a root certificate authority to a signing certificate, along with an unrelated
+	// certificate authority
+	root, rootKey := petest.NewCertificate("Test Root CA", nil, nil)
+	intermediate, intermediateKey := petest.NewCertificate("Test Intermediate CA", root, rootKey)
+	signer, signerKey := petest.NewCertificate("Test Signer", intermediate, intermediateKey)
+	unrelated, _ := petest.NewCertificate("Unrelated CA", nil, nil)
+	rootHash, intermediateHash := sha256.Sum256(root.RawTBSCertificate), sha256.Sum256(intermediate.RawTBSCertificate)
+
+	// Build an unsigned image, the same image signed by the signing certificate, and a copy of the signed image whose
+	// contents have been modified after it was signed
+	unsigned := petest.NewImage(pe.IMAGE_FILE_MACHINE_AMD64, pe.IMAGE_NT_OPTIONAL_HDR64_MAGIC, []byte("loader"))
+	parsed, err := pe.Parse(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageHash, err := parsed.AuthenticodeHash(crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := petest.AppendSignatures(unsigned, petest.NewSignature(crypto.SHA256, imageHash, signer, signerKey, signer, intermediate))
+	modified := append([]byte{}, signed...)
+	modified[len(unsigned)-1] ^= 0xff
+
+	testCases := []struct {
+		name    string
+		image   []byte
+		db      SignatureDatabase
+		dbx     SignatureDatabase
+		allowed bool
+		reason  string
+	}{
+		{
+			name:   "unsigned image",
+			image:  unsigned,
+			db:     newTestDatabase([]*x509.Certificate{root}, nil),
+			reason: "the image is not signed and its hash is not in db",
+		},
+		{
+			name:    "unsigned image with its hash in db",
+			image:   unsigned,
+			db:      newTestDatabase(nil, [][]byte{imageHash}),
+			allowed: true,
+			reason:  "the image hash is authorised in db",
+		},
+		{
+			name:    "signed by a certificate chaining to db",
+			image:   signed,
+			db:      newTestDatabase([]*x509.Certificate{unrelated, root}, nil),
+			allowed: true,
+			reason:  "chaining to \"Test Root CA\" in db",
+		},
+		{
+			name:    "signed by a certificate in db",
+			image:   signed,
+			db:      newTestDatabase([]*x509.Certificate{signer}, nil),
+			allowed: true,
+			reason:  "chaining to \"Test Signer\" in db",
+		},
+		{
+			name:   "signed by a certificate chaining to an unrelated certificate",
+			image:  signed,
+			db:     newTestDatabase([]*x509.Certificate{unrelated}, nil),
+			reason: "no valid signature chains to a certificate in db",
+		},
+		{
+			name:   "image hash in dbx",
+			image:  signed,
+			db:     newTestDatabase([]*x509.Certificate{root}, [][]byte{imageHash}),
+			dbx:    newTestDatabase(nil, [][]byte{imageHash}),
+			reason: "the image hash is revoked in dbx",
+		},
+		{
+			name:   "signing certificate in dbx",
+			image:  signed,
+			db:     newTestDatabase([]*x509.Certificate{root}, nil),
+			dbx:    newTestDatabase([]*x509.Certificate{signer}, nil),
+			reason: "certificate \"Test Signer\" is revoked in dbx",
+		},
+		{
+			name:   "intermediate certificate in dbx",
+			image:  signed,
+			db:     newTestDatabase([]*x509.Certificate{root}, nil),
+			dbx:    newTestDatabase([]*x509.Certificate{intermediate}, nil),
+			reason: "certificate \"Test Intermediate CA\" is revoked in dbx",
+		},
+		{
+			name:   "signing certificate in dbx despite its hash being authorised in db",
+			image:  signed,
+			db:     newTestDatabase(nil, [][]byte{imageHash}),
+			dbx:    newTestDatabase([]*x509.Certificate{signer}, nil),
+			reason: "certificate \"Test Signer\" is revoked in dbx",
+		},
+		{
+			name:   "root certificate that is not embedded in dbx",
+			image:  signed,
+			db:     newTestDatabase([]*x509.Certificate{root}, nil),
+			dbx:    newTestDatabase([]*x509.Certificate{root}, nil),
+			reason: "certificate \"Test Root CA\" is revoked in dbx",
+		},
+		{
+			name:  "root certificate hash in dbx",
+			image: signed,
+			db:    newTestDatabase([]*x509.Certificate{root}, nil),
+			dbx: SignatureDatabase{
+				{Type: EFI_CERT_X509_SHA256_GUID, Owner: testSignatureOwner, Data: append(rootHash[:], make([]byte, 16)...)},
+			},
+			reason: "certificate \"Test Root CA\" is revoked in dbx",
+		},
+		{
+			name:  "intermediate certificate hash in dbx",
+			image: signed,
+			db:    newTestDatabase([]*x509.Certificate{root}, nil),
+			dbx: SignatureDatabase{
+				{Type: EFI_CERT_X509_SHA256_GUID, Owner: testSignatureOwner, Data: append(intermediateHash[:], make([]byte, 16)...)},
+			},
+			reason: "certificate \"Test Intermediate CA\" is revoked in dbx",
+		},
+		{
+			name:    "certificate hash listed as an image hash in dbx",
+			image:   signed,
+			db:      newTestDatabase([]*x509.Certificate{root}, nil),
+			dbx:     newTestDatabase(nil, [][]byte{rootHash[:]}),
+			allowed: true,
+			reason:  "chaining to \"Test Root CA\" in db",
+		},
+		{
+			name:   "modified after signing",
+			image:  modified,
+			db:     newTestDatabase([]*x509.Certificate{root}, nil),
+			reason: "the image has been modified since it was signed",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			image, err := pe.Parse(testCase.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := &SecureBootState{Enabled: true, DB: testCase.db, DBX: testCase.dbx}
+			verdict := state.CheckImage(image)
+			if verdict.Allowed != testCase.allowed || !strings.Contains(verdict.Reason, testCase.reason) {
+				t.Errorf("got %v (%s), expected %v (%s)", verdict.Allowed, verdict.Reason, testCase.allowed, testCase.reason)
+			}
+		})
+	}
+}
+
+func TestReadSecureBootState(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x33}, sha256.Size)
+
+	// Missing variables are treated as disabled or empty
+	state, err := ReadSecureBootState(NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Enabled || state.SetupMode || state.IsEnforcing() || len(state.DB) != 0 || len(state.DBX) != 0 {
+		t.Errorf("got %+v for an empty store, expected Secure Boot to be disabled with empty databases", state)
+	}
+
+	// The variables are read from their respective vendor GUIDs
+	store := NewMemoryStore()
+	store.WriteVariable("SecureBoot", EFI_GLOBAL_VARIABLE, bootVariableAttributes, []byte{1})
+	store.WriteVariable("SetupMode", EFI_GLOBAL_VARIABLE, bootVariableAttributes, []byte{0})
+	store.WriteVariable("dbx", EFI_IMAGE_SECURITY_DATABASE_GUID, bootVariableAttributes, encodeSignatureList(EFI_CERT_SHA256_GUID, hash))
+	state, err = ReadSecureBootState(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.IsEnforcing() || len(state.DB) != 0 || !state.DBX.Contains(EFI_CERT_SHA256_GUID, hash) {
+		t.Errorf("got %+v, expected Secure Boot to be enforcing with the hash in dbx", state)
+	}
+
+	// Malformed variables are reported as errors
+	store.WriteVariable("db", EFI_IMAGE_SECURITY_DATABASE_GUID, bootVariableAttributes, []byte{0x01})
+	if _, err := ReadSecureBootState(store); err == nil || !strings.Contains(err.Error(), "UEFI variable db") {
+		t.Errorf("got error %v for a truncated db, expected a parse error", err)
+	}
+	store.WriteVariable("SetupMode", EFI_GLOBAL_VARIABLE, bootVariableAttributes, []byte{0, 0})
+	if _, err := ReadSecureBootState(store); err == nil || !strings.Contains(err.Error(), "SetupMode") {
+		t.Errorf("got error %v for an oversized SetupMode, expected a size error", err)
+	}
+}
@@ -0,0 +1,27 @@
+package uefi
+
+// Represents the state of the UEFI boot manager variables
+type BootStatus struct {
+
+	// The identifier of the boot entry that the system was booted from, or an empty string if it is unknown
+	BootCurrent string
+
+	// The identifier of the boot entry that will be used for the next boot, or an empty string if BootNext is not set
+	BootNext string
+
+	// The identifiers of the boot entries in the BootOrder variable, in order of priority
+	BootOrder []string
+
+	// The boot manager timeout in seconds, or nil if the Timeout variable is not set
+	Timeout *uint16
+}
+
+// Returns the boot entry with the specified identifier, or nil if there is no matching entry
+func FindBootEntry(entries []BootEntry, id string) *BootEntry {
+	for index := range entries {
+		if entries[index].ID == id {
+			return &entries[index]
+		}
+	}
+	return nil
+}
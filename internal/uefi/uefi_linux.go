@@ -3,14 +3,20 @@ package uefi
 import (
 	"errors"
 	"os"
-	"regexp"
+	"strconv"
 	"strings"
 
-	"github.com/tensorworks/bootnext/internal/process"
+	"golang.org/x/sys/unix"
 )
 
+// The sysfs attribute that reports the bitness of the firmware (32 or 64)
+const platformSizePath = "/sys/firmware/efi/fw_platform_size"
+
+// The names of the platform-specific backends that are supported under Linux
+var platformBackendNames = []string{"efibootmgr", "efivarfs"}
+
 // Determines whether the operating system has been booted in UEFI mode
-func IsUEFIEnabled() (bool, error) {
+func isUEFIEnabled() (bool, error) {
 
 	// Determine whether `/sys/firmware/efi` exists
 	_, err := os.Stat("/sys/firmware/efi")
@@ -24,43 +30,44 @@ func IsUEFIEnabled() (bool, error) {
 	}
 }
 
-// Returns the list of system tools that we require in order to interact with UEFI NVRAM variables
-func RequiredTools() []string {
-	return []string{"efibootmgr"}
-}
-
-// Lists the UEFI boot entries for the host machine
-func ListBootEntries() ([]BootEntry, error) {
-
-	// Run `efibootmgr` with no flags to print the list of boot entries
-	output, err := process.CaptureOutput([]string{"efibootmgr"})
-	if err != nil {
-		return nil, err
+// Returns the PE/COFF machine type of the images that the running firmware is able to execute
+// (The firmware bitness is read from sysfs, since a 64-bit kernel can be booted by 32-bit firmware)
+func FirmwareMachine() (uint16, error) {
+	name := unix.Utsname{}
+	if err := unix.Uname(&name); err != nil {
+		return 0, err
 	}
 
-	// Compile our regular expression for parsing the output
-	regex, err := regexp.Compile(`Boot([0-9]+)\*?\s+(.+)`)
-	if err != nil {
-		return nil, err
+	// Older kernels do not report the firmware bitness, in which case we assume that it matches the CPU
+	platformSize := 0
+	if contents, err := os.ReadFile(platformSizePath); err == nil {
+		platformSize, _ = strconv.Atoi(strings.TrimSpace(string(contents)))
 	}
 
-	// Parse the list of entries
-	entries := []BootEntry{}
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if groups := regex.FindStringSubmatch(line); groups != nil {
-			entries = append(entries, BootEntry{
-				ID:          groups[1],
-				Description: groups[2],
-			})
-		}
-	}
+	return firmwareMachine(unix.ByteSliceToString(name.Machine[:]), platformSize)
+}
 
-	return entries, nil
+// Returns the default backend, which accesses UEFI variables directly when efivarfs is mounted and falls back to
+// running `efibootmgr` otherwise
+func defaultBackend() Backend {
+	native := NewEfivarfsBackend(DefaultEfivarfsRoot)
+	if available, err := native.IsUEFIEnabled(); err == nil && available {
+		return native
+	}
+	return &EfibootmgrBackend{}
 }
 
-// Sets the value of the BootNext UEFI NVRAM variable
-func SetBootNext(entry BootEntry) error {
-	_, err := process.CaptureOutput([]string{"efibootmgr", "--bootnext", entry.ID})
-	return err
+// Creates the Linux-specific backend with the specified name, or returns nil if the name is not recognised
+func newPlatformBackend(name string, argument string) (Backend, error) {
+	switch name {
+	case "efibootmgr":
+		return &EfibootmgrBackend{}, nil
+	case "efivarfs":
+		if argument == "" {
+			argument = DefaultEfivarfsRoot
+		}
+		return NewEfivarfsBackend(argument), nil
+	default:
+		return nil, nil
+	}
 }
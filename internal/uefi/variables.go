@@ -0,0 +1,116 @@
+package uefi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+)
+
+// Variable attribute flags, from: <https://uefi.org/specs/UEFI/2.10/08_Services_Runtime_Services.html#getvariable>
+const (
+	EFI_VARIABLE_NON_VOLATILE                          uint32 = 0x00000001
+	EFI_VARIABLE_BOOTSERVICE_ACCESS                    uint32 = 0x00000002
+	EFI_VARIABLE_RUNTIME_ACCESS                        uint32 = 0x00000004
+	EFI_VARIABLE_HARDWARE_ERROR_RECORD                 uint32 = 0x00000008
+	EFI_VARIABLE_TIME_BASED_AUTHENTICATED_WRITE_ACCESS uint32 = 0x00000020
+	EFI_VARIABLE_APPEND_WRITE                          uint32 = 0x00000040
+)
+
+// The attributes used for the non-volatile boot manager variables that we write (`BootNext`, `Boot####`, etc.)
+const bootVariableAttributes = EFI_VARIABLE_NON_VOLATILE | EFI_VARIABLE_BOOTSERVICE_ACCESS | EFI_VARIABLE_RUNTIME_ACCESS
+
+// Identifies an individual UEFI variable by its name and vendor GUID
+type VariableName struct {
+	Name string
+	GUID GUID
+}
+
+// Provides raw access to UEFI NVRAM variables
+type VariableStore interface {
+
+	// Determines whether the store is able to access UEFI variables on the host system
+	IsAvailable() (bool, error)
+
+	// Lists the names of all variables in the store
+	ListVariables() ([]VariableName, error)
+
+	// Reads the attributes and data of the specified variable
+	// (Variables that do not exist produce an error that matches `fs.ErrNotExist`)
+	ReadVariable(name string, guid GUID) (uint32, []byte, error)
+
+	// Creates or replaces the specified variable
+	WriteVariable(name string, guid GUID, attributes uint32, data []byte) error
+
+	// Deletes the specified variable
+	// (Variables that do not exist produce an error that matches `fs.ErrNotExist`)
+	DeleteVariable(name string, guid GUID) error
+}
+
+// Formats a load option number as the four-digit hexadecimal identifier used in variable names
+func formatOptionNumber(number uint16) string {
+	return fmt.Sprintf("%04X", number)
+}
+
+// Parses a four-digit hexadecimal load option identifier
+func parseOptionNumber(id string) (uint16, error) {
+	number, err := strconv.ParseUint(id, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid load option identifier \"%s\"", id)
+	}
+	return uint16(number), nil
+}
+
+// Reads a global variable containing a single UINT16 value, reporting whether the variable exists
+func readUint16Variable(store VariableStore, name string) (uint16, bool, error) {
+	_, data, err := store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	} else if len(data) != 2 {
+		return 0, false, fmt.Errorf("UEFI variable %s has unexpected size %d", name, len(data))
+	}
+
+	return binary.LittleEndian.Uint16(data), true, nil
+}
+
+// Reads a global variable containing an array of UINT16 values, treating a missing variable as an empty array
+func readUint16ArrayVariable(store VariableStore, name string) ([]uint16, error) {
+	_, data, err := store.ReadVariable(name, EFI_GLOBAL_VARIABLE)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []uint16{}, nil
+	} else if err != nil {
+		return nil, err
+	} else if len(data)%2 != 0 {
+		return nil, fmt.Errorf("UEFI variable %s has unexpected size %d", name, len(data))
+	}
+
+	values := make([]uint16, len(data)/2)
+	for index := range values {
+		values[index] = binary.LittleEndian.Uint16(data[index*2:])
+	}
+	return values, nil
+}
+
+// Writes a global variable containing a single UINT16 value
+func writeUint16Variable(store VariableStore, name string, value uint16) error {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data, value)
+	return store.WriteVariable(name, EFI_GLOBAL_VARIABLE, bootVariableAttributes, data)
+}
+
+// Writes a global variable containing an array of UINT16 values
+func writeUint16ArrayVariable(store VariableStore, name string, values []uint16) error {
+	return store.WriteVariable(name, EFI_GLOBAL_VARIABLE, bootVariableAttributes, encodeUint16Array(values))
+}
+
+// Encodes an array of UINT16 values in little-endian byte order
+func encodeUint16Array(values []uint16) []byte {
+	data := make([]byte, len(values)*2)
+	for index, value := range values {
+		binary.LittleEndian.PutUint16(data[index*2:], value)
+	}
+	return data
+}